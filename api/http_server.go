@@ -1,20 +1,37 @@
 package api
 
 import (
+	"context"
+	"crypto/ecdsa"
 	"crypto/sha256"
+	"crypto/x509"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 
+	"github.com/kutluhann/decentralized-file-sharing-system/constants"
 	"github.com/kutluhann/decentralized-file-sharing-system/dht"
+	"github.com/kutluhann/decentralized-file-sharing-system/encryption"
+	"github.com/kutluhann/decentralized-file-sharing-system/rpc/dfssv1/dfssv1connect"
 )
 
 // StoreRequest represents the JSON payload for storing data
 type StoreRequest struct {
-	Key   string `json:"key"`   // Human-readable key (will be hashed to NodeID)
-	Value string `json:"value"` // Value to store (string or base64 for binary)
+	Key     string `json:"key"`               // Human-readable key (will be hashed to NodeID)
+	Value   string `json:"value"`             // Value to store (string or base64 for binary)
+	Erasure bool   `json:"erasure,omitempty"` // Store erasure-coded shards instead of full replicas
+	Encrypt bool   `json:"encrypt,omitempty"` // Encrypt the value, readable by this node and the recipients only
+
+	// With encrypt: derive the key from the content, so identical values are stored once.
+	// Anyone who can guess the value can then confirm that it is stored.
+	Convergent bool `json:"convergent,omitempty"`
+
+	// Hex PKIX public keys of the nodes that may also decrypt the value (see /status)
+	Recipients []string `json:"recipients,omitempty"`
 }
 
 // StoreResponse represents the response after storing
@@ -26,7 +43,8 @@ type StoreResponse struct {
 
 // GetRequest represents the JSON payload for retrieving data
 type GetRequest struct {
-	Key string `json:"key"` // Human-readable key (will be hashed to NodeID)
+	Key     string `json:"key"`                // Human-readable key (will be hashed to NodeID)
+	KeyHash string `json:"key_hash,omitempty"` // Hex key hash, used instead of key (e.g. from /shared-with-me)
 }
 
 // GetResponse represents the response after retrieval
@@ -38,53 +56,134 @@ type GetResponse struct {
 	HopCount int    `json:"hop_count"` // Number of network hops to find the value
 }
 
+// DeleteRequest represents the JSON payload for deleting data
+type DeleteRequest struct {
+	Key string `json:"key"` // Human-readable key (will be hashed to NodeID)
+}
+
+// DeleteResponse represents the response after deleting
+type DeleteResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+	KeyHash string `json:"key_hash"`
+}
+
 // StatusResponse represents node status information
 type StatusResponse struct {
-	NodeID        string `json:"node_id"`
-	IP            string `json:"ip"`
-	Port          int    `json:"port"`
-	StoredKeys    int    `json:"stored_keys"`
-	KnownPeers    int    `json:"known_peers"`
-	NetworkStatus string `json:"network_status"`
+	NodeID        string            `json:"node_id"`
+	PublicKey     string            `json:"public_key,omitempty"` // Hex PKIX key others encrypt for
+	IP            string            `json:"ip"`
+	Port          int               `json:"port"`
+	StoredKeys    int               `json:"stored_keys"`
+	StoredBytes   int64             `json:"stored_bytes"`
+	QuotaBytes    int64             `json:"quota_bytes,omitempty"` // 0 when storage is unlimited
+	Evictions     dht.EvictionStats `json:"evictions"`
+	KnownPeers    int               `json:"known_peers"`
+	NetworkStatus string            `json:"network_status"`
 }
 
 // HTTPServer wraps the DHT node and provides HTTP endpoints
 type HTTPServer struct {
-	Node *dht.Node
-	Port int
+	Node               *dht.Node
+	Port               int
+	MaxUploadBytes     int64 // Largest request body accepted when storing a value
+	ErasureDataShards  int   // Shards needed to rebuild an erasure-coded value
+	ErasureTotalShards int   // Shards stored per erasure-coded value
+
+	server *http.Server
 }
 
 // NewHTTPServer creates a new HTTP server instance
 func NewHTTPServer(node *dht.Node, port int) *HTTPServer {
 	return &HTTPServer{
-		Node: node,
-		Port: port,
+		Node:               node,
+		Port:               port,
+		MaxUploadBytes:     constants.MaxUploadBytes,
+		ErasureDataShards:  node.Config.ErasureDataShards,
+		ErasureTotalShards: node.Config.ErasureTotalShards,
+		server:             newServer(),
 	}
 }
 
-// Start begins listening for HTTP requests
-func (s *HTTPServer) Start() error {
+// newServer creates an HTTP server that also speaks HTTP/2 without TLS, as gRPC clients do
+func newServer() *http.Server {
+	server := &http.Server{Protocols: new(http.Protocols)}
+	server.Protocols.SetHTTP1(true)
+	server.Protocols.SetUnencryptedHTTP2(true)
+	return server
+}
+
+// Handler returns the routes of the HTTP and RPC APIs and the frontend
+func (s *HTTPServer) Handler() http.Handler {
+	mux := http.NewServeMux()
 
 	// SERVE FRONTEND FILES
 	fs := http.FileServer(http.Dir("./frontend"))
-	http.Handle("/", fs)
+	mux.Handle("/", fs)
 
 	// Set up routes
-	http.HandleFunc("/store", s.handleStore)
-	http.HandleFunc("/get", s.handleGet)
-	http.HandleFunc("/status", s.handleStatus)
-	http.HandleFunc("/health", s.handleHealth)
-	http.HandleFunc("/routing-table", s.handleRoutingTable)
-
-	addr := fmt.Sprintf(":%d", s.Port)
-	fmt.Printf("[HTTP-API] Starting HTTP server on %s\n", addr)
+	s.registerV1(mux)
+	rpcServer := NewRPCServer(s.Node)
+	rpcServer.MaxUploadBytes = s.MaxUploadBytes
+	mux.Handle(dfssv1connect.NewNodeServiceHandler(rpcServer))
+	mux.HandleFunc("/store", deprecated("/v1/keys/{key}", s.handleStore))
+	mux.HandleFunc("/get", deprecated("/v1/keys/{key}", s.handleGet))
+	mux.HandleFunc("/delete", deprecated("/v1/keys/{key}", s.handleDelete))
+	mux.HandleFunc("/publish", s.handlePublish)
+	mux.HandleFunc("/resolve", s.handleResolve)
+	mux.HandleFunc("/provide", s.handleProvide)
+	mux.HandleFunc("/providers", s.handleProviders)
+	mux.HandleFunc("/grant", s.handleGrant)
+	mux.HandleFunc("/revoke", s.handleRevoke)
+	mux.HandleFunc("/shared-with-me", s.handleSharedWithMe)
+	mux.HandleFunc("/status", s.handleStatus)
+	mux.HandleFunc("/health", s.handleHealth)
+	mux.HandleFunc("/routing-table", s.handleRoutingTable)
+	mux.HandleFunc("/hot-keys", s.handleHotKeys)
+	return mux
+}
+
+// Start begins listening for HTTP requests on Port
+func (s *HTTPServer) Start() error {
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", s.Port))
+	if err != nil {
+		return err
+	}
+	return s.Serve(listener)
+}
+
+// Serve handles HTTP requests on listener until Shutdown is called,
+// then returns http.ErrServerClosed
+func (s *HTTPServer) Serve(listener net.Listener) error {
+	fmt.Printf("[HTTP-API] Starting HTTP server on %s\n", listener.Addr())
 	fmt.Printf("[HTTP-API] Endpoints available:\n")
-	fmt.Printf("[HTTP-API]   POST   /store  - Store a key-value pair\n")
-	fmt.Printf("[HTTP-API]   POST   /get    - Retrieve a value by key\n")
+	fmt.Printf("[HTTP-API]   PUT    /v1/keys/{key} - Store the raw request body\n")
+	fmt.Printf("[HTTP-API]   GET    /v1/keys/{key} - Retrieve a raw value\n")
+	fmt.Printf("[HTTP-API]   DELETE /v1/keys/{key} - Delete a key this node stored\n")
+	fmt.Printf("[HTTP-API]   GET    /v1/hashes/{hash} - Retrieve a raw value by key hash\n")
+	fmt.Printf("[HTTP-API]   GET    /v1/lookup/{hash} - Find the nodes closest to a key hash\n")
+	fmt.Printf("[HTTP-API]   POST   /store  - Store a key-value pair (deprecated)\n")
+	fmt.Printf("[HTTP-API]   POST   /get    - Retrieve a value by key (deprecated)\n")
+	fmt.Printf("[HTTP-API]   DELETE /delete - Delete a key this node stored (deprecated)\n")
+	fmt.Printf("[HTTP-API]   POST   /publish - Point a name of this node to content\n")
+	fmt.Printf("[HTTP-API]   POST   /resolve - Resolve a name to its newest content\n")
+	fmt.Printf("[HTTP-API]   POST   /provide - Announce this node as a provider of a key\n")
+	fmt.Printf("[HTTP-API]   POST   /providers - List the providers of a key\n")
+	fmt.Printf("[HTTP-API]   POST   /grant  - Give a peer read access to an encrypted value\n")
+	fmt.Printf("[HTTP-API]   POST   /revoke - Take read access away from a peer\n")
+	fmt.Printf("[HTTP-API]   GET    /shared-with-me - List values shared with this node\n")
 	fmt.Printf("[HTTP-API]   GET    /status - Get node status\n")
 	fmt.Printf("[HTTP-API]   GET    /health - Health check\n")
+	fmt.Printf("[HTTP-API]   GET    /hot-keys - Key demand and replica targets\n")
+	fmt.Printf("[HTTP-API]   RPC    /%s - Connect, gRPC and gRPC-Web API\n", dfssv1connect.NodeServiceName)
+
+	s.server.Handler = s.Handler()
+	return s.server.Serve(listener)
+}
 
-	return http.ListenAndServe(addr, nil)
+// Shutdown stops accepting requests and waits for the active ones until ctx is done
+func (s *HTTPServer) Shutdown(ctx context.Context) error {
+	return s.server.Shutdown(ctx)
 }
 
 // handleStore handles POST requests to store data in the DHT
@@ -95,7 +194,7 @@ func (s *HTTPServer) handleStore(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Parse request body
-	body, err := io.ReadAll(r.Body)
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.MaxUploadBytes))
 	if err != nil {
 		http.Error(w, "Failed to read request body", http.StatusBadRequest)
 		return
@@ -123,7 +222,18 @@ func (s *HTTPServer) handleStore(w http.ResponseWriter, r *http.Request) {
 		req.Key, keyHashHex[:16], len(req.Value))
 
 	// Store in DHT
-	err = s.Node.Store(nodeID, []byte(req.Value))
+	if req.Encrypt {
+		recipients, perr := parsePublicKeys(req.Recipients)
+		if perr != nil {
+			http.Error(w, perr.Error(), http.StatusBadRequest)
+			return
+		}
+		err = s.Node.StoreEncrypted(nodeID, []byte(req.Value), recipients, req.Convergent)
+	} else if req.Erasure {
+		err = s.Node.StoreErasure(nodeID, []byte(req.Value), s.ErasureDataShards, s.ErasureTotalShards)
+	} else {
+		err = s.Node.Store(nodeID, []byte(req.Value))
+	}
 	if err != nil {
 		resp := StoreResponse{
 			Success: false,
@@ -147,6 +257,19 @@ func (s *HTTPServer) handleStore(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(resp)
 }
 
+// parsePublicKeys parses hex encoded PKIX ECDSA public keys
+func parsePublicKeys(keys []string) ([]*ecdsa.PublicKey, error) {
+	ders := make([][]byte, 0, len(keys))
+	for _, k := range keys {
+		der, err := hex.DecodeString(k)
+		if err != nil {
+			return nil, fmt.Errorf("recipient key is not hex: %v", err)
+		}
+		ders = append(ders, der)
+	}
+	return parsePKIXKeys(ders)
+}
+
 // handleGet handles POST requests to retrieve data from the DHT
 func (s *HTTPServer) handleGet(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -168,13 +291,21 @@ func (s *HTTPServer) handleGet(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.Key == "" {
+	if req.Key == "" && req.KeyHash == "" {
 		http.Error(w, "Key is required", http.StatusBadRequest)
 		return
 	}
 
 	// Hash the key to get NodeID
 	keyHash := sha256.Sum256([]byte(req.Key))
+	if req.Key == "" {
+		parsed, err := dht.ParseNodeID(req.KeyHash)
+		if err != nil {
+			http.Error(w, "key_hash must be 64 hex characters", http.StatusBadRequest)
+			return
+		}
+		keyHash = parsed
+	}
 	nodeID := dht.NodeID(keyHash)
 	keyHashHex := hex.EncodeToString(keyHash[:])
 
@@ -182,7 +313,7 @@ func (s *HTTPServer) handleGet(w http.ResponseWriter, r *http.Request) {
 		req.Key, keyHashHex[:16])
 
 	// Retrieve from DHT (with hop count)
-	value, hopCount, err := s.Node.FindValue(nodeID)
+	value, hopCount, err := s.Node.Retrieve(nodeID)
 	if err != nil {
 		resp := GetResponse{
 			Success:  false,
@@ -190,8 +321,13 @@ func (s *HTTPServer) handleGet(w http.ResponseWriter, r *http.Request) {
 			KeyHash:  keyHashHex,
 			HopCount: hopCount,
 		}
+		status := http.StatusNotFound
+		if errors.Is(err, encryption.ErrNotRecipient) {
+			resp.Message = fmt.Sprintf("Not authorized: %v", err)
+			status = http.StatusForbidden
+		}
 		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusNotFound)
+		w.WriteHeader(status)
 		json.NewEncoder(w).Encode(resp)
 		return
 	}
@@ -210,6 +346,65 @@ func (s *HTTPServer) handleGet(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(resp)
 }
 
+// handleDelete handles DELETE requests to remove data this node stored from the DHT
+func (s *HTTPServer) handleDelete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Parse request body
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	var req DeleteRequest
+	err = json.Unmarshal(body, &req)
+	if err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if req.Key == "" {
+		http.Error(w, "Key is required", http.StatusBadRequest)
+		return
+	}
+
+	// Hash the key to get NodeID
+	keyHash := sha256.Sum256([]byte(req.Key))
+	nodeID := dht.NodeID(keyHash)
+	keyHashHex := hex.EncodeToString(keyHash[:])
+
+	fmt.Printf("[HTTP-API] Delete request: key='%s' -> hash=%s\n",
+		req.Key, keyHashHex[:16])
+
+	// Send the signed tombstone to the replicas
+	err = s.Node.Delete(nodeID)
+	if err != nil {
+		resp := DeleteResponse{
+			Success: false,
+			Message: fmt.Sprintf("Failed to delete: %v", err),
+			KeyHash: keyHashHex,
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(resp)
+		return
+	}
+
+	// Success response
+	resp := DeleteResponse{
+		Success: true,
+		Message: "Successfully deleted from DHT",
+		KeyHash: keyHashHex,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
 // handleStatus returns information about the node
 func (s *HTTPServer) handleStatus(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -217,10 +412,7 @@ func (s *HTTPServer) handleStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Count stored keys
-	s.Node.StorageMux.RLock()
-	storedKeys := len(s.Node.Storage)
-	s.Node.StorageMux.RUnlock()
+	usage := s.Node.GetStorageUsage()
 
 	// Count known peers (approximate - count non-empty buckets)
 	knownPeers := 0
@@ -228,11 +420,21 @@ func (s *HTTPServer) handleStatus(w http.ResponseWriter, r *http.Request) {
 		knownPeers += s.Node.RoutingTable.Buckets[i].Len()
 	}
 
+	var publicKey string
+	if s.Node.PrivKey != nil {
+		der, _ := x509.MarshalPKIXPublicKey(&s.Node.PrivKey.PublicKey)
+		publicKey = hex.EncodeToString(der)
+	}
+
 	resp := StatusResponse{
 		NodeID:        s.Node.Self.ID.String(), /*[:16] + "..."*/
+		PublicKey:     publicKey,
 		IP:            s.Node.Self.IP,
 		Port:          s.Node.Self.Port,
-		StoredKeys:    storedKeys,
+		StoredKeys:    usage.Keys,
+		StoredBytes:   usage.Bytes,
+		QuotaBytes:    usage.MaxBytes,
+		Evictions:     s.Node.GetEvictionStats(),
 		KnownPeers:    knownPeers,
 		NetworkStatus: "connected",
 	}
@@ -263,3 +465,14 @@ func (s *HTTPServer) handleRoutingTable(w http.ResponseWriter, r *http.Request)
 	tableInfo := s.Node.GetRoutingTableInfo()
 	json.NewEncoder(w).Encode(tableInfo)
 }
+
+// handleHotKeys returns the FIND_VALUE hit counters and replica targets per key
+func (s *HTTPServer) handleHotKeys(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.Node.GetHotKeyInfo())
+}
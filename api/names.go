@@ -0,0 +1,147 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/kutluhann/decentralized-file-sharing-system/dht"
+)
+
+// PublishRequest represents the JSON payload for pointing a name to content
+type PublishRequest struct {
+	Label string `json:"label"` // Name under this node's PeerID
+	Key   string `json:"key"`   // Human-readable key of the content (will be hashed to NodeID)
+}
+
+// PublishResponse represents the response after publishing a name
+type PublishResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+	PeerID  string `json:"peer_id"`  // Owner part of the name, needed to resolve it
+	NameKey string `json:"name_key"` // DHT key the record is stored at
+}
+
+// ResolveRequest represents the JSON payload for resolving a name
+type ResolveRequest struct {
+	PeerID string `json:"peer_id"` // Hex PeerID of the name's owner
+	Label  string `json:"label"`
+}
+
+// ResolveResponse represents the newest record of a name and the content it points to
+type ResolveResponse struct {
+	Success  bool   `json:"success"`
+	Message  string `json:"message,omitempty"`
+	Version  uint64 `json:"version"`
+	Target   string `json:"target,omitempty"` // Key hash of the content
+	Value    string `json:"value,omitempty"`  // The content, if it could be retrieved
+	HopCount int    `json:"hop_count"`
+}
+
+// handlePublish handles POST requests to point one of this node's names to content
+func (s *HTTPServer) handlePublish(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	var req PublishRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	if req.Label == "" || req.Key == "" {
+		http.Error(w, "Label and key are required", http.StatusBadRequest)
+		return
+	}
+
+	target := dht.NodeID(sha256.Sum256([]byte(req.Key)))
+
+	fmt.Printf("[HTTP-API] Publish request: label='%s' -> key='%s'\n", req.Label, req.Key)
+
+	nameKey, err := s.Node.PublishName(req.Label, target)
+	resp := PublishResponse{
+		Success: err == nil,
+		Message: "Successfully published name",
+		PeerID:  s.Node.Self.ID.String(),
+		NameKey: nameKey.String(),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil {
+		resp.Message = fmt.Sprintf("Failed to publish: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+// handleResolve handles POST requests to resolve a name to its newest target
+func (s *HTTPServer) handleResolve(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	var req ResolveRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	peerID, err := dht.ParseNodeID(req.PeerID)
+	if err != nil || req.Label == "" {
+		http.Error(w, "A hex peer_id and a label are required", http.StatusBadRequest)
+		return
+	}
+
+	fmt.Printf("[HTTP-API] Resolve request: %s/%s\n", req.PeerID[:16], req.Label)
+
+	record, hopCount, err := s.Node.ResolveName(peerID, req.Label)
+	if err != nil {
+		resp := ResolveResponse{
+			Success:  false,
+			Message:  fmt.Sprintf("Name not found: %v", err),
+			HopCount: hopCount,
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(resp)
+		return
+	}
+
+	resp := ResolveResponse{
+		Success:  true,
+		Version:  record.Version,
+		Target:   record.Target.String(),
+		HopCount: hopCount,
+	}
+
+	// Follow the pointer to the immutable content
+	value, hops, err := s.Node.Retrieve(record.Target)
+	resp.HopCount += hops
+	if err != nil {
+		resp.Message = fmt.Sprintf("Name resolved, content not found: %v", err)
+	} else {
+		resp.Value = string(value)
+	}
+
+	fmt.Printf("[HTTP-API] ✓ Name resolved to version %d in %d hops\n", record.Version, resp.HopCount)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/kutluhann/decentralized-file-sharing-system/dht"
+)
+
+// ProviderRequest represents the JSON payload for announcing or listing providers of a key
+type ProviderRequest struct {
+	Key string `json:"key"` // Human-readable key (will be hashed to NodeID)
+}
+
+// ProviderInfo is a peer holding the content, with the address to fetch it from
+type ProviderInfo struct {
+	PeerID  string `json:"peer_id"`
+	IP      string `json:"ip"`
+	Port    int    `json:"port"`               // UDP port of the provider's DHT node
+	HTTPURL string `json:"http_url,omitempty"` // Base URL of the provider's HTTP API
+	Expires int64  `json:"expires"`
+}
+
+// ProvidersResponse represents the providers of a key
+type ProvidersResponse struct {
+	Success   bool           `json:"success"`
+	Message   string         `json:"message,omitempty"`
+	KeyHash   string         `json:"key_hash"`
+	Providers []ProviderInfo `json:"providers,omitempty"`
+	HopCount  int            `json:"hop_count"`
+}
+
+// parseProviderRequest reads the key of a provider request, writing the error response if it fails
+func parseProviderRequest(w http.ResponseWriter, r *http.Request) (dht.NodeID, string, bool) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return dht.NodeID{}, "", false
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return dht.NodeID{}, "", false
+	}
+
+	var req ProviderRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return dht.NodeID{}, "", false
+	}
+
+	if req.Key == "" {
+		http.Error(w, "Key is required", http.StatusBadRequest)
+		return dht.NodeID{}, "", false
+	}
+
+	keyHash := sha256.Sum256([]byte(req.Key))
+	return dht.NodeID(keyHash), hex.EncodeToString(keyHash[:]), true
+}
+
+// handleProvide handles POST requests to announce that this node holds the content of a key
+func (s *HTTPServer) handleProvide(w http.ResponseWriter, r *http.Request) {
+	key, keyHashHex, ok := parseProviderRequest(w, r)
+	if !ok {
+		return
+	}
+
+	fmt.Printf("[HTTP-API] Provide request: hash=%s\n", keyHashHex[:16])
+
+	resp := ProvidersResponse{
+		Success: true,
+		Message: "Announced this node as a provider",
+		KeyHash: keyHashHex,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := s.Node.Provide(key); err != nil {
+		resp.Success = false
+		resp.Message = fmt.Sprintf("Failed to announce: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+// handleProviders handles POST requests to list the providers of a key
+func (s *HTTPServer) handleProviders(w http.ResponseWriter, r *http.Request) {
+	key, keyHashHex, ok := parseProviderRequest(w, r)
+	if !ok {
+		return
+	}
+
+	fmt.Printf("[HTTP-API] Providers request: hash=%s\n", keyHashHex[:16])
+
+	records, hopCount, err := s.Node.FindProviders(key)
+	if err != nil {
+		resp := ProvidersResponse{
+			Success:  false,
+			Message:  fmt.Sprintf("No providers: %v", err),
+			KeyHash:  keyHashHex,
+			HopCount: hopCount,
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(resp)
+		return
+	}
+
+	providers := make([]ProviderInfo, len(records))
+	for i, p := range records {
+		providers[i] = ProviderInfo{
+			PeerID:  p.ID.String(),
+			IP:      p.IP,
+			Port:    p.Port,
+			Expires: p.Expires,
+		}
+		if p.HTTPPort != 0 {
+			providers[i].HTTPURL = fmt.Sprintf("http://%s:%d", p.IP, p.HTTPPort)
+		}
+	}
+
+	resp := ProvidersResponse{
+		Success:   true,
+		KeyHash:   keyHashHex,
+		Providers: providers,
+		HopCount:  hopCount,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
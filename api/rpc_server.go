@@ -0,0 +1,384 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+
+	"connectrpc.com/connect"
+
+	"github.com/kutluhann/decentralized-file-sharing-system/constants"
+	"github.com/kutluhann/decentralized-file-sharing-system/dht"
+	"github.com/kutluhann/decentralized-file-sharing-system/encryption"
+	"github.com/kutluhann/decentralized-file-sharing-system/rpc/dfssv1"
+	"github.com/kutluhann/decentralized-file-sharing-system/rpc/dfssv1/dfssv1connect"
+)
+
+// ---------------------------------------------------------
+// RPC API
+// The NodeService of proto/dfss/v1/node.proto, served next to the
+// HTTP API with Connect, which also speaks gRPC and gRPC-Web. Run
+// `buf generate` after changing the proto to update rpc/dfssv1.
+// ---------------------------------------------------------
+
+// RPCServer implements the NodeService on top of a DHT node
+type RPCServer struct {
+	Node               *dht.Node
+	MaxUploadBytes     int64 // Largest value accepted by Put and Upload
+	ErasureDataShards  int   // Shards needed to rebuild an erasure-coded value
+	ErasureTotalShards int   // Shards stored per erasure-coded value
+}
+
+var _ dfssv1connect.NodeServiceHandler = (*RPCServer)(nil)
+
+// NewRPCServer creates the NodeService of a node
+func NewRPCServer(node *dht.Node) *RPCServer {
+	return &RPCServer{
+		Node:               node,
+		MaxUploadBytes:     constants.MaxUploadBytes,
+		ErasureDataShards:  node.Config.ErasureDataShards,
+		ErasureTotalShards: node.Config.ErasureTotalShards,
+	}
+}
+
+// rpcError turns a DHT error into a Connect error with a matching code
+func rpcError(err error) error {
+	switch {
+	case errors.Is(err, dht.ErrNotFound):
+		return connect.NewError(connect.CodeNotFound, err)
+	case errors.Is(err, encryption.ErrNotRecipient):
+		return connect.NewError(connect.CodePermissionDenied, err)
+	case errors.Is(err, dht.ErrValueTooLarge):
+		return connect.NewError(connect.CodeResourceExhausted, err)
+	default:
+		return connect.NewError(connect.CodeUnavailable, err)
+	}
+}
+
+// resolveRef returns the DHT key of a key reference
+func resolveRef(ref *dfssv1.KeyRef) (dht.NodeID, error) {
+	switch r := ref.GetRef().(type) {
+	case *dfssv1.KeyRef_Key:
+		if r.Key == "" {
+			break
+		}
+		return dht.NodeID(sha256.Sum256([]byte(r.Key))), nil
+	case *dfssv1.KeyRef_KeyHash:
+		if len(r.KeyHash) != constants.KeySizeBytes {
+			return dht.NodeID{}, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("key_hash must be %d bytes", constants.KeySizeBytes))
+		}
+		return dht.NodeID(r.KeyHash), nil
+	}
+	return dht.NodeID{}, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("a key or key_hash is required"))
+}
+
+// parsePKIXKeys parses DER encoded PKIX ECDSA public keys
+func parsePKIXKeys(keys [][]byte) ([]*ecdsa.PublicKey, error) {
+	parsed := make([]*ecdsa.PublicKey, 0, len(keys))
+	for _, der := range keys {
+		pub, err := x509.ParsePKIXPublicKey(der)
+		if err != nil {
+			return nil, fmt.Errorf("invalid recipient key: %v", err)
+		}
+		ecdsaPub, ok := pub.(*ecdsa.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("recipient key is not an ECDSA key")
+		}
+		parsed = append(parsed, ecdsaPub)
+	}
+	return parsed, nil
+}
+
+// store stores a whole value in the given mode. Plain values are chunked like an upload.
+// Returns: size, SHA256 of the value, error
+func (s *RPCServer) store(key dht.NodeID, value []byte, mode dfssv1.StoreMode, recipientKeys [][]byte) (int64, [32]byte, error) {
+	recipients, err := parsePKIXKeys(recipientKeys)
+	if err != nil {
+		return 0, [32]byte{}, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+	if limit := maxCodedBytes(s.ErasureDataShards); mode != dfssv1.StoreMode_STORE_MODE_UNSPECIFIED && int64(len(value)) > limit {
+		return 0, [32]byte{}, connect.NewError(connect.CodeResourceExhausted,
+			fmt.Errorf("erasure-coded and encrypted values are limited to %d bytes", limit))
+	}
+
+	size, hash := int64(len(value)), sha256.Sum256(value)
+	switch mode {
+	case dfssv1.StoreMode_STORE_MODE_UNSPECIFIED:
+		size, hash, err = s.Node.StoreStream(key, bytes.NewReader(value))
+	case dfssv1.StoreMode_STORE_MODE_ERASURE:
+		err = s.Node.StoreErasure(key, value, s.ErasureDataShards, s.ErasureTotalShards)
+	case dfssv1.StoreMode_STORE_MODE_ENCRYPTED, dfssv1.StoreMode_STORE_MODE_CONVERGENT:
+		err = s.Node.StoreEncrypted(key, value, recipients, mode == dfssv1.StoreMode_STORE_MODE_CONVERGENT)
+	default:
+		return 0, [32]byte{}, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("unknown store mode %v", mode))
+	}
+	if err != nil {
+		return 0, [32]byte{}, rpcError(err)
+	}
+	return size, hash, nil
+}
+
+// Put stores a value
+func (s *RPCServer) Put(ctx context.Context, req *connect.Request[dfssv1.PutRequest]) (*connect.Response[dfssv1.PutResponse], error) {
+	msg := req.Msg
+	if msg.Key == "" {
+		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("a key is required"))
+	}
+	if int64(len(msg.Value)) > s.MaxUploadBytes {
+		return nil, connect.NewError(connect.CodeResourceExhausted, fmt.Errorf("values are limited to %d bytes", s.MaxUploadBytes))
+	}
+
+	key := dht.NodeID(sha256.Sum256([]byte(msg.Key)))
+
+	fmt.Printf("[RPC] Put: key='%s' -> hash=%s, value_size=%d bytes\n", msg.Key, key.String()[:16], len(msg.Value))
+
+	size, hash, err := s.store(key, msg.Value, msg.Mode, msg.Recipients)
+	if err != nil {
+		return nil, err
+	}
+	return connect.NewResponse(&dfssv1.PutResponse{
+		KeyHash: key[:],
+		Size:    size,
+		Sha256:  hash[:],
+	}), nil
+}
+
+// Get retrieves a value
+func (s *RPCServer) Get(ctx context.Context, req *connect.Request[dfssv1.GetRequest]) (*connect.Response[dfssv1.GetResponse], error) {
+	key, err := resolveRef(req.Msg.Ref)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("[RPC] Get: hash=%s\n", key.String()[:16])
+
+	value, hopCount, err := s.Node.Retrieve(key)
+	if err != nil {
+		return nil, rpcError(err)
+	}
+	return connect.NewResponse(&dfssv1.GetResponse{
+		KeyHash:  key[:],
+		Value:    value,
+		HopCount: int32(hopCount),
+	}), nil
+}
+
+// Delete removes a value this node stored
+func (s *RPCServer) Delete(ctx context.Context, req *connect.Request[dfssv1.DeleteRequest]) (*connect.Response[dfssv1.DeleteResponse], error) {
+	if req.Msg.Key == "" {
+		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("a key is required"))
+	}
+	key := dht.NodeID(sha256.Sum256([]byte(req.Msg.Key)))
+
+	fmt.Printf("[RPC] Delete: key='%s' -> hash=%s\n", req.Msg.Key, key.String()[:16])
+
+	if err := s.Node.Delete(key); err != nil {
+		return nil, connect.NewError(connect.CodePermissionDenied, err)
+	}
+	return connect.NewResponse(&dfssv1.DeleteResponse{KeyHash: key[:]}), nil
+}
+
+// Status describes the node
+func (s *RPCServer) Status(ctx context.Context, req *connect.Request[dfssv1.StatusRequest]) (*connect.Response[dfssv1.StatusResponse], error) {
+	usage := s.Node.GetStorageUsage()
+	evictions := s.Node.GetEvictionStats()
+
+	knownPeers := 0
+	for _, bucket := range s.Node.GetRoutingTableInfo() {
+		knownPeers += len(bucket.Contacts)
+	}
+
+	var publicKey []byte
+	if s.Node.PrivKey != nil {
+		publicKey, _ = x509.MarshalPKIXPublicKey(&s.Node.PrivKey.PublicKey)
+	}
+
+	return connect.NewResponse(&dfssv1.StatusResponse{
+		NodeId:      s.Node.Self.ID[:],
+		PublicKey:   publicKey,
+		Ip:          s.Node.Self.IP,
+		Port:        int32(s.Node.Self.Port),
+		StoredKeys:  int64(usage.Keys),
+		StoredBytes: usage.Bytes,
+		QuotaBytes:  usage.MaxBytes,
+		Evictions: &dfssv1.EvictionStats{
+			Evicted:        int64(evictions.Evicted),
+			EvictedBytes:   evictions.EvictedBytes,
+			Cached:         int64(evictions.Cached),
+			NotResponsible: int64(evictions.NotResponsible),
+			Responsible:    int64(evictions.Responsible),
+			Refused:        int64(evictions.Refused),
+		},
+		KnownPeers: int64(knownPeers),
+	}), nil
+}
+
+// rpcContact converts a DHT contact
+func rpcContact(c dht.Contact) *dfssv1.Contact {
+	return &dfssv1.Contact{
+		Id:           c.ID[:],
+		Ip:           c.IP,
+		Port:         int32(c.Port),
+		LastSeenUnix: c.LastSeen.Unix(),
+	}
+}
+
+// RoutingTable lists the non-empty buckets of the routing table
+func (s *RPCServer) RoutingTable(ctx context.Context, req *connect.Request[dfssv1.RoutingTableRequest]) (*connect.Response[dfssv1.RoutingTableResponse], error) {
+	resp := &dfssv1.RoutingTableResponse{}
+	for _, bucket := range s.Node.GetRoutingTableInfo() {
+		b := &dfssv1.Bucket{Index: int32(bucket.Index)}
+		for _, c := range bucket.Contacts {
+			b.Contacts = append(b.Contacts, rpcContact(c))
+		}
+		resp.Buckets = append(resp.Buckets, b)
+	}
+	return connect.NewResponse(resp), nil
+}
+
+// Peers lists every contact in the routing table
+func (s *RPCServer) Peers(ctx context.Context, req *connect.Request[dfssv1.PeersRequest]) (*connect.Response[dfssv1.PeersResponse], error) {
+	resp := &dfssv1.PeersResponse{}
+	for _, bucket := range s.Node.GetRoutingTableInfo() {
+		for _, c := range bucket.Contacts {
+			resp.Peers = append(resp.Peers, rpcContact(c))
+		}
+	}
+	return connect.NewResponse(resp), nil
+}
+
+// uploadReader turns the chunks of an upload stream into a reader
+type uploadReader struct {
+	stream  *connect.ClientStream[dfssv1.UploadRequest]
+	current []byte
+	limit   int64 // Bytes still allowed
+}
+
+// errUploadTooLarge is returned by an uploadReader once the upload passes the limit
+var errUploadTooLarge = errors.New("upload too large")
+
+func (u *uploadReader) Read(p []byte) (int, error) {
+	for len(u.current) == 0 {
+		if !u.stream.Receive() {
+			if err := u.stream.Err(); err != nil {
+				return 0, err
+			}
+			return 0, io.EOF
+		}
+		chunk, ok := u.stream.Msg().Part.(*dfssv1.UploadRequest_Chunk)
+		if !ok {
+			return 0, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("only the first message may be a header"))
+		}
+		u.current = chunk.Chunk
+		u.limit -= int64(len(chunk.Chunk))
+		if u.limit < 0 {
+			return 0, errUploadTooLarge
+		}
+	}
+
+	count := copy(p, u.current)
+	u.current = u.current[count:]
+	return count, nil
+}
+
+// Upload stores a value sent as a header followed by chunks
+func (s *RPCServer) Upload(ctx context.Context, stream *connect.ClientStream[dfssv1.UploadRequest]) (*connect.Response[dfssv1.UploadResponse], error) {
+	if !stream.Receive() {
+		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("upload has no header: %v", stream.Err()))
+	}
+	header := stream.Msg().GetHeader()
+	if header == nil || header.Key == "" {
+		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("the first message must be a header with a key"))
+	}
+	key := dht.NodeID(sha256.Sum256([]byte(header.Key)))
+	limit := s.MaxUploadBytes
+	if header.Mode != dfssv1.StoreMode_STORE_MODE_UNSPECIFIED {
+		// Erasure coding and encryption need the whole value
+		limit = min(limit, maxCodedBytes(s.ErasureDataShards))
+	}
+	body := &uploadReader{stream: stream, limit: limit}
+
+	fmt.Printf("[RPC] Upload: key='%s' -> hash=%s\n", header.Key, key.String()[:16])
+
+	var size int64
+	var hash [32]byte
+	var err error
+	if header.Mode == dfssv1.StoreMode_STORE_MODE_UNSPECIFIED {
+		size, hash, err = s.Node.StoreStream(key, body)
+	} else {
+		var value []byte
+		if value, err = io.ReadAll(body); err == nil {
+			size, hash, err = s.store(key, value, header.Mode, header.Recipients)
+		}
+	}
+
+	var connectErr *connect.Error
+	switch {
+	case errors.Is(err, errUploadTooLarge) && header.Mode != dfssv1.StoreMode_STORE_MODE_UNSPECIFIED:
+		return nil, connect.NewError(connect.CodeResourceExhausted,
+			fmt.Errorf("erasure-coded and encrypted values are limited to %d bytes", limit))
+	case errors.Is(err, errUploadTooLarge):
+		return nil, connect.NewError(connect.CodeResourceExhausted, fmt.Errorf("values are limited to %d bytes", s.MaxUploadBytes))
+	case errors.As(err, &connectErr):
+		return nil, err
+	case err != nil:
+		return nil, rpcError(err)
+	}
+
+	fmt.Printf("[RPC] ✓ Uploaded %d bytes\n", size)
+
+	return connect.NewResponse(&dfssv1.UploadResponse{
+		KeyHash: key[:],
+		Size:    size,
+		Sha256:  hash[:],
+	}), nil
+}
+
+// Download streams a value as a header followed by chunks. The next chunks are
+// only fetched as fast as the client receives them.
+func (s *RPCServer) Download(ctx context.Context, req *connect.Request[dfssv1.DownloadRequest], stream *connect.ServerStream[dfssv1.DownloadResponse]) error {
+	key, err := resolveRef(req.Msg.Ref)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("[RPC] Download: hash=%s\n", key.String()[:16])
+
+	reader, err := s.Node.OpenStream(key)
+	if err != nil {
+		return rpcError(err)
+	}
+	defer reader.Close()
+
+	err = stream.Send(&dfssv1.DownloadResponse{Part: &dfssv1.DownloadResponse_Header{Header: &dfssv1.DownloadHeader{
+		KeyHash:  key[:],
+		Size:     reader.Size,
+		Sha256:   reader.Hash[:],
+		HopCount: int32(reader.HopCount),
+	}}})
+	if err != nil {
+		return err
+	}
+
+	chunk := make([]byte, s.Node.Config.StreamChunkBytes)
+	for {
+		count, err := io.ReadFull(reader, chunk)
+		if count > 0 {
+			sendErr := stream.Send(&dfssv1.DownloadResponse{Part: &dfssv1.DownloadResponse_Chunk{Chunk: chunk[:count]}})
+			if sendErr != nil {
+				return sendErr
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil
+		}
+		if err != nil {
+			return rpcError(err)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"connectrpc.com/connect"
+
+	"github.com/kutluhann/decentralized-file-sharing-system/api"
+	"github.com/kutluhann/decentralized-file-sharing-system/constants"
+	"github.com/kutluhann/decentralized-file-sharing-system/dht"
+	"github.com/kutluhann/decentralized-file-sharing-system/node"
+	"github.com/kutluhann/decentralized-file-sharing-system/rpc/dfssv1"
+	"github.com/kutluhann/decentralized-file-sharing-system/rpc/dfssv1/dfssv1connect"
+)
+
+// startNode starts a genesis node on a random local UDP port, without the HTTP API,
+// and closes it when the test ends
+func startNode(t *testing.T) *dht.Node {
+	t.Helper()
+
+	config := node.DefaultConfig()
+	config.Port = 0
+	config.HTTPPort = -1
+	config.Genesis = true
+	config.DHT.PlotDir = t.TempDir()
+	config.DHT.PosPrefixBits = 8 // A small plot still answers almost every challenge
+	config.DHT.PosNumEntries = 4096
+
+	n, err := node.New(config)
+	if err != nil {
+		t.Fatalf("Failed to create node: %v", err)
+	}
+	t.Cleanup(func() { n.Close() })
+	if err := n.Start(context.Background()); err != nil {
+		t.Fatalf("Failed to start node: %v", err)
+	}
+	return n.DHT
+}
+
+// startCluster starts n connected nodes
+func startCluster(t *testing.T, n int) []*dht.Node {
+	t.Helper()
+
+	nodes := make([]*dht.Node, n)
+	for i := range nodes {
+		nodes[i] = startNode(t)
+	}
+	for _, a := range nodes {
+		for _, b := range nodes {
+			if a != b {
+				a.RoutingTable.Update(b.Self)
+			}
+		}
+	}
+	return nodes
+}
+
+// startRPC serves the NodeService of node over HTTP/2 and returns a client using the given protocol option
+func startRPC(t *testing.T, node *dht.Node, maxUpload int64, opts ...connect.ClientOption) dfssv1connect.NodeServiceClient {
+	t.Helper()
+
+	rpcServer := api.NewRPCServer(node)
+	rpcServer.MaxUploadBytes = maxUpload
+	mux := http.NewServeMux()
+	mux.Handle(dfssv1connect.NewNodeServiceHandler(rpcServer))
+
+	server := httptest.NewUnstartedServer(mux)
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	return dfssv1connect.NewNodeServiceClient(server.Client(), server.URL, opts...)
+}
+
+// TestRPCPutGetDelete tests the unary calls of the NodeService
+func TestRPCPutGetDelete(t *testing.T) {
+	nodes := startCluster(t, 3)
+	client := startRPC(t, nodes[0], constants.MaxUploadBytes)
+	ctx := context.Background()
+
+	value := []byte{0, 1, 2, 0xff}
+	put, err := client.Put(ctx, connect.NewRequest(&dfssv1.PutRequest{Key: "doc", Value: value}))
+	if err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if hash := sha256.Sum256(value); !bytes.Equal(put.Msg.Sha256, hash[:]) || put.Msg.Size != int64(len(value)) {
+		t.Errorf("Unexpected Put response %v", put.Msg)
+	}
+
+	// Any node can serve the value, by key or by key hash
+	other := startRPC(t, nodes[1], constants.MaxUploadBytes)
+	for _, ref := range []*dfssv1.KeyRef{
+		{Ref: &dfssv1.KeyRef_Key{Key: "doc"}},
+		{Ref: &dfssv1.KeyRef_KeyHash{KeyHash: put.Msg.KeyHash}},
+	} {
+		got, err := other.Get(ctx, connect.NewRequest(&dfssv1.GetRequest{Ref: ref}))
+		if err != nil || !bytes.Equal(got.Msg.Value, value) {
+			t.Fatalf("Get returned %v, %v", got, err)
+		}
+	}
+
+	if _, err := client.Delete(ctx, connect.NewRequest(&dfssv1.DeleteRequest{Key: "doc"})); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	_, err = other.Get(ctx, connect.NewRequest(&dfssv1.GetRequest{Ref: &dfssv1.KeyRef{Ref: &dfssv1.KeyRef_Key{Key: "doc"}}}))
+	if connect.CodeOf(err) != connect.CodeNotFound {
+		t.Errorf("Expected NotFound after the delete, got %v", err)
+	}
+
+	// A value larger than a datagram is chunked like an upload
+	large := make([]byte, 2*constants.StreamChunkBytes+10)
+	rand.Read(large)
+	put, err = client.Put(ctx, connect.NewRequest(&dfssv1.PutRequest{Key: "large", Value: large}))
+	if hash := sha256.Sum256(large); err != nil || !bytes.Equal(put.Msg.Sha256, hash[:]) || put.Msg.Size != int64(len(large)) {
+		t.Fatalf("Put of a large value returned %v, %v", put, err)
+	}
+	if value, _, err := nodes[0].FindValue(dht.NodeID(sha256.Sum256([]byte("large")))); err != nil {
+		t.Fatalf("Large value not found: %v", err)
+	} else if _, ok := dht.ParseChunkManifest(value); !ok {
+		t.Errorf("Expected a chunk manifest for the large value")
+	}
+	got, err := other.Get(ctx, connect.NewRequest(&dfssv1.GetRequest{Ref: &dfssv1.KeyRef{Ref: &dfssv1.KeyRef_Key{Key: "large"}}}))
+	if err != nil || !bytes.Equal(got.Msg.Value, large) {
+		t.Fatalf("Get of the large value returned %d bytes, %v", len(got.Msg.GetValue()), err)
+	}
+
+	// An empty value is stored like any other
+	if _, err := client.Put(ctx, connect.NewRequest(&dfssv1.PutRequest{Key: "empty"})); err != nil {
+		t.Fatalf("Put of an empty value failed: %v", err)
+	}
+	got, err = other.Get(ctx, connect.NewRequest(&dfssv1.GetRequest{Ref: &dfssv1.KeyRef{Ref: &dfssv1.KeyRef_Key{Key: "empty"}}}))
+	if err != nil || len(got.Msg.Value) != 0 {
+		t.Errorf("Get of an empty value returned %v, %v", got, err)
+	}
+
+	status, err := client.Status(ctx, connect.NewRequest(&dfssv1.StatusRequest{}))
+	if err != nil || !bytes.Equal(status.Msg.NodeId, nodes[0].Self.ID[:]) || status.Msg.KnownPeers != 2 {
+		t.Errorf("Unexpected status %v, %v", status, err)
+	}
+	peers, err := client.Peers(ctx, connect.NewRequest(&dfssv1.PeersRequest{}))
+	if err != nil || len(peers.Msg.Peers) != 2 {
+		t.Errorf("Expected 2 peers, got %v, %v", peers, err)
+	}
+	table, err := client.RoutingTable(ctx, connect.NewRequest(&dfssv1.RoutingTableRequest{}))
+	if err != nil || len(table.Msg.Buckets) == 0 {
+		t.Errorf("Expected routing table buckets, got %v, %v", table, err)
+	}
+}
+
+// TestRPCUploadDownload tests streaming a chunked value over the gRPC protocol
+func TestRPCUploadDownload(t *testing.T) {
+	nodes := startCluster(t, 3)
+	maxUpload := int64(4 * constants.StreamChunkBytes)
+	client := startRPC(t, nodes[0], maxUpload, connect.WithGRPC())
+	ctx := context.Background()
+
+	value := make([]byte, 3*constants.StreamChunkBytes+100)
+	rand.Read(value)
+
+	upload := func(key string, value []byte) (*connect.Response[dfssv1.UploadResponse], error) {
+		stream := client.Upload(ctx)
+		stream.Send(&dfssv1.UploadRequest{Part: &dfssv1.UploadRequest_Header{Header: &dfssv1.UploadHeader{Key: key}}})
+		for start := 0; start < len(value); start += 10000 {
+			chunk := value[start:min(start+10000, len(value))]
+			if err := stream.Send(&dfssv1.UploadRequest{Part: &dfssv1.UploadRequest_Chunk{Chunk: chunk}}); err != nil {
+				break
+			}
+		}
+		return stream.CloseAndReceive()
+	}
+
+	uploaded, err := upload("video", value)
+	if err != nil || uploaded.Msg.Size != int64(len(value)) {
+		t.Fatalf("Upload returned %v, %v", uploaded, err)
+	}
+
+	other := startRPC(t, nodes[1], maxUpload, connect.WithGRPC())
+	stream, err := other.Download(ctx, connect.NewRequest(&dfssv1.DownloadRequest{
+		Ref: &dfssv1.KeyRef{Ref: &dfssv1.KeyRef_Key{Key: "video"}},
+	}))
+	if err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+	defer stream.Close()
+
+	var header *dfssv1.DownloadHeader
+	var downloaded bytes.Buffer
+	for stream.Receive() {
+		if h := stream.Msg().GetHeader(); h != nil {
+			header = h
+			continue
+		}
+		downloaded.Write(stream.Msg().GetChunk())
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatalf("Download stream failed: %v", err)
+	}
+	if header == nil || header.Size != int64(len(value)) || !bytes.Equal(header.Sha256, uploaded.Msg.Sha256) {
+		t.Errorf("Unexpected download header %v", header)
+	}
+	if !bytes.Equal(downloaded.Bytes(), value) {
+		t.Errorf("Downloaded %d bytes that differ from the upload", downloaded.Len())
+	}
+
+	_, err = upload("too-big", make([]byte, maxUpload+1))
+	var connectErr *connect.Error
+	if !errors.As(err, &connectErr) || connectErr.Code() != connect.CodeResourceExhausted {
+		t.Errorf("Expected ResourceExhausted for an upload past the limit, got %v", err)
+	}
+}
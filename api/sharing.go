@@ -0,0 +1,122 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/kutluhann/decentralized-file-sharing-system/dht"
+)
+
+// ShareRequest represents the JSON payload for granting or revoking read access
+type ShareRequest struct {
+	Key    string `json:"key"`     // Human-readable key of an encrypted value this node stored
+	PeerID string `json:"peer_id"` // Hex PeerID of the peer to grant or revoke
+}
+
+// ShareResponse represents the response after granting or revoking read access
+type ShareResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+	KeyHash string `json:"key_hash"`
+}
+
+// SharedFileInfo is an encrypted value another peer shared with this node
+type SharedFileInfo struct {
+	KeyHash string `json:"key_hash"` // Fetch it with /get and this key_hash
+	Owner   string `json:"owner"`    // PeerID of the owner
+	Size    int    `json:"size"`
+	Version uint64 `json:"version"`
+}
+
+// SharedWithMeResponse lists the values shared with this node
+type SharedWithMeResponse struct {
+	Success bool             `json:"success"`
+	Message string           `json:"message,omitempty"`
+	Files   []SharedFileInfo `json:"files"`
+}
+
+// handleGrant handles POST requests to give a peer read access to an encrypted value
+func (s *HTTPServer) handleGrant(w http.ResponseWriter, r *http.Request) {
+	s.handleShare(w, r, "Grant", s.Node.Grant)
+}
+
+// handleRevoke handles POST requests to take read access away from a peer, re-keying the value
+func (s *HTTPServer) handleRevoke(w http.ResponseWriter, r *http.Request) {
+	s.handleShare(w, r, "Revoke", s.Node.Revoke)
+}
+
+// handleShare parses a share request and applies action to it
+func (s *HTTPServer) handleShare(w http.ResponseWriter, r *http.Request, name string, action func(key, peerID dht.NodeID) error) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	var req ShareRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	peerID, err := dht.ParseNodeID(req.PeerID)
+	if err != nil || req.Key == "" {
+		http.Error(w, "A key and a hex peer_id are required", http.StatusBadRequest)
+		return
+	}
+
+	key := dht.NodeID(sha256.Sum256([]byte(req.Key)))
+
+	fmt.Printf("[HTTP-API] %s request: key='%s' peer=%s\n", name, req.Key, req.PeerID[:16])
+
+	resp := ShareResponse{
+		Success: true,
+		Message: fmt.Sprintf("%s succeeded", name),
+		KeyHash: key.String(),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := action(key, peerID); err != nil {
+		resp.Success = false
+		resp.Message = fmt.Sprintf("%s failed: %v", name, err)
+		w.WriteHeader(http.StatusForbidden)
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+// handleSharedWithMe handles GET requests to list the values other peers shared with this node
+func (s *HTTPServer) handleSharedWithMe(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	fmt.Printf("[HTTP-API] Shared-with-me request\n")
+
+	resp := SharedWithMeResponse{Success: true, Files: []SharedFileInfo{}}
+
+	w.Header().Set("Content-Type", "application/json")
+	shared, err := s.Node.SharedWithMe()
+	if err != nil {
+		resp.Success = false
+		resp.Message = fmt.Sprintf("Failed to list shared values: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	for _, f := range shared {
+		resp.Files = append(resp.Files, SharedFileInfo{
+			KeyHash: f.Key.String(),
+			Owner:   f.Owner.String(),
+			Size:    f.Size,
+			Version: f.Version,
+		})
+	}
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,318 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/kutluhann/decentralized-file-sharing-system/dht"
+	"github.com/kutluhann/decentralized-file-sharing-system/encryption"
+)
+
+// ---------------------------------------------------------
+// REST API v1
+// Values are sent and returned as raw bodies of any content type
+// under /v1/keys/{key}, where key is the human-readable key. The
+// ETag of a value is the SHA-256 of its bytes. Every error is a
+// JSON ErrorResponse with a machine-readable code.
+// ---------------------------------------------------------
+
+// Error codes of the v1 API
+const (
+	CodeBadRequest       = "bad_request"
+	CodeTooLarge         = "payload_too_large"
+	CodeNotFound         = "not_found"
+	CodeForbidden        = "forbidden"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeStoreFailed      = "store_failed"
+	CodeLookupFailed     = "lookup_failed"
+)
+
+// APIError describes why a v1 request failed
+type APIError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ErrorResponse is the body of every failed v1 request
+type ErrorResponse struct {
+	Error APIError `json:"error"`
+}
+
+// PutKeyResponse represents the response after storing a value with PUT /v1/keys/{key}
+type PutKeyResponse struct {
+	Key     string `json:"key"`
+	KeyHash string `json:"key_hash"`
+	Size    int64  `json:"size"`
+	ETag    string `json:"etag"`
+}
+
+// LookupNode is one of the nodes closest to a lookup target
+type LookupNode struct {
+	NodeID string `json:"node_id"`
+	IP     string `json:"ip"`
+	Port   int    `json:"port"` // UDP port of the DHT protocol
+}
+
+// LookupResponse lists the nodes closest to an ID, closest first, as found by GET /v1/lookup/{hash}
+type LookupResponse struct {
+	Target   string       `json:"target"`
+	Nodes    []LookupNode `json:"nodes"`
+	HopCount int          `json:"hop_count"`
+}
+
+// maxCodedBytes returns the largest value stored erasure coded or encrypted. Both
+// need the whole value in memory, and each erasure shard has to fit in a chunk.
+func maxCodedBytes(dataShards int) int64 {
+	return int64(dht.MaxErasureBytes(dataShards))
+}
+
+// registerV1 sets up the v1 routes
+func (s *HTTPServer) registerV1(mux *http.ServeMux) {
+	mux.HandleFunc("PUT /v1/keys/{key...}", s.handlePutKey)
+	mux.HandleFunc("GET /v1/keys/{key...}", s.handleGetKey)
+	mux.HandleFunc("DELETE /v1/keys/{key...}", s.handleDeleteKey)
+	mux.HandleFunc("GET /v1/hashes/{hash}", s.handleGetHash)
+	mux.HandleFunc("GET /v1/lookup/{hash}", s.handleLookup)
+	mux.HandleFunc("/v1/", s.handleV1Fallback)
+}
+
+// writeError writes a v1 error response
+func writeError(w http.ResponseWriter, status int, code string, format string, args ...any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{Error: APIError{Code: code, Message: fmt.Sprintf(format, args...)}})
+}
+
+// etagOf returns the strong ETag of a value from its SHA-256
+func etagOf(hash [32]byte) string {
+	return `"` + hex.EncodeToString(hash[:]) + `"`
+}
+
+// matchesETag reports whether an If-None-Match header matches etag
+func matchesETag(header string, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// queryBool parses an optional boolean query parameter
+func queryBool(r *http.Request, name string) (bool, error) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return false, nil
+	}
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, fmt.Errorf("%s must be a boolean", name)
+	}
+	return value, nil
+}
+
+// handlePutKey stores the raw request body. Query parameters select the storage
+// mode: erasure=true, or encrypt=true with optional convergent=true and one
+// recipient=<hex PKIX key> per extra reader.
+func (s *HTTPServer) handlePutKey(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+
+	var flags [3]bool
+	for i, name := range []string{"erasure", "encrypt", "convergent"} {
+		value, err := queryBool(r, name)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, CodeBadRequest, "%v", err)
+			return
+		}
+		flags[i] = value
+	}
+	erasure, encrypt, convergent := flags[0], flags[1], flags[2]
+	if erasure && encrypt {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "erasure and encrypt cannot be combined")
+		return
+	}
+	recipients, err := parsePublicKeys(r.URL.Query()["recipient"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "%v", err)
+		return
+	}
+
+	nodeID := dht.NodeID(sha256.Sum256([]byte(key)))
+	limit := s.MaxUploadBytes
+	if erasure || encrypt {
+		limit = min(limit, maxCodedBytes(s.ErasureDataShards))
+	}
+	body := http.MaxBytesReader(w, r.Body, limit)
+
+	fmt.Printf("[HTTP-API] v1 PUT: key='%s' -> hash=%s\n", key, nodeID.String()[:16])
+
+	// Plain values are stored chunk by chunk as the body arrives, erasure coding
+	// and encryption need the whole value and are limited to maxCodedBytes
+	var size int64
+	var hash [32]byte
+	if erasure || encrypt {
+		var value []byte
+		value, err = io.ReadAll(body)
+		if err == nil && encrypt {
+			err = s.Node.StoreEncrypted(nodeID, value, recipients, convergent)
+		} else if err == nil {
+			err = s.Node.StoreErasure(nodeID, value, s.ErasureDataShards, s.ErasureTotalShards)
+		}
+		size, hash = int64(len(value)), sha256.Sum256(value)
+	} else {
+		size, hash, err = s.Node.StoreStream(nodeID, body)
+	}
+
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge) && (erasure || encrypt):
+		writeError(w, http.StatusRequestEntityTooLarge, CodeTooLarge,
+			"erasure-coded and encrypted values are limited to %d bytes", tooLarge.Limit)
+		return
+	case errors.As(err, &tooLarge):
+		writeError(w, http.StatusRequestEntityTooLarge, CodeTooLarge, "values are limited to %d bytes", tooLarge.Limit)
+		return
+	case err != nil:
+		writeError(w, http.StatusServiceUnavailable, CodeStoreFailed, "failed to store: %v", err)
+		return
+	}
+
+	fmt.Printf("[HTTP-API] ✓ Stored %d bytes\n", size)
+
+	etag := etagOf(hash)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", etag)
+	json.NewEncoder(w).Encode(PutKeyResponse{
+		Key:     key,
+		KeyHash: nodeID.String(),
+		Size:    size,
+		ETag:    etag,
+	})
+}
+
+// handleGetKey returns the raw value of a human-readable key
+func (s *HTTPServer) handleGetKey(w http.ResponseWriter, r *http.Request) {
+	s.serveValue(w, r, dht.NodeID(sha256.Sum256([]byte(r.PathValue("key")))))
+}
+
+// handleGetHash returns the raw value of a hex key hash, e.g. one listed by /shared-with-me
+func (s *HTTPServer) handleGetHash(w http.ResponseWriter, r *http.Request) {
+	nodeID, err := dht.ParseNodeID(r.PathValue("hash"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "the hash must be 64 hex characters")
+		return
+	}
+	s.serveValue(w, r, nodeID)
+}
+
+// serveValue streams a value as the raw response body, honouring If-None-Match
+func (s *HTTPServer) serveValue(w http.ResponseWriter, r *http.Request, nodeID dht.NodeID) {
+	fmt.Printf("[HTTP-API] v1 GET: hash=%s\n", nodeID.String()[:16])
+
+	reader, err := s.Node.OpenStream(nodeID)
+	switch {
+	case errors.Is(err, dht.ErrNotFound):
+		writeError(w, http.StatusNotFound, CodeNotFound, "%v", err)
+		return
+	case errors.Is(err, encryption.ErrNotRecipient):
+		writeError(w, http.StatusForbidden, CodeForbidden, "%v", err)
+		return
+	case err != nil:
+		writeError(w, http.StatusBadGateway, CodeLookupFailed, "%v", err)
+		return
+	}
+	defer reader.Close()
+
+	etag := etagOf(reader.Hash)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("X-Key-Hash", nodeID.String())
+	w.Header().Set("X-Hop-Count", strconv.Itoa(reader.HopCount))
+	if header := r.Header.Get("If-None-Match"); header != "" && matchesETag(header, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.FormatInt(reader.Size, 10))
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	// Chunks are only fetched as fast as the client reads them. A chunk lost
+	// mid-stream cuts the response short of its Content-Length.
+	written, err := io.Copy(w, reader)
+	if err != nil {
+		fmt.Printf("[HTTP-API] ✗ Stream aborted after %d of %d bytes: %v\n", written, reader.Size, err)
+		return
+	}
+	fmt.Printf("[HTTP-API] ✓ Streamed %d bytes [hops: %d]\n", written, reader.HopCount)
+}
+
+// handleDeleteKey deletes a key this node stored
+func (s *HTTPServer) handleDeleteKey(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	nodeID := dht.NodeID(sha256.Sum256([]byte(key)))
+
+	fmt.Printf("[HTTP-API] v1 DELETE: key='%s' -> hash=%s\n", key, nodeID.String()[:16])
+
+	if err := s.Node.Delete(nodeID); err != nil {
+		writeError(w, http.StatusForbidden, CodeForbidden, "failed to delete: %v", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleLookup runs a node lookup for a hex ID, e.g. a key hash or a PeerID
+func (s *HTTPServer) handleLookup(w http.ResponseWriter, r *http.Request) {
+	target, err := dht.ParseNodeID(r.PathValue("hash"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeBadRequest, "the hash must be 64 hex characters")
+		return
+	}
+
+	fmt.Printf("[HTTP-API] v1 LOOKUP: hash=%s\n", target.String()[:16])
+
+	contacts, hopCount := s.Node.NodeLookup(target)
+	nodes := make([]LookupNode, len(contacts))
+	for i, c := range contacts {
+		nodes[i] = LookupNode{NodeID: c.ID.String(), IP: c.IP, Port: c.Port}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(LookupResponse{
+		Target:   target.String(),
+		Nodes:    nodes,
+		HopCount: hopCount,
+	})
+}
+
+// handleV1Fallback answers v1 requests no route matched with a JSON error
+func (s *HTTPServer) handleV1Fallback(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/v1/keys/"):
+		w.Header().Set("Allow", "GET, HEAD, PUT, DELETE")
+	case strings.HasPrefix(r.URL.Path, "/v1/hashes/"), strings.HasPrefix(r.URL.Path, "/v1/lookup/"):
+		w.Header().Set("Allow", "GET, HEAD")
+	default:
+		writeError(w, http.StatusNotFound, CodeNotFound, "no route for %s", r.URL.Path)
+		return
+	}
+	writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method %s is not allowed here", r.Method)
+}
+
+// deprecated marks a pre-v1 route, pointing clients to its successor
+func deprecated(successor string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", "<"+successor+`>; rel="successor-version"`)
+		handler(w, r)
+	}
+}
@@ -0,0 +1,422 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/kutluhann/decentralized-file-sharing-system/api"
+	"github.com/kutluhann/decentralized-file-sharing-system/dht"
+)
+
+// ---------------------------------------------------------
+// CLIENT
+// A typed client for the HTTP API of one or more nodes. Every
+// call goes to the node that last answered, and moves on to the
+// next node when a node can't be reached or fails with a 5xx.
+// Values are sent and received as streams, so files of any size
+// never need to fit in memory.
+// ---------------------------------------------------------
+
+// DefaultTimeout limits each attempt of a call that does not stream a value
+const DefaultTimeout = 30 * time.Second
+
+// ErrNotFound matches errors of lookups for a key no node holds
+var ErrNotFound = errors.New("not found")
+
+// Error is a request a node answered with an error status
+type Error struct {
+	Node    string // Base URL of the node
+	Status  int
+	Code    string // v1 error code, empty for the older routes
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("%s: %d %s: %s", e.Node, e.Status, e.Code, e.Message)
+	}
+	return fmt.Sprintf("%s: %d %s", e.Node, e.Status, e.Message)
+}
+
+// Is makes errors.Is(err, ErrNotFound) match not_found errors
+func (e *Error) Is(target error) bool {
+	return target == ErrNotFound && e.Status == http.StatusNotFound
+}
+
+// Client talks to the nodes at the given base URLs, e.g. http://localhost:8000
+type Client struct {
+	Nodes      []string
+	HTTPClient *http.Client
+	Timeout    time.Duration // Per attempt, 0 for none. Uploads and downloads are only bound by their context.
+
+	preferred atomic.Int32 // Index of the node that last answered
+}
+
+// New creates a client for the given nodes
+func New(nodes ...string) *Client {
+	trimmed := make([]string, len(nodes))
+	for i, node := range nodes {
+		trimmed[i] = strings.TrimSuffix(node, "/")
+	}
+	return &Client{
+		Nodes:      trimmed,
+		HTTPClient: &http.Client{},
+		Timeout:    DefaultTimeout,
+	}
+}
+
+// PutOptions select how a value is stored. Erasure and Encrypt cannot be combined.
+type PutOptions struct {
+	Erasure    bool
+	Encrypt    bool
+	Convergent bool     // With Encrypt, derive the key from the content so identical values deduplicate
+	Recipients []string // With Encrypt, hex PKIX public keys of the peers that may also read the value
+}
+
+func (o PutOptions) query() url.Values {
+	query := url.Values{}
+	if o.Erasure {
+		query.Set("erasure", "true")
+	}
+	if o.Encrypt {
+		query.Set("encrypt", "true")
+	}
+	if o.Convergent {
+		query.Set("convergent", "true")
+	}
+	for _, recipient := range o.Recipients {
+		query.Add("recipient", recipient)
+	}
+	return query
+}
+
+// call describes one request, sent to each node in turn until one answers
+type call struct {
+	method string
+	path   string // Escaped path with its query
+	body   io.Reader
+	header http.Header
+	stream bool // The response body is returned to the caller, so no per-attempt timeout
+
+	// retry reports whether an error status should be retried on the next node.
+	// By default only 5xx are retried.
+	retry func(status int) bool
+}
+
+// rewind prepares the request body for another attempt.
+// Returns false when the body can't be sent again.
+func (c *call) rewind(attempt int) bool {
+	if c.body == nil || attempt == 0 {
+		return true
+	}
+	seeker, ok := c.body.(io.Seeker)
+	if !ok {
+		return false
+	}
+	_, err := seeker.Seek(0, io.SeekStart)
+	return err == nil
+}
+
+// do sends a call and returns the first response with a 2xx or 3xx status.
+// The caller must close its body.
+func (c *Client) do(ctx context.Context, cl call) (*http.Response, error) {
+	if len(c.Nodes) == 0 {
+		return nil, fmt.Errorf("no nodes configured")
+	}
+	retry := cl.retry
+	if retry == nil {
+		retry = func(status int) bool { return status >= 500 }
+	}
+
+	start := int(c.preferred.Load())
+	var lastErr error
+	for attempt := range c.Nodes {
+		if ctx.Err() != nil {
+			break
+		}
+		// A body that can't be rewound was consumed by the previous attempt
+		if !cl.rewind(attempt) {
+			break
+		}
+
+		index := (start + attempt) % len(c.Nodes)
+		resp, err := c.send(ctx, c.Nodes[index], cl)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if resp.StatusCode >= 400 {
+			err := readError(c.Nodes[index], resp)
+			if retry(resp.StatusCode) {
+				lastErr = err
+				continue
+			}
+			return nil, err
+		}
+		c.preferred.Store(int32(index))
+		return resp, nil
+	}
+
+	if lastErr == nil {
+		lastErr = ctx.Err()
+	}
+	return nil, lastErr
+}
+
+// send makes one attempt of a call
+func (c *Client) send(ctx context.Context, node string, cl call) (*http.Response, error) {
+	cancel := context.CancelFunc(func() {})
+	if c.Timeout > 0 && !cl.stream {
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, cl.method, node+cl.path, cl.body)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	for name, values := range cl.header {
+		req.Header[name] = values
+	}
+	// The body is read by the next attempt, the request must not close it
+	if cl.body != nil {
+		req.Body = io.NopCloser(cl.body)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelBody releases the timeout of an attempt once its response is read
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// readError turns an error response into an *Error
+func readError(node string, resp *http.Response) error {
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+
+	e := &Error{Node: node, Status: resp.StatusCode}
+	var v1 api.ErrorResponse
+	if json.Unmarshal(body, &v1) == nil && v1.Error.Code != "" {
+		e.Code, e.Message = v1.Error.Code, v1.Error.Message
+	} else {
+		e.Message = strings.TrimSpace(string(body))
+	}
+	return e
+}
+
+// getJSON decodes the JSON response of a GET
+func (c *Client) getJSON(ctx context.Context, path string, v any) error {
+	resp, err := c.do(ctx, call{method: http.MethodGet, path: path})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// keyPath returns the v1 path of a human-readable key
+func keyPath(key string) string {
+	return "/v1/keys/" + url.PathEscape(key)
+}
+
+// hashPath returns the v1 path of a hex key hash
+func hashPath(keyHash string) string {
+	return "/v1/hashes/" + url.PathEscape(keyHash)
+}
+
+// KeyHash returns the hex DHT key a human-readable key is stored at
+func KeyHash(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
+
+// Put stores a value at key
+func (c *Client) Put(ctx context.Context, key string, value []byte, opts PutOptions) (*api.PutKeyResponse, error) {
+	return c.Upload(ctx, key, bytes.NewReader(value), opts)
+}
+
+// Upload stores the contents of r at key, streaming it to the node. Uploads
+// are only retried on another node when r is an io.Seeker, e.g. an *os.File.
+func (c *Client) Upload(ctx context.Context, key string, r io.Reader, opts PutOptions) (*api.PutKeyResponse, error) {
+	path := keyPath(key)
+	if query := opts.query(); len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	resp, err := c.do(ctx, call{
+		method: http.MethodPut,
+		path:   path,
+		body:   r,
+		header: http.Header{"Content-Type": {"application/octet-stream"}},
+		stream: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var put api.PutKeyResponse
+	if err := json.NewDecoder(resp.Body).Decode(&put); err != nil {
+		return nil, err
+	}
+	return &put, nil
+}
+
+// Get retrieves the whole value at key
+func (c *Client) Get(ctx context.Context, key string) ([]byte, error) {
+	value, err := c.Open(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer value.Close()
+	return io.ReadAll(value)
+}
+
+// Open starts streaming the value at key
+func (c *Client) Open(ctx context.Context, key string) (*Value, error) {
+	return c.open(ctx, keyPath(key))
+}
+
+// OpenHash starts streaming the value at a hex key hash, e.g. one listed by SharedWithMe
+func (c *Client) OpenHash(ctx context.Context, keyHash string) (*Value, error) {
+	return c.open(ctx, hashPath(keyHash))
+}
+
+func (c *Client) open(ctx context.Context, path string) (*Value, error) {
+	resp, err := c.do(ctx, call{method: http.MethodGet, path: path, stream: true})
+	if err != nil {
+		return nil, err
+	}
+	return &Value{
+		ValueInfo: valueInfo(resp),
+		body:      resp.Body,
+		verifier:  newVerifier(resp.Header.Get("ETag")),
+	}, nil
+}
+
+// Stat describes the value at key without downloading it
+func (c *Client) Stat(ctx context.Context, key string) (*ValueInfo, error) {
+	return c.stat(ctx, keyPath(key))
+}
+
+// StatHash describes the value at a hex key hash without downloading it
+func (c *Client) StatHash(ctx context.Context, keyHash string) (*ValueInfo, error) {
+	return c.stat(ctx, hashPath(keyHash))
+}
+
+func (c *Client) stat(ctx context.Context, path string) (*ValueInfo, error) {
+	resp, err := c.do(ctx, call{method: http.MethodHead, path: path})
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	info := valueInfo(resp)
+	return &info, nil
+}
+
+// valueInfo reads the description of a value from the headers of a response
+func valueInfo(resp *http.Response) ValueInfo {
+	hopCount, _ := strconv.Atoi(resp.Header.Get("X-Hop-Count"))
+	return ValueInfo{
+		KeyHash:  resp.Header.Get("X-Key-Hash"),
+		Size:     resp.ContentLength,
+		ETag:     resp.Header.Get("ETag"),
+		HopCount: hopCount,
+	}
+}
+
+// Delete deletes a key. Only the node that stored a value may delete it, so
+// every node is tried until one accepts.
+func (c *Client) Delete(ctx context.Context, key string) error {
+	resp, err := c.do(ctx, call{
+		method: http.MethodDelete,
+		path:   keyPath(key),
+		retry: func(status int) bool {
+			return status >= 500 || status == http.StatusForbidden
+		},
+	})
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// Status describes the node that answers
+func (c *Client) Status(ctx context.Context) (*api.StatusResponse, error) {
+	var status api.StatusResponse
+	if err := c.getJSON(ctx, "/status", &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// RoutingTable lists the non-empty buckets of the routing table of the node that answers
+func (c *Client) RoutingTable(ctx context.Context) ([]dht.BucketInfo, error) {
+	var buckets []dht.BucketInfo
+	if err := c.getJSON(ctx, "/routing-table", &buckets); err != nil {
+		return nil, err
+	}
+	return buckets, nil
+}
+
+// Peers lists every contact in the routing table of the node that answers
+func (c *Client) Peers(ctx context.Context) ([]dht.Contact, error) {
+	buckets, err := c.RoutingTable(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var peers []dht.Contact
+	for _, bucket := range buckets {
+		peers = append(peers, bucket.Contacts...)
+	}
+	return peers, nil
+}
+
+// Lookup finds the nodes closest to a hex key hash or PeerID, closest first
+func (c *Client) Lookup(ctx context.Context, keyHash string) (*api.LookupResponse, error) {
+	var lookup api.LookupResponse
+	if err := c.getJSON(ctx, "/v1/lookup/"+url.PathEscape(keyHash), &lookup); err != nil {
+		return nil, err
+	}
+	return &lookup, nil
+}
+
+// SharedWithMe lists the files other peers shared with the node that answers
+func (c *Client) SharedWithMe(ctx context.Context) ([]api.SharedFileInfo, error) {
+	var resp api.SharedWithMeResponse
+	if err := c.getJSON(ctx, "/shared-with-me", &resp); err != nil {
+		return nil, err
+	}
+	if !resp.Success {
+		return nil, fmt.Errorf("failed to list shared files: %s", resp.Message)
+	}
+	return resp.Files, nil
+}
@@ -0,0 +1,174 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/kutluhann/decentralized-file-sharing-system/api"
+	"github.com/kutluhann/decentralized-file-sharing-system/constants"
+	"github.com/kutluhann/decentralized-file-sharing-system/dht"
+	"github.com/kutluhann/decentralized-file-sharing-system/id_tools"
+)
+
+// startNodes starts n connected nodes, each serving the HTTP API, and returns their URLs
+func startNodes(t *testing.T, n int) []string {
+	t.Helper()
+
+	nodes := make([]*dht.Node, n)
+	urls := make([]string, n)
+	for i := range nodes {
+		privKey, peerID := id_tools.GenerateNewPID()
+		network, err := dht.NewNetwork("127.0.0.1:0", dht.NodeID(peerID))
+		if err != nil {
+			t.Fatalf("Failed to start network: %v", err)
+		}
+		t.Cleanup(func() { network.Close() })
+		self := dht.Contact{
+			ID:   dht.NodeID(peerID),
+			IP:   "127.0.0.1",
+			Port: network.Conn.LocalAddr().(*net.UDPAddr).Port,
+		}
+		nodes[i] = dht.NewNode(self, privKey)
+		nodes[i].Network = network
+		network.SetHandler(nodes[i])
+		go network.Listen()
+
+		server := httptest.NewServer(api.NewHTTPServer(nodes[i], 0).Handler())
+		t.Cleanup(server.Close)
+		urls[i] = server.URL
+	}
+	for _, a := range nodes {
+		for _, b := range nodes {
+			if a != b {
+				a.RoutingTable.Update(b.Self)
+			}
+		}
+	}
+	return urls
+}
+
+// deadURL returns the URL of a server that is no longer listening
+func deadURL() string {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	return server.URL
+}
+
+// TestClientRoundTrip tests every call against real nodes, behind a node that is down
+func TestClientRoundTrip(t *testing.T) {
+	urls := startNodes(t, 2)
+	c := New(deadURL(), urls[0])
+	ctx := context.Background()
+
+	put, err := c.Put(ctx, "notes/today", []byte("hello"), PutOptions{})
+	if err != nil || put.Size != 5 {
+		t.Fatalf("Put returned %v, %v", put, err)
+	}
+	value, err := c.Get(ctx, "notes/today")
+	if err != nil || string(value) != "hello" {
+		t.Fatalf("Get returned %q, %v", value, err)
+	}
+
+	// A file of several chunks is streamed both ways
+	dir := t.TempDir()
+	data := make([]byte, 2*constants.StreamChunkBytes+10)
+	rand.Read(data)
+	src := filepath.Join(dir, "src.bin")
+	os.WriteFile(src, data, 0o644)
+	if _, err := c.UploadFile(ctx, "file", src, PutOptions{}); err != nil {
+		t.Fatalf("UploadFile failed: %v", err)
+	}
+	// Erasure coding needs the whole value, so it is limited to a few chunks
+	var apiErr *Error
+	if _, err := c.Put(ctx, "coded", make([]byte, 8*constants.StreamChunkBytes), PutOptions{Erasure: true}); !errors.As(err, &apiErr) || apiErr.Status != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected 413 for a large erasure-coded value, got %v", err)
+	}
+	dst := filepath.Join(dir, "dst.bin")
+	written, err := New(urls[1]).DownloadFile(ctx, "file", dst)
+	if err != nil || written != int64(len(data)) {
+		t.Fatalf("DownloadFile returned %d, %v", written, err)
+	}
+	if got, _ := os.ReadFile(dst); !bytes.Equal(got, data) {
+		t.Errorf("Downloaded file differs from the upload")
+	}
+	info, err := c.Stat(ctx, "file")
+	if err != nil || info.Size != int64(len(data)) || info.KeyHash != KeyHash("file") || info.ETag == "" {
+		t.Errorf("Unexpected stat %+v, %v", info, err)
+	}
+	if _, err := c.StatHash(ctx, KeyHash("missing")); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for a missing key, got %v", err)
+	}
+	lookup, err := c.Lookup(ctx, KeyHash("file"))
+	if err != nil || len(lookup.Nodes) != 1 || lookup.Target != KeyHash("file") {
+		t.Errorf("Unexpected lookup %+v, %v", lookup, err)
+	}
+
+	status, err := c.Status(ctx)
+	if err != nil || status.KnownPeers != 1 {
+		t.Errorf("Unexpected status %v, %v", status, err)
+	}
+	peers, err := c.Peers(ctx)
+	if err != nil || len(peers) != 1 {
+		t.Errorf("Expected 1 peer, got %v, %v", peers, err)
+	}
+	if files, err := c.SharedWithMe(ctx); err != nil || len(files) != 0 {
+		t.Errorf("Expected no shared files, got %v, %v", files, err)
+	}
+
+	// The value belongs to the first node, the second refuses the delete
+	if err := New(urls[1], urls[0]).Delete(ctx, "notes/today"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := c.Get(ctx, "notes/today"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound after the delete, got %v", err)
+	}
+}
+
+// TestClientRetries tests which failures move on to the next node
+func TestClientRetries(t *testing.T) {
+	var calls atomic.Int32
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		io.Copy(io.Discard, r.Body)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{"error":{"code":"store_failed","message":"no peers"}}`))
+	}))
+	defer failing.Close()
+	working := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Method == http.MethodPut && string(body) != "value" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"key":"k","size":5}`))
+	}))
+	defer working.Close()
+
+	ctx := context.Background()
+	c := New(failing.URL, working.URL)
+	if _, err := c.Put(ctx, "k", []byte("value"), PutOptions{}); err != nil {
+		t.Fatalf("Put was not retried on the working node: %v", err)
+	}
+	// The node that answered is tried first from now on
+	if _, err := c.Put(ctx, "k", []byte("value"), PutOptions{}); err != nil || calls.Load() != 1 {
+		t.Errorf("Expected the working node to be preferred, got %d calls to the failing one, %v", calls.Load(), err)
+	}
+
+	// A reader that can't be rewound is only sent once
+	_, err := New(failing.URL, working.URL).Upload(ctx, "k", struct{ io.Reader }{strings.NewReader("value")}, PutOptions{})
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.Code != "store_failed" {
+		t.Errorf("Expected the store_failed error, got %v", err)
+	}
+}
@@ -0,0 +1,107 @@
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kutluhann/decentralized-file-sharing-system/api"
+)
+
+// ValueInfo describes a value without its content
+type ValueInfo struct {
+	KeyHash  string
+	Size     int64 // -1 when the node did not send a length
+	ETag     string
+	HopCount int
+}
+
+// Value streams a value from a node. Its SHA-256 is checked against the ETag
+// once the whole value was read. Close must be called when done.
+type Value struct {
+	ValueInfo
+
+	body     io.ReadCloser
+	verifier *verifier
+}
+
+func (v *Value) Read(p []byte) (int, error) {
+	count, err := v.body.Read(p)
+	v.verifier.Write(p[:count])
+	if err == io.EOF {
+		if verifyErr := v.verifier.check(); verifyErr != nil {
+			return count, verifyErr
+		}
+	}
+	return count, err
+}
+
+// Close stops the download
+func (v *Value) Close() error {
+	return v.body.Close()
+}
+
+// verifier hashes a value to compare it with its ETag, the quoted hex SHA-256
+type verifier struct {
+	hash     hash.Hash
+	expected string // Empty when the ETag can't be checked
+}
+
+func newVerifier(etag string) *verifier {
+	return &verifier{hash: sha256.New(), expected: strings.Trim(etag, `"`)}
+}
+
+func (v *verifier) Write(p []byte) {
+	v.hash.Write(p)
+}
+
+func (v *verifier) check() error {
+	if v.expected == "" {
+		return nil
+	}
+	if got := hex.EncodeToString(v.hash.Sum(nil)); got != v.expected {
+		return fmt.Errorf("value does not match its ETag")
+	}
+	return nil
+}
+
+// UploadFile stores the file at path under key
+func (c *Client) UploadFile(ctx context.Context, key string, path string, opts PutOptions) (*api.PutKeyResponse, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return c.Upload(ctx, key, file, opts)
+}
+
+// DownloadFile saves the value at key to path. The file is written next to
+// path and only renamed into place once the whole value was verified.
+func (c *Client) DownloadFile(ctx context.Context, key string, path string) (int64, error) {
+	value, err := c.Open(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	defer value.Close()
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.part")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+
+	written, err := io.Copy(tmp, value)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return written, err
+	}
+	return written, os.Rename(tmp.Name(), path)
+}
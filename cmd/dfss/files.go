@@ -0,0 +1,471 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"hash"
+	"io"
+	"net/url"
+	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kutluhann/decentralized-file-sharing-system/client"
+	"github.com/kutluhann/decentralized-file-sharing-system/dht"
+)
+
+// ---------------------------------------------------------
+// FILES
+// Commands storing and fetching files through the HTTP API of
+// a node, -node or DFSS_NODE, trying the next node of the list
+// when one is down. Every download is checked against the
+// SHA-256 the node announces and the one recorded at upload.
+// ---------------------------------------------------------
+
+// NodeEnv names the environment variable listing the nodes to talk to
+const NodeEnv = "DFSS_NODE"
+
+// defaultNode is the API of a node started with the default configuration
+const defaultNode = "http://localhost:8000"
+
+// nodeFlags adds the -node flag and returns the client it selects
+func nodeFlags(fs *flag.FlagSet) func() *client.Client {
+	nodes := os.Getenv(NodeEnv)
+	if nodes == "" {
+		nodes = defaultNode
+	}
+	fs.StringVar(&nodes, "node", nodes, "Comma-separated base URLs of the node APIs, "+NodeEnv+" otherwise")
+	return func() *client.Client {
+		return client.New(strings.Split(nodes, ",")...)
+	}
+}
+
+// stringList is a flag that can be given several times
+type stringList []string
+
+func (l *stringList) String() string     { return strings.Join(*l, ",") }
+func (l *stringList) Set(v string) error { *l = append(*l, v); return nil }
+
+// commandContext is cancelled by Ctrl+C, so transfers stop cleanly
+func commandContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt)
+}
+
+// describe turns an error into a message that says what to do about it
+func describe(err error) string {
+	var apiErr *client.Error
+	var urlErr *url.Error
+	switch {
+	case errors.Is(err, context.Canceled):
+		return "interrupted"
+	case errors.As(err, &apiErr):
+		message := apiErr.Message
+		if message == "" {
+			message = fmt.Sprintf("request failed with status %d", apiErr.Status)
+		}
+		return fmt.Sprintf("%s (node %s)", message, apiErr.Node)
+	case errors.As(err, &urlErr):
+		node := urlErr.URL
+		if u, parseErr := url.Parse(urlErr.URL); parseErr == nil {
+			node = u.Scheme + "://" + u.Host
+		}
+		return fmt.Sprintf("cannot reach node %s: %v\nIs the node running? Choose the node with -node or %s", node, urlErr.Err, NodeEnv)
+	}
+	return err.Error()
+}
+
+// notFound explains a lookup for a key no node holds
+func notFound(err error, key string) error {
+	if errors.Is(err, client.ErrNotFound) {
+		return fmt.Errorf("no file is stored at %q", key)
+	}
+	return err
+}
+
+// hashFile returns the hex SHA-256 of a file and rewinds it
+func hashFile(file *os.File) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func filePut(fs *flag.FlagSet, args []string) error {
+	newClient := nodeFlags(fs)
+	as := fs.String("as", "", "Key to store the file at, its name by default")
+	erasure := fs.Bool("erasure", false, "Store erasure coded shards instead of full replicas")
+	encrypt := fs.Bool("encrypt", false, "Encrypt the file, only the node and -recipient peers can read it")
+	convergent := fs.Bool("convergent", false, "With -encrypt, derive the key from the content so identical files deduplicate")
+	var recipients stringList
+	fs.Var(&recipients, "recipient", "With -encrypt, hex public key of another peer that may read the file, repeatable")
+	quiet := fs.Bool("q", false, "Do not show a progress bar")
+	args, err := parseArgs(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return fmt.Errorf("%s is a directory, only files can be stored", args[0])
+	}
+
+	key := *as
+	if key == "" {
+		key = filepath.Base(args[0])
+	}
+	sum, err := hashFile(file)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %v", args[0], err)
+	}
+
+	ctx, stop := commandContext()
+	defer stop()
+	bar := newProgress(key, info.Size(), *quiet)
+	put, err := newClient().Upload(ctx, key, &progressReader{r: file, progress: bar}, client.PutOptions{
+		Erasure:    *erasure,
+		Encrypt:    *encrypt,
+		Convergent: *convergent,
+		Recipients: recipients,
+	})
+	bar.finish()
+	if err != nil {
+		return err
+	}
+	if etag := strings.Trim(put.ETag, `"`); etag != sum {
+		return fmt.Errorf("node stored different content than %s: sha256 %s, expected %s", args[0], etag, sum)
+	}
+
+	idx, err := loadIndex()
+	if err == nil {
+		idx.Entries[key] = indexEntry{
+			Key:     key,
+			KeyHash: put.KeyHash,
+			Size:    put.Size,
+			SHA256:  sum,
+			Source:  args[0],
+			Stored:  time.Now().UTC().Truncate(time.Second),
+		}
+		err = idx.save()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "dfss: warning: %v\n", err)
+	}
+
+	fmt.Printf("Stored %s at key %q (%s)\n", args[0], key, formatBytes(put.Size))
+	fmt.Println("Key hash:", put.KeyHash)
+	fmt.Println("SHA-256: ", sum)
+	return nil
+}
+
+func fileGet(fs *flag.FlagSet, args []string) error {
+	newClient := nodeFlags(fs)
+	output := fs.String("o", "", "Output file, the last part of the key by default, - for standard output")
+	byHash := fs.Bool("hash", false, "KEY is a hex key hash, e.g. one listed by ls -shared")
+	expected := fs.String("sha256", "", "Expected SHA-256 of the content, the one recorded by put by default")
+	quiet := fs.Bool("q", false, "Do not show a progress bar")
+	args, err := parseArgs(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	key := args[0]
+
+	if *expected == "" && !*byHash {
+		if idx, err := loadIndex(); err == nil {
+			*expected = idx.Entries[key].SHA256
+		}
+	}
+	*expected = strings.ToLower(*expected)
+
+	ctx, stop := commandContext()
+	defer stop()
+	c := newClient()
+	var value *client.Value
+	if *byHash {
+		value, err = c.OpenHash(ctx, key)
+	} else {
+		value, err = c.Open(ctx, key)
+	}
+	if err != nil {
+		return notFound(err, key)
+	}
+	defer value.Close()
+
+	// Refuse a substituted value before downloading it, the content itself is
+	// checked against the ETag once it was read
+	if etag := strings.Trim(value.ETag, `"`); *expected != "" && etag != "" && etag != *expected {
+		return fmt.Errorf("node serves different content for %q: sha256 %s, expected %s", key, etag, *expected)
+	}
+
+	dst := *output
+	if dst == "" {
+		dst = pathOf(key, *byHash)
+	}
+	bar := newProgress(key, value.Size, *quiet)
+	src := &checkedReader{r: &progressReader{r: value, progress: bar}, hash: sha256.New(), expected: *expected}
+
+	var written int64
+	if dst == "-" {
+		written, err = io.Copy(os.Stdout, src)
+	} else {
+		written, err = saveFile(dst, src)
+	}
+	bar.finish()
+	if err != nil {
+		return fmt.Errorf("download of %q failed after %s: %w", key, formatBytes(written), err)
+	}
+	sum := hex.EncodeToString(src.hash.Sum(nil))
+
+	// With -o - the content is on standard output, the summary must not mix with it
+	summary := os.Stdout
+	if dst == "-" {
+		summary = os.Stderr
+		dst = "standard output"
+	}
+	fmt.Fprintf(summary, "Saved %q to %s (%s, %d hops)\n", key, dst, formatBytes(written), value.HopCount)
+	fmt.Fprintln(summary, "SHA-256 verified:", sum)
+	return nil
+}
+
+// pathOf returns the default file name of a download
+func pathOf(key string, byHash bool) string {
+	if byHash {
+		return key
+	}
+	name := path.Base(key)
+	if name == "/" || name == "." || name == ".." {
+		return "download"
+	}
+	return name
+}
+
+// checkedReader fails at the end of a download whose SHA-256 is not the
+// expected one, before saveFile moves it into place
+type checkedReader struct {
+	r        io.Reader
+	hash     hash.Hash
+	expected string // Empty when nothing was recorded
+}
+
+func (r *checkedReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.hash.Write(p[:n])
+	if err == io.EOF && r.expected != "" {
+		if sum := hex.EncodeToString(r.hash.Sum(nil)); sum != r.expected {
+			return n, fmt.Errorf("content does not match: sha256 %s, expected %s", sum, r.expected)
+		}
+	}
+	return n, err
+}
+
+// saveFile writes r next to path and renames it into place once complete
+func saveFile(path string, r io.Reader) (int64, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.part")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+	// CreateTemp makes the file private, a download is an ordinary file
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return 0, err
+	}
+
+	written, err := io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return written, err
+	}
+	return written, os.Rename(tmp.Name(), path)
+}
+
+func fileRm(fs *flag.FlagSet, args []string) error {
+	newClient := nodeFlags(fs)
+	args, err := parseArgs(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	key := args[0]
+
+	ctx, stop := commandContext()
+	defer stop()
+	if err := newClient().Delete(ctx, key); err != nil {
+		return err
+	}
+
+	if idx, err := loadIndex(); err == nil {
+		if _, ok := idx.Entries[key]; ok {
+			delete(idx.Entries, key)
+			if err := idx.save(); err != nil {
+				fmt.Fprintf(os.Stderr, "dfss: warning: %v\n", err)
+			}
+		}
+	}
+	fmt.Printf("Deleted %q\n", key)
+	return nil
+}
+
+func fileLs(fs *flag.FlagSet, args []string) error {
+	newClient := nodeFlags(fs)
+	shared := fs.Bool("shared", false, "Also list the files other peers shared with the node")
+	if _, err := parseArgs(fs, args, 0, 0); err != nil {
+		return err
+	}
+
+	idx, err := loadIndex()
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tSIZE\tSHA-256\tSTORED")
+	for _, e := range idx.sorted() {
+		fmt.Fprintf(w, "%s\t%s\t%.16s\t%s\n", e.Key, formatBytes(e.Size), e.SHA256, e.Stored.Local().Format("2006-01-02 15:04"))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if !*shared {
+		return nil
+	}
+
+	ctx, stop := commandContext()
+	defer stop()
+	files, err := newClient().SharedWithMe(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("\nShared with the node (get them with -hash):\n")
+	fmt.Fprintln(w, "KEY HASH\tSIZE\tOWNER\tVERSION")
+	for _, f := range files {
+		fmt.Fprintf(w, "%s\t%s\t%.16s\t%d\n", f.KeyHash, formatBytes(int64(f.Size)), f.Owner, f.Version)
+	}
+	return w.Flush()
+}
+
+func fileStat(fs *flag.FlagSet, args []string) error {
+	newClient := nodeFlags(fs)
+	byHash := fs.Bool("hash", false, "KEY is a hex key hash")
+	args, err := parseArgs(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	key := args[0]
+
+	ctx, stop := commandContext()
+	defer stop()
+	var info *client.ValueInfo
+	if *byHash {
+		info, err = newClient().StatHash(ctx, key)
+	} else {
+		info, err = newClient().Stat(ctx, key)
+	}
+	if err != nil {
+		return notFound(err, key)
+	}
+
+	sum := strings.Trim(info.ETag, `"`)
+	if !*byHash {
+		fmt.Printf("Key:      %s\n", key)
+	}
+	fmt.Printf("Key hash: %s\n", info.KeyHash)
+	fmt.Printf("Size:     %s (%d bytes)\n", formatBytes(info.Size), info.Size)
+	fmt.Printf("SHA-256:  %s\n", sum)
+	fmt.Printf("Hops:     %d\n", info.HopCount)
+
+	if idx, err := loadIndex(); err == nil && !*byHash {
+		if e, ok := idx.Entries[key]; ok {
+			match := "matches"
+			if e.SHA256 != sum {
+				match = "DIFFERS from"
+			}
+			fmt.Printf("Stored:   %s from %s, content %s the upload\n",
+				e.Stored.Local().Format("2006-01-02 15:04"), e.Source, match)
+		}
+	}
+	return nil
+}
+
+func filePeers(fs *flag.FlagSet, args []string) error {
+	newClient := nodeFlags(fs)
+	if _, err := parseArgs(fs, args, 0, 0); err != nil {
+		return err
+	}
+
+	ctx, stop := commandContext()
+	defer stop()
+	c := newClient()
+	status, err := c.Status(ctx)
+	if err != nil {
+		return err
+	}
+	peers, err := c.Peers(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Node %.16s at %s:%d, %s, %d peers\n", status.NodeID, status.IP, status.Port, status.NetworkStatus, len(peers))
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NODE ID\tADDRESS\tLAST SEEN")
+	for _, p := range peers {
+		fmt.Fprintf(w, "%s\t%s:%d\t%s ago\n", p.ID.String(), p.IP, p.Port, time.Since(p.LastSeen).Round(time.Second))
+	}
+	return w.Flush()
+}
+
+func fileLookup(fs *flag.FlagSet, args []string) error {
+	newClient := nodeFlags(fs)
+	byHash := fs.Bool("hash", false, "KEY is a hex key hash or PeerID")
+	args, err := parseArgs(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	keyHash := args[0]
+	if !*byHash {
+		keyHash = client.KeyHash(args[0])
+	}
+	target, err := dht.ParseNodeID(keyHash)
+	if err != nil {
+		return fmt.Errorf("invalid key hash %q: %v", keyHash, err)
+	}
+
+	ctx, stop := commandContext()
+	defer stop()
+	lookup, err := newClient().Lookup(ctx, keyHash)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Nodes closest to %s (%d hops):\n", lookup.Target, lookup.HopCount)
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "#\tNODE ID\tADDRESS\tCOMMON PREFIX")
+	for i, n := range lookup.Nodes {
+		prefix := "?"
+		if id, err := dht.ParseNodeID(n.NodeID); err == nil {
+			prefix = fmt.Sprintf("%d bits", target.PrefixLen(id))
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s:%d\t%s\n", i+1, n.NodeID, n.IP, n.Port, prefix)
+	}
+	return w.Flush()
+}
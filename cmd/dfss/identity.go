@@ -0,0 +1,275 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/kutluhann/decentralized-file-sharing-system/config"
+	"github.com/kutluhann/decentralized-file-sharing-system/id_tools"
+)
+
+// ---------------------------------------------------------
+// IDENTITY
+// Key files are the ones the node reads (-key), encrypted when
+// DFSS_KEY_PASSPHRASE or -passphrase-file is set. Public keys are
+// PKIX PEM and signatures are ASN.1 ECDSA over the SHA-256 of a
+// file, so both work with openssl as well.
+// ---------------------------------------------------------
+
+var identitySubcommands = []subcommand{
+	{"new", "", "Generate a key file and print its PeerID", identityNew},
+	{"show", "", "Print the PeerID and public key of a key file", identityShow},
+	{"export-pub", "", "Write the public key of a key file as PEM", identityExportPub},
+	{"verify", "PEERID", "Check that a key file or public key belongs to a PeerID", identityVerify},
+	{"sign", "FILE", "Sign a file, the signature goes to FILE.sig", identitySign},
+	{"verify-sig", "FILE [SIGNATURE]", "Verify the signature of a file, FILE.sig by default", identityVerifySig},
+}
+
+func runIdentity(_ *flag.FlagSet, args []string) error {
+	return dispatch("dfss identity", identitySubcommands, args)
+}
+
+// keyFlags adds the flags locating a key file and its passphrase
+func keyFlags(fs *flag.FlagSet) *config.IdentityConfig {
+	c := &config.IdentityConfig{}
+	fs.StringVar(&c.KeyFile, "key", id_tools.PrivateKeyFilePath, "Private key file")
+	fs.StringVar(&c.PassphraseFile, "passphrase-file", "", "File holding the passphrase, "+config.PassphraseEnv+" otherwise")
+	return c
+}
+
+// loadKey loads the key file of c
+func loadKey(c *config.IdentityConfig) (*ecdsa.PrivateKey, id_tools.PeerID, error) {
+	passphrase, err := c.Passphrase()
+	if err != nil {
+		return nil, id_tools.PeerID{}, err
+	}
+	key, peerID, err := id_tools.LoadPrivateKey(c.KeyFile, passphrase)
+	if errors.Is(err, id_tools.ErrPassphraseRequired) {
+		err = fmt.Errorf("%v: set %s or use -passphrase-file", err, config.PassphraseEnv)
+	}
+	return key, peerID, err
+}
+
+// loadPublicKey reads the public key file pubFile, or the public half of the key file of c
+func loadPublicKey(pubFile string, c *config.IdentityConfig) (*ecdsa.PublicKey, error) {
+	if pubFile == "" {
+		key, _, err := loadKey(c)
+		if err != nil {
+			return nil, err
+		}
+		return &key.PublicKey, nil
+	}
+
+	data, err := os.ReadFile(pubFile)
+	if err != nil {
+		return nil, err
+	}
+	return id_tools.ParsePublicKey(data)
+}
+
+func identityNew(fs *flag.FlagSet, args []string) error {
+	c := keyFlags(fs)
+	force := fs.Bool("force", false, "Replace an existing key file, its identity is lost")
+	if _, err := parseArgs(fs, args, 0, 0); err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(c.KeyFile); err == nil && !*force {
+		return fmt.Errorf("%s already exists, use -force to replace it or start the node with -rotate-key to move its identity", c.KeyFile)
+	}
+	passphrase, err := c.Passphrase()
+	if err != nil {
+		return err
+	}
+
+	key, peerID := id_tools.GenerateNewPID()
+	if err := id_tools.SavePrivateKey(c.KeyFile, key, passphrase); err != nil {
+		return err
+	}
+
+	encrypted := ""
+	if len(passphrase) > 0 {
+		encrypted = " (encrypted)"
+	}
+	fmt.Printf("Created %s%s\n", c.KeyFile, encrypted)
+	fmt.Println("PeerID:", peerID)
+	return nil
+}
+
+func identityShow(fs *flag.FlagSet, args []string) error {
+	c := keyFlags(fs)
+	if _, err := parseArgs(fs, args, 0, 0); err != nil {
+		return err
+	}
+
+	key, peerID, err := loadKey(c)
+	if err != nil {
+		return err
+	}
+	public, err := id_tools.EncodePublicKey(&key.PublicKey)
+	if err != nil {
+		return err
+	}
+
+	encryption := "not encrypted"
+	if data, err := os.ReadFile(c.KeyFile); err == nil {
+		if block, _ := pem.Decode(data); block != nil && block.Type == "ENCRYPTED PRIVATE KEY" {
+			encryption = "encrypted"
+		}
+	}
+	fmt.Printf("Key file:   %s (%s)\n", c.KeyFile, encryption)
+	fmt.Println("PeerID:    ", peerID)
+	if _, err := os.Stat(c.KeyFile + ".rotation"); err == nil {
+		fmt.Println("Rotation:   pending, published on the next start of the node")
+	}
+	fmt.Printf("Public key:\n%s", public)
+	return nil
+}
+
+func identityExportPub(fs *flag.FlagSet, args []string) error {
+	c := keyFlags(fs)
+	output := fs.String("o", "", "Output file, standard output by default")
+	if _, err := parseArgs(fs, args, 0, 0); err != nil {
+		return err
+	}
+
+	key, _, err := loadKey(c)
+	if err != nil {
+		return err
+	}
+	public, err := id_tools.EncodePublicKey(&key.PublicKey)
+	if err != nil {
+		return err
+	}
+
+	if *output == "" {
+		_, err = os.Stdout.Write(public)
+		return err
+	}
+	return os.WriteFile(*output, public, 0644)
+}
+
+func identityVerify(fs *flag.FlagSet, args []string) error {
+	c := keyFlags(fs)
+	pubFile := fs.String("pub", "", "Public key file to check instead of the key file")
+	args, err := parseArgs(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	peerID, err := id_tools.ParsePeerID(args[0])
+	if err != nil {
+		return err
+	}
+	source := *pubFile
+	if source == "" {
+		source = c.KeyFile
+
+		// A private key also has to sign, which catches a damaged key file
+		key, _, err := loadKey(c)
+		if err != nil {
+			return err
+		}
+		if !id_tools.VerifyIdentity(key, peerID) {
+			return fmt.Errorf("%s does not belong to PeerID %s", source, peerID)
+		}
+	} else {
+		publicKey, err := loadPublicKey(*pubFile, c)
+		if err != nil {
+			return err
+		}
+		if !id_tools.CheckPublicKeyMatchesPeerID(publicKey, peerID) {
+			return fmt.Errorf("%s does not belong to PeerID %s", source, peerID)
+		}
+	}
+
+	fmt.Printf("OK: %s belongs to PeerID %s\n", source, peerID)
+	return nil
+}
+
+func identitySign(fs *flag.FlagSet, args []string) error {
+	c := keyFlags(fs)
+	output := fs.String("o", "", "Signature file, FILE.sig by default")
+	args, err := parseArgs(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	key, peerID, err := loadKey(c)
+	if err != nil {
+		return err
+	}
+	file, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	signature, err := id_tools.SignFile(key, file)
+	if err != nil {
+		return fmt.Errorf("failed to sign %s: %v", args[0], err)
+	}
+	if *output == "" {
+		*output = args[0] + ".sig"
+	}
+	if err := os.WriteFile(*output, signature, 0644); err != nil {
+		return err
+	}
+
+	fmt.Printf("Signed %s as %s, signature in %s\n", args[0], peerID, *output)
+	return nil
+}
+
+func identityVerifySig(fs *flag.FlagSet, args []string) error {
+	c := keyFlags(fs)
+	pubFile := fs.String("pub", "", "Public key of the signer, the key file otherwise")
+	peer := fs.String("peer", "", "PeerID the signer must have")
+	args, err := parseArgs(fs, args, 1, 2)
+	if err != nil {
+		return err
+	}
+
+	publicKey, err := loadPublicKey(*pubFile, c)
+	if err != nil {
+		return err
+	}
+	signer := id_tools.GeneratePeerIDFromPublicKey(publicKey)
+	if *peer != "" {
+		peerID, err := id_tools.ParsePeerID(*peer)
+		if err != nil {
+			return err
+		}
+		if signer != peerID {
+			return fmt.Errorf("public key belongs to PeerID %s, not %s", signer, peerID)
+		}
+	}
+
+	sigFile := args[0] + ".sig"
+	if len(args) == 2 {
+		sigFile = args[1]
+	}
+	signature, err := os.ReadFile(sigFile)
+	if err != nil {
+		return err
+	}
+	file, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	valid, err := id_tools.VerifyFile(publicKey, file, signature)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %v", args[0], err)
+	}
+	if !valid {
+		return fmt.Errorf("BAD signature on %s", args[0])
+	}
+
+	fmt.Printf("Good signature on %s from PeerID %s\n", args[0], signer)
+	return nil
+}
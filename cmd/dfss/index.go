@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// IndexEnv names the environment variable overriding the path of the index
+const IndexEnv = "DFSS_INDEX"
+
+// indexEntry remembers a file stored from this machine
+type indexEntry struct {
+	Key     string    `json:"key"`
+	KeyHash string    `json:"key_hash"`
+	Size    int64     `json:"size"`
+	SHA256  string    `json:"sha256"` // Hex SHA-256 of the content, checked on download
+	Source  string    `json:"source"` // Path of the uploaded file
+	Stored  time.Time `json:"stored"`
+}
+
+// index is the list of files stored from this machine. The network has no
+// listing of its own, so `dfss ls` shows what `dfss put` recorded here.
+type index struct {
+	path    string
+	Entries map[string]indexEntry `json:"entries"` // By key
+}
+
+// indexPath returns the path of the index, in the user configuration directory by default
+func indexPath() (string, error) {
+	if path := os.Getenv(IndexEnv); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("no place for the file index, set %s: %v", IndexEnv, err)
+	}
+	return filepath.Join(dir, "dfss", "files.json"), nil
+}
+
+// loadIndex reads the index, an index that does not exist yet is empty
+func loadIndex() (*index, error) {
+	path, err := indexPath()
+	if err != nil {
+		return nil, err
+	}
+
+	idx := &index{path: path, Entries: map[string]indexEntry{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return idx, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file index: %v", err)
+	}
+	if err := json.Unmarshal(data, idx); err != nil {
+		return nil, fmt.Errorf("file index %s is damaged: %v", path, err)
+	}
+	if idx.Entries == nil {
+		idx.Entries = map[string]indexEntry{}
+	}
+	return idx, nil
+}
+
+// save writes the index, replacing the old one only once it is complete
+func (idx *index) save() error {
+	data, err := json.MarshalIndent(idx, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(idx.path), 0700); err != nil {
+		return fmt.Errorf("failed to save file index: %v", err)
+	}
+	tmp := idx.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to save file index: %v", err)
+	}
+	if err := os.Rename(tmp, idx.path); err != nil {
+		return fmt.Errorf("failed to save file index: %v", err)
+	}
+	return nil
+}
+
+// sorted returns the entries ordered by key
+func (idx *index) sorted() []indexEntry {
+	entries := make([]indexEntry, 0, len(idx.Entries))
+	for _, e := range idx.Entries {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+	return entries
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// dfss is the command line tool of the network. File commands talk to the
+// HTTP API of a running node, identity commands work on key files.
+
+var commands = []subcommand{
+	{"put", "FILE", "Store a file, its name is the key unless -as is given", filePut},
+	{"get", "KEY", "Download a file and verify its SHA-256", fileGet},
+	{"rm", "KEY", "Delete a file stored through the node", fileRm},
+	{"ls", "", "List the files stored from this machine and the ones shared with the node", fileLs},
+	{"stat", "KEY", "Show the size and hash of a file without downloading it", fileStat},
+	{"peers", "", "List the peers in the routing table of the node", filePeers},
+	{"lookup", "KEY", "Find the nodes closest to a key", fileLookup},
+	{"identity", "SUBCOMMAND", "Generate, inspect and export node identities", runIdentity},
+}
+
+func main() {
+	err := dispatch("dfss", commands, os.Args[1:])
+	switch {
+	case errors.Is(err, flag.ErrHelp):
+	case errors.Is(err, errUsage):
+		os.Exit(2)
+	case err != nil:
+		fmt.Fprintln(os.Stderr, "dfss:", describe(err))
+		os.Exit(1)
+	}
+}
+
+// errUsage is returned after the usage of a command was printed for wrong arguments
+var errUsage = errors.New("usage")
+
+// subcommand is one action of a command
+type subcommand struct {
+	name    string
+	args    string // Synopsis of the arguments after the flags
+	summary string
+	run     func(fs *flag.FlagSet, args []string) error
+}
+
+// dispatch runs the subcommand named by args[0], command is the name of the
+// program and the commands before it, e.g. "dfss identity"
+func dispatch(command string, subcommands []subcommand, args []string) error {
+	printUsage := func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s <command> [flags] [args]\n\nCommands:\n", command)
+		for _, s := range subcommands {
+			fmt.Fprintf(os.Stderr, "  %-12s %s\n", s.name, s.summary)
+		}
+	}
+	if len(args) == 0 {
+		printUsage()
+		return errUsage
+	}
+	if args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		printUsage()
+		return nil
+	}
+
+	for _, s := range subcommands {
+		if s.name != args[0] {
+			continue
+		}
+		fs := flag.NewFlagSet(command+" "+s.name, flag.ContinueOnError)
+		fs.Usage = func() {
+			fmt.Fprintf(os.Stderr, "Usage: %s %s [flags] %s\n\n%s\n", command, s.name, s.args, s.summary)
+			fs.PrintDefaults()
+		}
+		return s.run(fs, args[1:])
+	}
+
+	fmt.Fprintf(os.Stderr, "%s: unknown command %q\n\n", command, args[0])
+	printUsage()
+	return errUsage
+}
+
+// parseArgs parses the flags and checks that between min and max arguments follow them
+func parseArgs(fs *flag.FlagSet, args []string, min, max int) ([]string, error) {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil, err
+		}
+		return nil, errUsage
+	}
+	if fs.NArg() < min || fs.NArg() > max {
+		fs.Usage()
+		return nil, errUsage
+	}
+	return fs.Args(), nil
+}
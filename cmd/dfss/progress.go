@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// progressWidth is the number of characters of the bar itself
+const progressWidth = 30
+
+// progress draws a transfer on standard error. It stays silent when standard
+// error is not a terminal, so scripts only see the final result.
+type progress struct {
+	label string
+	total int64 // -1 when unknown
+	done  int64
+	start time.Time
+	drawn time.Time
+	quiet bool
+}
+
+func newProgress(label string, total int64, quiet bool) *progress {
+	if info, err := os.Stderr.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		quiet = true
+	}
+	return &progress{label: label, total: total, start: time.Now(), quiet: quiet}
+}
+
+// add counts n more bytes, redrawing at most 10 times a second
+func (p *progress) add(n int) {
+	p.done += int64(n)
+	if now := time.Now(); now.Sub(p.drawn) >= 100*time.Millisecond {
+		p.drawn = now
+		p.draw()
+	}
+}
+
+// reset starts over, e.g. when an upload is retried on another node
+func (p *progress) reset() {
+	p.done = 0
+	p.start = time.Now()
+}
+
+// finish draws the final state and ends the line
+func (p *progress) finish() {
+	if p.quiet {
+		return
+	}
+	p.draw()
+	fmt.Fprintln(os.Stderr)
+}
+
+func (p *progress) draw() {
+	if p.quiet {
+		return
+	}
+	rate := float64(p.done) / max(time.Since(p.start).Seconds(), 0.001)
+	if p.total <= 0 {
+		fmt.Fprintf(os.Stderr, "\r%s  %s  %s/s ", p.label, formatBytes(p.done), formatBytes(int64(rate)))
+		return
+	}
+
+	filled := int(float64(progressWidth) * float64(min(p.done, p.total)) / float64(p.total))
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", progressWidth-filled)
+	if filled < progressWidth {
+		bar = bar[:filled] + ">" + bar[filled+1:]
+	}
+	fmt.Fprintf(os.Stderr, "\r%s  [%s] %3d%%  %s/%s  %s/s ", p.label, bar,
+		100*p.done/p.total, formatBytes(p.done), formatBytes(p.total), formatBytes(int64(rate)))
+}
+
+// progressReader counts what is read through it. Seeking back to the start,
+// as the client does to retry an upload, starts the bar over.
+type progressReader struct {
+	r        io.Reader
+	progress *progress
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.progress.add(n)
+	return n, err
+}
+
+func (r *progressReader) Seek(offset int64, whence int) (int64, error) {
+	seeker, ok := r.r.(io.Seeker)
+	if !ok {
+		return 0, fmt.Errorf("upload cannot be restarted")
+	}
+	pos, err := seeker.Seek(offset, whence)
+	if err == nil && pos == 0 {
+		r.progress.reset()
+	}
+	return pos, err
+}
+
+// formatBytes returns a size in binary units, e.g. 1.5 MiB
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
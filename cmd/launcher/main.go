@@ -19,6 +19,7 @@ const (
 	StartUDPPort  = 9000             // Node 0 = 9000, Node 1 = 9001...
 	BootstrapAddr = "127.0.0.1:9000" // Address of Node 0 (Genesis)
 	ProjectRoot   = "../../"         // Path to the main.go file from here
+	StopTimeout   = 70 * time.Second // How long nodes get to hand off their keys on Ctrl+C
 )
 
 var cmds []*exec.Cmd
@@ -40,11 +41,7 @@ func main() {
 	go func() {
 		<-c
 		fmt.Println("\n[Launcher] Stopping all nodes...")
-		for _, cmd := range cmds {
-			if cmd.Process != nil {
-				cmd.Process.Kill()
-			}
-		}
+		stopNodes()
 		os.Exit(0)
 	}()
 
@@ -104,6 +101,8 @@ func startNode(id int, isGenesis bool, mainGoPath, frontendSrc string) {
 
 	cmd := exec.Command("go", args...)
 	cmd.Dir = nodeDir // Run INSIDE the node's folder (isolates private_key.pem)
+	// Own process group, so a signal reaches the node and not only `go run`
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
 
 	// D. Redirect Output to Log File
 	logFile, _ := os.Create(filepath.Join(nodeDir, "node.log"))
@@ -118,6 +117,34 @@ func startNode(id int, isGenesis bool, mainGoPath, frontendSrc string) {
 	fmt.Printf(" -> Node %d running (HTTP :%d / UDP :%d)\n", id, httpPort, udpPort)
 }
 
+// stopNodes sends SIGTERM to every node so it hands off its keys, and kills
+// the nodes still running after StopTimeout
+func stopNodes() {
+	exited := make(chan struct{})
+	go func() {
+		for _, cmd := range cmds {
+			if cmd.Process != nil {
+				syscall.Kill(-cmd.Process.Pid, syscall.SIGTERM)
+			}
+		}
+		for _, cmd := range cmds {
+			cmd.Wait()
+		}
+		close(exited)
+	}()
+
+	select {
+	case <-exited:
+	case <-time.After(StopTimeout):
+		fmt.Println("[Launcher] Nodes did not stop in time, killing them")
+		for _, cmd := range cmds {
+			if cmd.Process != nil {
+				syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+			}
+		}
+	}
+}
+
 // Recursive Copy Function
 func copyDir(src, dst string) error {
 	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
@@ -1,61 +1,475 @@
 package config
 
 import (
-	"crypto/ecdsa"
-	"sync"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/kutluhann/decentralized-file-sharing-system/constants"
+	"github.com/kutluhann/decentralized-file-sharing-system/dht"
 	"github.com/kutluhann/decentralized-file-sharing-system/id_tools"
+	"github.com/kutluhann/decentralized-file-sharing-system/node"
+	"gopkg.in/yaml.v3"
 )
 
-// Config is a simple in-memory for runtime configuration
-// (private keys, context, derived keys from env, etc).
+// ---------------------------------------------------------
+// CONFIGURATION
+// Every setting of a node in one typed structure. Values come
+// from the defaults, then a YAML file, then DFSS_* environment
+// variables (a .env file included), then command line flags;
+// each source overrides the ones before it. Wire format sizes
+// stay in the constants package since all nodes must agree.
+// ---------------------------------------------------------
+
+// EnvPrefix starts the name of every environment variable, e.g. DFSS_DHT_K
+const EnvPrefix = "DFSS"
+
+// Config is the configuration of a node
 type Config struct {
-	privateKey *ecdsa.PrivateKey
-	peerID     id_tools.PeerID
+	Network  NetworkConfig  `yaml:"network"`
+	DHT      DHTConfig      `yaml:"dht"`
+	Timeouts TimeoutConfig  `yaml:"timeouts"`
+	Storage  StorageConfig  `yaml:"storage"`
+	Stream   StreamConfig   `yaml:"stream"`
+	Erasure  ErasureConfig  `yaml:"erasure"`
+	PoS      PoSConfig      `yaml:"pos"`
+	API      APIConfig      `yaml:"api"`
+	Identity IdentityConfig `yaml:"identity"`
 }
 
-var (
-	config     *Config
-	configOnce sync.Once
-)
+// NetworkConfig describes how the node reaches the network
+type NetworkConfig struct {
+	Host      string `yaml:"host"`      // IP address peers reach this node at
+	Port      int    `yaml:"port"`      // UDP port of the DHT protocol, 0 picks a free port
+	Genesis   bool   `yaml:"genesis"`   // Start a new network instead of joining one
+	Bootstrap string `yaml:"bootstrap"` // UDP address (IP:Port) of a node to join through
+}
+
+// DHTConfig holds the protocol parameters
+type DHTConfig struct {
+	K                        int           `yaml:"k"`
+	DisjointPaths            int           `yaml:"disjoint_paths"`
+	ReplicationInterval      time.Duration `yaml:"replication_interval"`
+	ReplicationCheckInterval time.Duration `yaml:"replication_check_interval"`
+	SyncInterval             time.Duration `yaml:"sync_interval"`
+	ProviderInterval         time.Duration `yaml:"provider_interval"`
+	ProviderTTL              time.Duration `yaml:"provider_ttl"`
+	TombstoneTTL             time.Duration `yaml:"tombstone_ttl"`
+	CacheTTL                 time.Duration `yaml:"cache_ttl"`
+	CacheMinTTL              time.Duration `yaml:"cache_min_ttl"`
+	HotKeyWindow             time.Duration `yaml:"hot_key_window"`
+	HotKeyBuckets            int           `yaml:"hot_key_buckets"`
+	HotKeyThreshold          int           `yaml:"hot_key_threshold"`
+	HotKeyMaxReplicas        int           `yaml:"hot_key_max_replicas"`
+	HotKeyPushInterval       time.Duration `yaml:"hot_key_push_interval"`
+	HotKeyReplicaTTL         time.Duration `yaml:"hot_key_replica_ttl"`
+	SyncFanoutBits           int           `yaml:"sync_fanout_bits"` // Must match the other nodes
+	SyncLeafKeys             int           `yaml:"sync_leaf_keys"`
+	SyncPullBatch            int           `yaml:"sync_pull_batch"`
+	MaxProvidersPerKey       int           `yaml:"max_providers_per_key"`
+	MaxInboxNotices          int           `yaml:"max_inbox_notices"`
+	MaxOwnerNotices          int           `yaml:"max_owner_notices"`
+	InboxNoticeTTL           time.Duration `yaml:"inbox_notice_ttl"`
+}
+
+// TimeoutConfig holds how long the node waits for others
+type TimeoutConfig struct {
+	RPC      time.Duration `yaml:"rpc"`      // Response to a request
+	Join     time.Duration `yaml:"join"`     // Each step of the join handshake
+	Shutdown time.Duration `yaml:"shutdown"` // Active HTTP requests of a closing node
+	Leave    time.Duration `yaml:"leave"`    // Key handoff of a node shutting down on a signal
+}
+
+// StorageConfig bounds what peers can make the node store, 0 disables a limit
+type StorageConfig struct {
+	QuotaBytes         int64   `yaml:"quota_bytes"`
+	PeerQuotaBytes     int64   `yaml:"peer_quota_bytes"`
+	PeerQuotaRecords   int     `yaml:"peer_quota_records"`
+	PeerQuotaProviders int     `yaml:"peer_quota_providers"` // Keys a peer may be the provider of
+	PeerStoreRate      float64 `yaml:"peer_store_rate"`      // STORE requests per second
+	PeerStoreBurst     int     `yaml:"peer_store_burst"`     // STORE requests at once
+	EvictionHeadroom   int     `yaml:"eviction_headroom_percent"`
+}
+
+// StreamConfig holds how values are split into chunks when streamed
+type StreamConfig struct {
+	ChunkBytes int `yaml:"chunk_bytes"` // At most the size that fits in one datagram
+	Window     int `yaml:"window"`      // Chunks in flight
+}
+
+// ErasureConfig holds the shard counts of values stored erasure coded through the API
+type ErasureConfig struct {
+	DataShards  int `yaml:"data_shards"`
+	TotalShards int `yaml:"total_shards"`
+}
+
+// PoSConfig holds the Proof of Space parameters
+type PoSConfig struct {
+	PlotDir          string        `yaml:"plot_dir"`
+	PrefixBits       int           `yaml:"prefix_bits"`
+	NumEntries       int           `yaml:"num_entries"`
+	ChallengeTimeout time.Duration `yaml:"challenge_timeout"`
+}
+
+// APIConfig describes the HTTP and RPC API
+type APIConfig struct {
+	Port           int   `yaml:"port"` // 0 picks a free port, -1 disables the API
+	MaxUploadBytes int64 `yaml:"max_upload_bytes"`
+}
+
+// IdentityConfig locates the key file of the node. The passphrase of an encrypted
+// key file is read from PassphraseFile or the DFSS_KEY_PASSPHRASE variable, never
+// from the configuration itself.
+type IdentityConfig struct {
+	KeyFile        string `yaml:"key_file"`
+	PassphraseFile string `yaml:"passphrase_file"`
+	Rotate         bool   `yaml:"-"` // Replace the key before starting, only set with -rotate-key
+}
+
+// PassphraseEnv names the environment variable holding the key file passphrase
+const PassphraseEnv = EnvPrefix + "_KEY_PASSPHRASE"
+
+// Passphrase returns the passphrase of the key file, empty for an unencrypted one
+func (c IdentityConfig) Passphrase() ([]byte, error) {
+	if c.PassphraseFile != "" {
+		data, err := os.ReadFile(c.PassphraseFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read passphrase file: %v", err)
+		}
+		return bytes.TrimRight(data, "\r\n"), nil
+	}
+	return []byte(os.Getenv(PassphraseEnv)), nil
+}
+
+// Default returns the built-in configuration
+func Default() Config {
+	n := node.DefaultConfig()
+	return Config{
+		Network: NetworkConfig{
+			Host: n.Host,
+			Port: n.Port,
+		},
+		DHT: DHTConfig{
+			K:                        n.DHT.K,
+			DisjointPaths:            n.DHT.DisjointPaths,
+			ReplicationInterval:      n.DHT.ReplicationInterval,
+			ReplicationCheckInterval: n.DHT.ReplicationCheckInterval,
+			SyncInterval:             n.DHT.SyncInterval,
+			ProviderInterval:         n.DHT.ProviderInterval,
+			ProviderTTL:              n.DHT.ProviderTTL,
+			TombstoneTTL:             n.DHT.TombstoneTTL,
+			CacheTTL:                 n.DHT.CacheTTL,
+			CacheMinTTL:              n.DHT.CacheMinTTL,
+			HotKeyWindow:             n.DHT.HotKeyWindow,
+			HotKeyBuckets:            n.DHT.HotKeyBuckets,
+			HotKeyThreshold:          n.DHT.HotKeyThreshold,
+			HotKeyMaxReplicas:        n.DHT.HotKeyMaxReplicas,
+			HotKeyPushInterval:       n.DHT.HotKeyPushInterval,
+			HotKeyReplicaTTL:         n.DHT.HotKeyReplicaTTL,
+			SyncFanoutBits:           n.DHT.SyncFanoutBits,
+			SyncLeafKeys:             n.DHT.SyncLeafKeys,
+			SyncPullBatch:            n.DHT.SyncPullBatch,
+			MaxProvidersPerKey:       n.DHT.MaxProvidersPerKey,
+			MaxInboxNotices:          n.DHT.MaxInboxNotices,
+			MaxOwnerNotices:          n.DHT.MaxOwnerNotices,
+			InboxNoticeTTL:           n.DHT.InboxNoticeTTL,
+		},
+		Timeouts: TimeoutConfig{
+			RPC:      n.DHT.RPCTimeout,
+			Join:     n.DHT.JoinTimeout,
+			Shutdown: n.ShutdownTimeout,
+			Leave:    constants.LeaveTimeout * time.Second,
+		},
+		Storage: StorageConfig{
+			QuotaBytes:         n.Limits.MaxBytes,
+			PeerQuotaBytes:     n.Limits.PeerMaxBytes,
+			PeerQuotaRecords:   n.Limits.PeerMaxRecords,
+			PeerQuotaProviders: n.Limits.PeerMaxProviders,
+			PeerStoreRate:      n.Limits.PeerRate,
+			PeerStoreBurst:     n.Limits.PeerBurst,
+			EvictionHeadroom:   n.DHT.EvictionHeadroomPercent,
+		},
+		Stream: StreamConfig{
+			ChunkBytes: n.DHT.StreamChunkBytes,
+			Window:     n.DHT.StreamWindow,
+		},
+		Erasure: ErasureConfig{
+			DataShards:  n.DHT.ErasureDataShards,
+			TotalShards: n.DHT.ErasureTotalShards,
+		},
+		PoS: PoSConfig{
+			PlotDir:          n.DHT.PlotDir,
+			PrefixBits:       n.DHT.PosPrefixBits,
+			NumEntries:       n.DHT.PosNumEntries,
+			ChallengeTimeout: n.DHT.PosChallengeTimeout,
+		},
+		API: APIConfig{
+			Port:           n.HTTPPort,
+			MaxUploadBytes: n.MaxUploadBytes,
+		},
+		Identity: IdentityConfig{
+			KeyFile: id_tools.PrivateKeyFilePath,
+		},
+	}
+}
 
-func Init() *Config {
-	configOnce.Do(func() {
+// Load builds the configuration from the defaults, the file given with
+// -config or DFSS_CONFIG, the environment and the command line arguments,
+// and validates it
+func Load(args []string) (Config, error) {
+	godotenv.Load()
 
-		godotenv.Load()
+	c := Default()
+	fs, apply := c.flagSet()
+	if err := fs.Parse(args); err != nil {
+		return Config{}, err
+	}
 
-		config = &Config{
-			privateKey: nil,
-			peerID:     id_tools.PeerID{},
+	path := os.Getenv(EnvPrefix + "_CONFIG")
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "config" {
+			path = f.Value.String()
 		}
 	})
-	return config
+	if path != "" {
+		if err := c.LoadFile(path); err != nil {
+			return Config{}, err
+		}
+	}
+	if err := c.LoadEnv(os.LookupEnv); err != nil {
+		return Config{}, err
+	}
+	apply()
+
+	if err := c.Validate(); err != nil {
+		return Config{}, err
+	}
+	return c, nil
 }
 
-func GetConfig() *Config {
-	if config == nil {
-		return Init()
+// LoadFile overrides the settings present in a YAML file. Unknown keys are an error.
+func (c *Config) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %v", err)
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("invalid config file %s: %v", path, err)
 	}
-	return config
+	return nil
 }
 
-func (c *Config) SetPrivateKey(key *ecdsa.PrivateKey) {
-	c.privateKey = key
+// LoadEnv overrides the settings that have an environment variable. The
+// variable of a setting is EnvPrefix, its section and its key in upper case,
+// e.g. DFSS_STORAGE_QUOTA_BYTES for storage.quota_bytes.
+func (c *Config) LoadEnv(lookup func(string) (string, bool)) error {
+	sections := reflect.ValueOf(c).Elem()
+	for i := 0; i < sections.NumField(); i++ {
+		section := sections.Field(i)
+		sectionName := sections.Type().Field(i).Tag.Get("yaml")
+		for j := 0; j < section.NumField(); j++ {
+			key := section.Type().Field(j).Tag.Get("yaml")
+			if key == "-" {
+				continue
+			}
+			name := strings.ToUpper(EnvPrefix + "_" + sectionName + "_" + key)
+			value, ok := lookup(name)
+			if !ok {
+				continue
+			}
+			if err := setValue(section.Field(j), value); err != nil {
+				return fmt.Errorf("invalid %s: %v", name, err)
+			}
+		}
+	}
+	return nil
 }
 
-func (c *Config) GetPrivateKey() *ecdsa.PrivateKey {
-	return c.privateKey
+// setValue parses a string into a setting of any of the types Config uses
+func setValue(field reflect.Value, value string) error {
+	if field.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		i, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		field.SetInt(i)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+	return nil
 }
 
-func (c *Config) HasPrivateKey() bool {
-	return c.privateKey != nil
+// flagSet returns the command line flags of a node. Flags only override the
+// configuration when apply is called, and only those that were set.
+func (c *Config) flagSet() (*flag.FlagSet, func()) {
+	fs := flag.NewFlagSet("dfss", flag.ContinueOnError)
+	fs.String("config", "", "YAML configuration file (also DFSS_CONFIG)")
+	genesis := fs.Bool("genesis", c.Network.Genesis, "Start as a Genesis Node (no bootstrap)")
+	host := fs.String("host", c.Network.Host, "IP address peers reach this node at")
+	port := fs.Int("port", c.Network.Port, "UDP port to listen on")
+	httpPort := fs.Int("http", c.API.Port, "HTTP API port for client requests, -1 disables it")
+	bootstrap := fs.String("bootstrap", c.Network.Bootstrap, "Bootstrap Node IP:Port (e.g. 127.0.0.1:8080)")
+	paths := fs.Int("paths", c.DHT.DisjointPaths, "Number of disjoint lookup paths (S/Kademlia)")
+	quota := fs.Int64("quota", c.Storage.QuotaBytes/(1024*1024), "Storage quota in MiB, 0 for unlimited")
+	maxUpload := fs.Int64("max-upload", c.API.MaxUploadBytes/(1024*1024), "Largest value the HTTP API accepts, in MiB")
+	dataShards := fs.Int("erasure-data", c.Erasure.DataShards, "Shards needed to rebuild an erasure-coded value")
+	totalShards := fs.Int("erasure-total", c.Erasure.TotalShards, "Shards stored per erasure-coded value")
+	chunkBytes := fs.Int("chunk-bytes", c.Stream.ChunkBytes, "Chunk size of streamed values in bytes")
+	plotDir := fs.String("plot-dir", c.PoS.PlotDir, "Directory of the Proof of Space plot")
+	keyFile := fs.String("key", c.Identity.KeyFile, "Private key file of the node identity")
+	rotate := fs.Bool("rotate-key", false, "Replace the identity key and publish the rotation signed by the old one")
+
+	apply := func() {
+		fs.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "genesis":
+				c.Network.Genesis = *genesis
+			case "host":
+				c.Network.Host = *host
+			case "port":
+				c.Network.Port = *port
+			case "http":
+				c.API.Port = *httpPort
+			case "bootstrap":
+				c.Network.Bootstrap = *bootstrap
+			case "paths":
+				c.DHT.DisjointPaths = *paths
+			case "quota":
+				c.Storage.QuotaBytes = *quota * 1024 * 1024
+			case "max-upload":
+				c.API.MaxUploadBytes = *maxUpload * 1024 * 1024
+			case "erasure-data":
+				c.Erasure.DataShards = *dataShards
+			case "erasure-total":
+				c.Erasure.TotalShards = *totalShards
+			case "chunk-bytes":
+				c.Stream.ChunkBytes = *chunkBytes
+			case "plot-dir":
+				c.PoS.PlotDir = *plotDir
+			case "key":
+				c.Identity.KeyFile = *keyFile
+			case "rotate-key":
+				c.Identity.Rotate = *rotate
+			}
+		})
+	}
+	return fs, apply
 }
 
-func (c *Config) GetPeerID() id_tools.PeerID {
-	return c.peerID
+// Validate returns the first setting that is out of range, or nil
+func (c Config) Validate() error {
+	switch {
+	case c.Network.Host == "":
+		return fmt.Errorf("network.host must not be empty")
+	case c.Network.Port < 0 || c.Network.Port > 65535:
+		return fmt.Errorf("network.port %d is not a valid port", c.Network.Port)
+	case !c.Network.Genesis && c.Network.Bootstrap == "":
+		return fmt.Errorf("network.bootstrap is required unless network.genesis is set")
+	case c.API.Port < -1 || c.API.Port > 65535:
+		return fmt.Errorf("api.port %d is not a valid port", c.API.Port)
+	case c.API.MaxUploadBytes <= 0:
+		return fmt.Errorf("api.max_upload_bytes must be positive")
+	case c.Storage.QuotaBytes < 0 || c.Storage.PeerQuotaBytes < 0 || c.Storage.PeerQuotaRecords < 0 ||
+		c.Storage.PeerQuotaProviders < 0:
+		return fmt.Errorf("storage quotas must not be negative, use 0 for unlimited")
+	case c.Storage.PeerStoreRate < 0 || c.Storage.PeerStoreBurst < 0:
+		return fmt.Errorf("storage rate limits must not be negative, use 0 for unlimited")
+	case c.Timeouts.Shutdown <= 0 || c.Timeouts.Leave <= 0:
+		return fmt.Errorf("timeouts.shutdown and timeouts.leave must be positive")
+	case c.Identity.KeyFile == "":
+		return fmt.Errorf("identity.key_file must not be empty")
+	}
+	return c.Node().DHT.Validate()
 }
 
-func (c *Config) SetPeerID(pid id_tools.PeerID) {
-	c.peerID = pid
+// Node returns the configuration of the node package. The identity is left
+// to the caller.
+func (c Config) Node() node.Config {
+	return node.Config{
+		Host:      c.Network.Host,
+		Port:      c.Network.Port,
+		HTTPPort:  c.API.Port,
+		Genesis:   c.Network.Genesis,
+		Bootstrap: c.Network.Bootstrap,
+		DHT: dht.Config{
+			K:                        c.DHT.K,
+			DisjointPaths:            c.DHT.DisjointPaths,
+			RPCTimeout:               c.Timeouts.RPC,
+			JoinTimeout:              c.Timeouts.Join,
+			ReplicationInterval:      c.DHT.ReplicationInterval,
+			ReplicationCheckInterval: c.DHT.ReplicationCheckInterval,
+			SyncInterval:             c.DHT.SyncInterval,
+			ProviderInterval:         c.DHT.ProviderInterval,
+			ProviderTTL:              c.DHT.ProviderTTL,
+			TombstoneTTL:             c.DHT.TombstoneTTL,
+			CacheTTL:                 c.DHT.CacheTTL,
+			CacheMinTTL:              c.DHT.CacheMinTTL,
+			HotKeyWindow:             c.DHT.HotKeyWindow,
+			HotKeyBuckets:            c.DHT.HotKeyBuckets,
+			HotKeyThreshold:          c.DHT.HotKeyThreshold,
+			HotKeyMaxReplicas:        c.DHT.HotKeyMaxReplicas,
+			HotKeyPushInterval:       c.DHT.HotKeyPushInterval,
+			HotKeyReplicaTTL:         c.DHT.HotKeyReplicaTTL,
+			SyncFanoutBits:           c.DHT.SyncFanoutBits,
+			SyncLeafKeys:             c.DHT.SyncLeafKeys,
+			SyncPullBatch:            c.DHT.SyncPullBatch,
+			MaxProvidersPerKey:       c.DHT.MaxProvidersPerKey,
+			EvictionHeadroomPercent:  c.Storage.EvictionHeadroom,
+			MaxInboxNotices:          c.DHT.MaxInboxNotices,
+			MaxOwnerNotices:          c.DHT.MaxOwnerNotices,
+			InboxNoticeTTL:           c.DHT.InboxNoticeTTL,
+			ErasureDataShards:        c.Erasure.DataShards,
+			ErasureTotalShards:       c.Erasure.TotalShards,
+			StreamChunkBytes:         c.Stream.ChunkBytes,
+			StreamWindow:             c.Stream.Window,
+			PlotDir:                  c.PoS.PlotDir,
+			PosPrefixBits:            c.PoS.PrefixBits,
+			PosNumEntries:            c.PoS.NumEntries,
+			PosChallengeTimeout:      c.PoS.ChallengeTimeout,
+		},
+		Limits: dht.StoreLimits{
+			MaxBytes:         c.Storage.QuotaBytes,
+			PeerMaxBytes:     c.Storage.PeerQuotaBytes,
+			PeerMaxRecords:   c.Storage.PeerQuotaRecords,
+			PeerMaxProviders: c.Storage.PeerQuotaProviders,
+			PeerRate:         c.Storage.PeerStoreRate,
+			PeerBurst:        c.Storage.PeerStoreBurst,
+		},
+		MaxUploadBytes:  c.API.MaxUploadBytes,
+		ShutdownTimeout: c.Timeouts.Shutdown,
+	}
 }
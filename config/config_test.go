@@ -0,0 +1,119 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeFile writes a config file to a temporary directory
+func writeFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "dfss.yaml")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	return path
+}
+
+// TestLoadPrecedence tests that flags override the environment, which overrides the file
+func TestLoadPrecedence(t *testing.T) {
+	path := writeFile(t, `
+network:
+  genesis: true
+  port: 9000
+dht:
+  k: 5
+  hot_key_max_replicas: 20
+timeouts:
+  rpc: 2s
+storage:
+  quota_bytes: 1048576
+`)
+	t.Setenv("DFSS_CONFIG", path)
+	t.Setenv("DFSS_NETWORK_PORT", "9001")
+	t.Setenv("DFSS_TIMEOUTS_RPC", "3s")
+
+	c, err := Load([]string{"-port", "9002"})
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if c.Network.Port != 9002 {
+		t.Errorf("Expected the flag to win, got port %d", c.Network.Port)
+	}
+	if c.Timeouts.RPC != 3*time.Second {
+		t.Errorf("Expected the environment to override the file, got %s", c.Timeouts.RPC)
+	}
+	if c.DHT.K != 5 || c.Storage.QuotaBytes != 1<<20 {
+		t.Errorf("File settings were not applied: k=%d quota=%d", c.DHT.K, c.Storage.QuotaBytes)
+	}
+	if c.DHT.DisjointPaths != Default().DHT.DisjointPaths {
+		t.Errorf("Unset setting lost its default: %d", c.DHT.DisjointPaths)
+	}
+
+	n := c.Node()
+	if n.DHT.K != 5 || n.DHT.RPCTimeout != 3*time.Second || n.Limits.MaxBytes != 1<<20 {
+		t.Errorf("Node configuration does not match: %+v", n.DHT)
+	}
+}
+
+// TestLoadRejectsInvalid tests that unknown keys, bad values and inconsistent settings fail at startup
+func TestLoadRejectsInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		file  string
+		env   map[string]string
+		args  []string
+		error string
+	}{
+		{"unknown key", "dht:\n  kk: 4\n", nil, []string{"-genesis"}, "kk"},
+		{"plain number as duration", "timeouts:\n  rpc: 5\n", nil, []string{"-genesis"}, "time.Duration"},
+		{"bad env value", "", map[string]string{"DFSS_DHT_K": "three"}, []string{"-genesis"}, "DFSS_DHT_K"},
+		{"zero k", "dht:\n  k: 0\n", nil, []string{"-genesis"}, "k must be at least 1"},
+		{"missing bootstrap", "", nil, nil, "bootstrap"},
+		{"short tombstone", "dht:\n  tombstone_ttl: 1m\n", nil, []string{"-genesis"}, "tombstone"},
+		{"small plot", "pos:\n  num_entries: 1000\n", nil, []string{"-genesis"}, "plot"},
+		{"oversized chunk", "stream:\n  chunk_bytes: 65536\n", nil, []string{"-genesis"}, "chunk size"},
+		{"too few shards", "", map[string]string{"DFSS_ERASURE_TOTAL_SHARDS": "4"}, []string{"-genesis"}, "erasure"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("DFSS_CONFIG", writeFile(t, tt.file))
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			_, err := Load(tt.args)
+			if err == nil || !strings.Contains(err.Error(), tt.error) {
+				t.Errorf("Expected an error about %q, got %v", tt.error, err)
+			}
+		})
+	}
+}
+
+// TestExampleFile tests that config.example.yaml lists the defaults
+func TestExampleFile(t *testing.T) {
+	c := Default()
+	if err := c.LoadFile("../config.example.yaml"); err != nil {
+		t.Fatalf("LoadFile failed: %v", err)
+	}
+	if !reflect.DeepEqual(c, Default()) {
+		t.Errorf("Example file differs from the defaults:\n%+v\n%+v", c, Default())
+	}
+}
+
+// TestPassphrase tests that the passphrase file wins over the environment
+func TestPassphrase(t *testing.T) {
+	t.Setenv(PassphraseEnv, "from env")
+	c := IdentityConfig{}
+	if p, err := c.Passphrase(); err != nil || string(p) != "from env" {
+		t.Errorf("Expected the environment passphrase, got %q, %v", p, err)
+	}
+
+	c.PassphraseFile = writeFile(t, "from file\n")
+	if p, err := c.Passphrase(); err != nil || string(p) != "from file" {
+		t.Errorf("Expected the file passphrase without newline, got %q, %v", p, err)
+	}
+}
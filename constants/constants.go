@@ -6,6 +6,91 @@ const (
 	K            = 3
 	Alpha        = 3 // Concurrency parameter
 
+	// Network timeouts
+	RPCTimeout  = 5  // Seconds to wait for the response to a request
+	JoinTimeout = 10 // Seconds each step of the join handshake may take
+
+	// Number of disjoint lookup paths (S/Kademlia). A lookup only fails if
+	// every path runs into a malicious node, and FIND_VALUE needs a majority
+	// of the paths to agree on the value.
+	DisjointPaths = 3
+
+	// Path caching: after a successful lookup the value is cached on the closest
+	// node that did not have it. The expiry shrinks with that node's distance from the key.
+	CacheTTL    = 3600 // Maximum cache expiry in seconds (node right next to the replicas)
+	CacheMinTTL = 60   // Minimum cache expiry in seconds
+
+	// Hot-key detection: FIND_VALUE hits are counted per key over a sliding window.
+	// A key with HotKeyThreshold hits in the window gets K extra replicas per
+	// HotKeyThreshold hits (up to HotKeyMaxReplicas). Extra replicas are pushed as
+	// expiring copies and refreshed while the key stays hot, so the replica count
+	// decays back to K once demand drops.
+	HotKeyWindow       = 60  // Sliding window length in seconds
+	HotKeyBuckets      = 6   // Number of buckets the window is split into
+	HotKeyThreshold    = 20  // Hits per window for a key to count as hot
+	HotKeyMaxReplicas  = 12  // Upper bound on the replica count of a hot key
+	HotKeyPushInterval = 30  // Seconds between two pushes of extra replicas for the same key
+	HotKeyReplicaTTL   = 120 // Expiry in seconds of an extra replica
+
+	// Replication: one scheduler republishes all stored keys in batches
+	ReplicationInterval      = 600       // Seconds between two republishes of the same key
+	ReplicationCheckInterval = 60        // Seconds between two scheduler runs
+	ReplicationBatchBytes    = 32 * 1024 // Maximum value bytes per STORE_BATCH message
+
+	// Anti-entropy: neighbours compare Merkle digests of their shared keyspace range
+	// and pull the keys they are missing.
+	SyncInterval   = 300 // Seconds between two anti-entropy rounds
+	SyncFanoutBits = 4   // Each digest level splits a range into 2^SyncFanoutBits sub-ranges
+	SyncLeafKeys   = 64  // Ranges with at most this many keys are compared key by key
+	SyncPullBatch  = 16  // Keys requested per SYNC_PULL
+
+	// Erasure coding configuration
+	ErasureDataShards  = 4 // Shards needed to rebuild a value
+	ErasureTotalShards = 6 // Shards stored per value, so any 2 may be lost
+
+	// Deletion configuration
+	TombstoneTTL = 86400 // Seconds a tombstone is kept and republished, must outlive ReplicationInterval
+
+	// Provider record configuration
+	MaxProvidersPerKey = 20    // Providers a node keeps per key
+	ProviderTTL        = 86400 // Seconds a provider record lives unless announced again
+	ProviderInterval   = 43200 // Seconds between two announcements of a provided key
+
+	// Sharing configuration: an inbox keeps the newest notices that fit in one
+	// datagram, and at most MaxOwnerNotices of them from the same owner, so a
+	// stranger cannot crowd out the others. Notices expire after InboxNoticeTTL
+	// unless the owner grants again.
+	MaxInboxNotices = 64
+	MaxOwnerNotices = 8
+	InboxNoticeTTL  = 30 * 86400 // Seconds
+
+	// Storage quotas: a node refuses STOREs once it holds StorageQuotaBytes, or once
+	// a single peer added PeerQuotaBytes or PeerQuotaRecords keys, and ADD_PROVIDERs
+	// once a peer provides PeerQuotaProviders keys. Peers may send PeerStoreRate
+	// STORE or ADD_PROVIDER requests per second, with bursts of PeerStoreBurst.
+	StorageQuotaBytes  = 1024 * 1024 * 1024 // 1 GiB
+	PeerQuotaBytes     = 64 * 1024 * 1024   // 64 MiB
+	PeerQuotaRecords   = 100000
+	PeerQuotaProviders = 10000
+	PeerStoreRate      = 50
+	PeerStoreBurst     = 200
+	RateLimiterPeers   = 4096 // Peers tracked by the rate limiter before idle ones are forgotten
+
+	// Eviction: a full node evicts keys ranked below an incoming value, freeing
+	// this share of the quota on top of what the value needs
+	EvictionHeadroomPercent = 5
+
+	// Streaming: values larger than a chunk are stored as chunks at their SHA-256,
+	// with a manifest at the value's key. At most StreamWindow chunks of an upload
+	// or download are in flight, which bounds memory and slows down the other side.
+	StreamChunkBytes = 32 * 1024 // Default, and largest that fits in one UDP datagram once base64 encoded
+	StreamWindow     = 8
+
+	// HTTP API configuration
+	MaxUploadBytes  = 64 * 1024 * 1024 // Default largest value body the HTTP API accepts
+	ShutdownTimeout = 10               // Seconds a closing node waits for active HTTP requests
+	LeaveTimeout    = 60               // Seconds a node shutting down on a signal spends handing off keys
+
 	// Proof of Space configuration
 
 	// 2^^16 = 65536 entries, if an attacker wants to attack, it should calculate this many hashes in PoSChallengeTimeout seconds
@@ -2,8 +2,8 @@ package dht
 
 import (
 	"fmt"
-
-	"github.com/kutluhann/decentralized-file-sharing-system/constants"
+	"sort"
+	"sync"
 )
 
 // ---------------------------------------------------------
@@ -12,13 +12,15 @@ import (
 // ---------------------------------------------------------
 type LookupState struct {
 	Target    NodeID
+	K         int             // Number of closest nodes the lookup queries
 	Shortlist []Contact       // The list of all nodes we know about in this search
 	Contacted map[NodeID]bool // Keeps track of who we already queried
 }
 
-func NewLookupState(target NodeID, initialNodes []Contact) *LookupState {
+func NewLookupState(target NodeID, k int, initialNodes []Contact) *LookupState {
 	state := &LookupState{
 		Target:    target,
+		K:         k,
 		Shortlist: make([]Contact, 0),
 		Contacted: make(map[NodeID]bool),
 	}
@@ -62,7 +64,7 @@ func (ls *LookupState) Sort() {
 
 // PickNextBest returns the closest node that has NOT been queried yet.
 func (ls *LookupState) PickNextBest() *Contact {
-	limit := constants.K
+	limit := ls.K
 
 	if len(ls.Shortlist) < limit {
 		limit = len(ls.Shortlist)
@@ -85,88 +87,255 @@ func (ls *LookupState) MarkContacted(id NodeID) {
 	ls.Contacted[id] = true
 }
 
+// Remove drops a contact from the shortlist.
+func (ls *LookupState) Remove(id NodeID) {
+	for i, c := range ls.Shortlist {
+		if c.ID == id {
+			ls.Shortlist = append(ls.Shortlist[:i], ls.Shortlist[i+1:]...)
+			return
+		}
+	}
+}
+
 // ---------------------------------------------------------
-// THE NodeLookup algorithm (Iterative Node Lookup)
+// DISJOINT PATHS (S/Kademlia)
+// A lookup is split into d independent searches that never
+// query the same node, so a malicious node can only steer the
+// path that reached it instead of capturing the whole lookup.
 // ---------------------------------------------------------
 
-// NodeLookup performs the iterative lookup for a target ID.
-// It keeps crawling the network until it finds the k closest nodes.
-// Returns: closest contacts, number of hops (FIND_NODE queries made)
-func (n *Node) NodeLookup(targetID NodeID) ([]Contact, int) {
-	// 1. INITIALIZATION
-	// Start with the closest nodes we know locally.
-	localCandidates := n.RoutingTable.GetClosestNodes(targetID, constants.K)
+// PathClaims records which path queried each node.
+type PathClaims struct {
+	Owner map[NodeID]int
+	mutex sync.Mutex
+}
+
+func NewPathClaims() *PathClaims {
+	return &PathClaims{
+		Owner: make(map[NodeID]int),
+	}
+}
+
+// Claim reserves a node for a path. It returns false if another
+// path has already queried that node.
+func (pc *PathClaims) Claim(id NodeID, path int) bool {
+	pc.mutex.Lock()
+	defer pc.mutex.Unlock()
+
+	if owner, exists := pc.Owner[id]; exists {
+		return owner == path
+	}
+	pc.Owner[id] = path
+	return true
+}
+
+// PathResult is the outcome of a single disjoint path.
+type PathResult struct {
+	Path     int
+	Value    []byte    // Set when the path found the value (FIND_VALUE only)
+	Contacts []Contact // All contacts this path has seen, sorted by distance
+	Hops     int       // Number of RPCs sent on this path
+	Exact    bool      // The target node itself was found
+	Holder   Contact   // Node that returned Value
+	Missed   []Contact // Nodes that answered without the value
+}
+
+// lookupQuery is the RPC a path sends to each candidate.
+// FIND_NODE only returns contacts, FIND_VALUE may return the value instead.
+type lookupQuery func(target Contact, key NodeID) ([]byte, []Contact, error)
 
-	// Debug print
-	fmt.Printf("[LOOKUP] Searching for target: %s\n", targetID.String()[:16])
-	fmt.Printf("[LOOKUP] Starting with %d local candidates\n", len(localCandidates))
+// findNodeQuery adapts SendFindNode to the lookupQuery signature.
+func (n *Node) findNodeQuery(target Contact, key NodeID) ([]byte, []Contact, error) {
+	nodes, err := n.Network.SendFindNode(target, key)
+	return nil, nodes, err
+}
+
+// pathCount returns the configured number of disjoint paths (d).
+func (n *Node) pathCount() int {
+	if n.Config.DisjointPaths < 1 {
+		return 1
+	}
+	return n.Config.DisjointPaths
+}
+
+// splitPaths deals the initial candidates round-robin over at most d
+// lookup states, so every path starts from a different set of nodes.
+func splitPaths(target NodeID, k int, initial []Contact, d int) []*LookupState {
+	if len(initial) < d {
+		d = len(initial)
+	}
+
+	buckets := make([][]Contact, d)
+	for i, c := range initial {
+		buckets[i%d] = append(buckets[i%d], c)
+	}
 
-	state := NewLookupState(targetID, localCandidates)
-	hopCount := 0 // Track number of FIND_NODE queries
+	states := make([]*LookupState, d)
+	for i := range buckets {
+		states[i] = NewLookupState(target, k, buckets[i])
+	}
+	return states
+}
+
+// runPath performs the iterative lookup for a single path.
+// Nodes already queried by another path are dropped from the shortlist.
+func (n *Node) runPath(tag string, path int, state *LookupState, claims *PathClaims, query lookupQuery) PathResult {
+	result := PathResult{Path: path}
 
-	// 2. THE MAIN LOOP
-	// We keep going until we run out of new people to ask.
 	for {
 		// A. SELECTION
 		candidate := state.PickNextBest()
 
-		// TERMINATION: If no unqueried nodes remain, we are done.
+		// TERMINATION: If no unqueried nodes remain, this path is done.
 		if candidate == nil {
-			fmt.Printf("[LOOKUP] No more unqueried nodes, terminating\n")
+			fmt.Printf("[%s p%d] No more unqueried nodes, terminating\n", tag, path)
 			break
 		}
+		c := *candidate
+		state.MarkContacted(c.ID)
 
-		// B. NETWORK CALL (RPC)
-		fmt.Printf("[LOOKUP] Querying %s:%d for nodes closer to target (hop %d)\n",
-			candidate.IP, candidate.Port, hopCount+1)
+		if !claims.Claim(c.ID, path) {
+			state.Remove(c.ID)
+			continue
+		}
 
-		// Send FIND_NODE RPC over UDP
-		hopCount++ // Increment hop count for each FIND_NODE query
-		newNodes, err := n.Network.SendFindNode(*candidate, targetID)
+		// B. NETWORK CALL (RPC)
+		result.Hops++
+		fmt.Printf("[%s p%d] Querying %s:%d (hop %d)\n", tag, path, c.IP, c.Port, result.Hops)
 
-		// Mark as contacted regardless of success/fail to avoid loops
-		state.MarkContacted(candidate.ID)
+		value, newNodes, err := query(c, state.Target)
 
 		// C. UPDATE STATE
 		if err != nil {
-			fmt.Printf("[LOOKUP] ✗ Failed to query %s:%d: %v\n",
-				candidate.IP, candidate.Port, err)
+			fmt.Printf("[%s p%d] ✗ Failed to query %s:%d: %v\n", tag, path, c.IP, c.Port, err)
 			continue
 		}
 
-		// If successful, add the new suggestions to our list
-		state.Append(newNodes)
-
 		// Passive Update: Since they replied, we verify they are alive
-		n.RoutingTable.Update(*candidate)
+		n.RoutingTable.Update(c)
+
+		if value != nil {
+			fmt.Printf("[%s p%d] ✓ Node %s returned the value (%d bytes)\n",
+				tag, path, c.ID.String()[:16], len(value))
+			result.Value = value
+			result.Holder = c
+			break
+		}
+		result.Missed = append(result.Missed, c)
+
+		state.Append(newNodes)
 
 		// *** EARLY EXIT CHECK ***
-		// If one of the returned nodes is the target, we are done!
+		// If one of the returned nodes is the target, this path is done.
 		for _, receivedNode := range newNodes {
-			if receivedNode.ID == targetID {
-				// Found it! Return just this one.
-				fmt.Printf("[LOOKUP] ✓ Found exact target node: %s (hops: %d)\n",
-					receivedNode.ID.String()[:16], hopCount)
-				return []Contact{receivedNode}, hopCount
+			if receivedNode.ID == state.Target {
+				fmt.Printf("[%s p%d] ✓ Found exact target node: %s\n",
+					tag, path, receivedNode.ID.String()[:16])
+				result.Contacts = []Contact{receivedNode}
+				result.Exact = true
+				return result
 			}
 		}
 	}
 
-	// 3. RETURN RESULTS
-	// Return the top K nodes from our sorted shortlist
-	fmt.Printf("[LOOKUP] Lookup complete, returning %d closest nodes (hops: %d)\n",
-		min(len(state.Shortlist), constants.K), hopCount)
+	result.Contacts = state.Shortlist
+	return result
+}
+
+// disjointLookup runs d lookup paths towards the target in parallel.
+func (n *Node) disjointLookup(tag string, targetID NodeID, query lookupQuery) []PathResult {
+	d := n.pathCount()
+
+	// Start with enough local candidates to give every path its own set.
+	localCandidates := n.RoutingTable.GetClosestNodes(targetID, n.Config.K*d)
+	states := splitPaths(targetID, n.Config.K, localCandidates, d)
+
+	fmt.Printf("[%s] Searching for target: %s\n", tag, targetID.String()[:16])
+	fmt.Printf("[%s] Starting %d disjoint paths from %d local candidates\n",
+		tag, len(states), len(localCandidates))
+
+	claims := NewPathClaims()
+	results := make([]PathResult, len(states))
+
+	var wg sync.WaitGroup
+	for i, state := range states {
+		wg.Add(1)
+		go func(path int, state *LookupState) {
+			defer wg.Done()
+			results[path] = n.runPath(tag, path, state, claims, query)
+		}(i, state)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// mergePaths combines the per-path results, taking the best node of every
+// path before the second best of any path. A path captured by an attacker
+// can therefore contribute at most its share of the returned contacts.
+func mergePaths(targetID NodeID, results []PathResult, count int) []Contact {
+	longest := 0
+	for _, r := range results {
+		if len(r.Contacts) > longest {
+			longest = len(r.Contacts)
+		}
+	}
+
+	var merged []Contact
+	seen := make(map[NodeID]bool)
+
+	for rank := 0; rank < longest && len(merged) < count; rank++ {
+		var tier []Contact
+		for _, r := range results {
+			if rank < len(r.Contacts) && !seen[r.Contacts[rank].ID] {
+				seen[r.Contacts[rank].ID] = true
+				tier = append(tier, r.Contacts[rank])
+			}
+		}
+
+		sort.Slice(tier, func(i, j int) bool {
+			return tier[i].ID.Xor(targetID).Less(tier[j].ID.Xor(targetID))
+		})
+		merged = append(merged, tier...)
+	}
 
-	if len(state.Shortlist) > constants.K {
-		return state.Shortlist[:constants.K], hopCount
+	if len(merged) > count {
+		return merged[:count]
 	}
-	return state.Shortlist, hopCount
+	return merged
 }
 
-// Helper function for min
-func min(a, b int) int {
-	if a < b {
-		return a
+// ---------------------------------------------------------
+// THE NodeLookup algorithm (Iterative Node Lookup)
+// ---------------------------------------------------------
+
+// NodeLookup performs the iterative lookup for a target ID over d disjoint paths.
+// It keeps crawling the network until every path has found its k closest nodes.
+// Returns: closest contacts, number of hops (FIND_NODE queries made)
+func (n *Node) NodeLookup(targetID NodeID) ([]Contact, int) {
+	return n.NodeLookupN(targetID, n.Config.K)
+}
+
+// NodeLookupN is NodeLookup returning up to count contacts instead of k.
+// Contacts beyond the k closest of each path were seen but not necessarily queried.
+func (n *Node) NodeLookupN(targetID NodeID, count int) ([]Contact, int) {
+	results := n.disjointLookup("LOOKUP", targetID, n.findNodeQuery)
+
+	hopCount := 0
+	for _, r := range results {
+		hopCount += r.Hops
+	}
+
+	for _, r := range results {
+		if r.Exact {
+			return r.Contacts, hopCount
+		}
 	}
-	return b
+
+	closest := mergePaths(targetID, results, count)
+
+	fmt.Printf("[LOOKUP] Lookup complete, returning %d closest nodes (hops: %d)\n",
+		len(closest), hopCount)
+
+	return closest, hopCount
 }
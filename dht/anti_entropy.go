@@ -0,0 +1,309 @@
+package dht
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sort"
+
+	"github.com/kutluhann/decentralized-file-sharing-system/constants"
+)
+
+// ---------------------------------------------------------
+// ANTI-ENTROPY
+// Neighbouring replicas compare Merkle digests of the keyspace
+// range they share and pull only the keys they are missing, so
+// replicas converge after partitions or restarts instead of
+// waiting for the next republish.
+// ---------------------------------------------------------
+
+// inRange reports whether a key shares the first bits of prefix
+func inRange(key, prefix NodeID, bits int) bool {
+	return key.PrefixLen(prefix) >= bits
+}
+
+// childIndex returns the fanout bits of a key that follow the first bits
+func childIndex(key NodeID, bits int, fanout int) int {
+	idx := 0
+	for i := 0; i < fanout; i++ {
+		pos := bits + i
+		idx <<= 1
+		if pos < len(key)*8 && key[pos/8]&(0x80>>(pos%8)) != 0 {
+			idx |= 1
+		}
+	}
+	return idx
+}
+
+// childPrefix returns the prefix of the idx-th sub-range of a range
+func childPrefix(prefix NodeID, bits int, fanout int, idx int) NodeID {
+	child := prefix
+	for i := 0; i < fanout; i++ {
+		pos := bits + i
+		if pos >= len(child)*8 {
+			break
+		}
+		mask := byte(0x80 >> (pos % 8))
+		if idx&(1<<(fanout-1-i)) != 0 {
+			child[pos/8] |= mask
+		} else {
+			child[pos/8] &^= mask
+		}
+	}
+	return child
+}
+
+// isLeafRange reports whether a range is compared key by key instead of split further
+func (n *Node) isLeafRange(count int, bits int) bool {
+	return count <= n.Config.SyncLeafKeys || n.isLastLevel(bits)
+}
+
+// isLastLevel reports whether a range is too narrow to be split into sub-ranges
+func (n *Node) isLastLevel(bits int) bool {
+	return bits+n.Config.SyncFanoutBits > constants.KeySizeBytes*8
+}
+
+// localKeyDigests returns the digests of the replicas we store in a range, sorted by key.
+// Cached copies and erasure shards are not replicas and are left out.
+func (n *Node) localKeyDigests(prefix NodeID, bits int) []KeyDigest {
+	var digests []KeyDigest
+
+	n.StorageMux.RLock()
+	for key, value := range n.Storage {
+		if !n.isReplica(key) || !inRange(key, prefix, bits) {
+			continue
+		}
+		digests = append(digests, KeyDigest{Key: key, Hash: sha256.Sum256(value)})
+	}
+	n.StorageMux.RUnlock()
+
+	sort.Slice(digests, func(i, j int) bool {
+		return digests[i].Key.Less(digests[j].Key)
+	})
+	return digests
+}
+
+// splitRange distributes sorted key digests over the sub-ranges of a range
+func (n *Node) splitRange(digests []KeyDigest, bits int) [][]KeyDigest {
+	children := make([][]KeyDigest, 1<<n.Config.SyncFanoutBits)
+	for _, d := range digests {
+		i := childIndex(d.Key, bits, n.Config.SyncFanoutBits)
+		children[i] = append(children[i], d)
+	}
+	return children
+}
+
+// merkleHash hashes a range. Small ranges hash their key digests,
+// larger ones the hashes of their sub-ranges.
+func (n *Node) merkleHash(digests []KeyDigest, bits int) [32]byte {
+	h := sha256.New()
+	if n.isLeafRange(len(digests), bits) {
+		for _, d := range digests {
+			h.Write(d.Key[:])
+			h.Write(d.Hash[:])
+		}
+	} else {
+		for _, child := range n.splitRange(digests, bits) {
+			childHash := n.merkleHash(child, bits+n.Config.SyncFanoutBits)
+			h.Write(childHash[:])
+		}
+	}
+
+	var sum [32]byte
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
+
+// rangeDigests returns the Merkle digest of every sub-range of a range
+func (n *Node) rangeDigests(digests []KeyDigest, bits int) []RangeDigest {
+	children := n.splitRange(digests, bits)
+	result := make([]RangeDigest, len(children))
+	for i, child := range children {
+		result[i] = RangeDigest{
+			Hash:  n.merkleHash(child, bits+n.Config.SyncFanoutBits),
+			Count: len(child),
+		}
+	}
+	return result
+}
+
+// --- Server side ---
+
+// HandleSyncDigest returns the sub-range digests of a range, or its key list for leaf requests
+func (n *Node) HandleSyncDigest(sender Contact, req SyncDigestRequest) SyncDigestResponse {
+	n.RoutingTable.Update(sender)
+
+	digests := n.localKeyDigests(req.Prefix, req.Bits)
+	if req.Leaf || n.isLastLevel(req.Bits) {
+		return SyncDigestResponse{Keys: digests}
+	}
+	return SyncDigestResponse{Children: n.rangeDigests(digests, req.Bits)}
+}
+
+// HandleSyncPull returns the requested replicas, up to ReplicationBatchBytes of values
+func (n *Node) HandleSyncPull(sender Contact, req SyncPullRequest) SyncPullResponse {
+	n.RoutingTable.Update(sender)
+
+	var items []StoreRequest
+	size := 0
+
+	n.StorageMux.RLock()
+	defer n.StorageMux.RUnlock()
+
+	for _, key := range req.Keys {
+		value, exists := n.Storage[key]
+		if !exists || !n.isReplica(key) {
+			continue
+		}
+		if len(items) > 0 && size+len(value) > constants.ReplicationBatchBytes {
+			break
+		}
+		items = append(items, StoreRequest{Key: key, Value: value, Owner: n.Owners[key]})
+		size += len(value)
+	}
+
+	return SyncPullResponse{Items: items}
+}
+
+// --- Client side ---
+
+// SyncWithNeighbours runs one anti-entropy round with our k closest neighbours.
+// Each pair compares the smallest keyspace range containing both nodes.
+// Returns the number of keys pulled.
+func (n *Node) SyncWithNeighbours() int {
+	if n.Network == nil {
+		return 0
+	}
+
+	pulled := 0
+	for _, peer := range n.RoutingTable.GetClosestNodes(n.Self.ID, n.Config.K) {
+		if peer.ID == n.Self.ID {
+			continue
+		}
+
+		bits := n.Self.ID.PrefixLen(peer.ID)
+		count, err := n.syncRange(peer, n.Self.ID, bits)
+		if err != nil {
+			fmt.Printf("[SYNC] ✗ Anti-entropy with %s failed: %v\n", peer.ID.String()[:16], err)
+		}
+		pulled += count
+	}
+
+	if pulled > 0 {
+		fmt.Printf("[SYNC] ✓ Pulled %d missing keys from neighbours\n", pulled)
+	}
+	return pulled
+}
+
+// syncRange compares the sub-range digests of a range with a peer and descends into those that differ
+func (n *Node) syncRange(peer Contact, prefix NodeID, bits int) (int, error) {
+	if n.isLastLevel(bits) {
+		return n.syncLeaf(peer, prefix, bits)
+	}
+
+	remote, err := n.Network.SendSyncDigest(peer, SyncDigestRequest{Prefix: prefix, Bits: bits})
+	if err != nil {
+		return 0, err
+	}
+
+	mine := n.rangeDigests(n.localKeyDigests(prefix, bits), bits)
+	if len(remote.Children) != len(mine) {
+		return 0, fmt.Errorf("expected %d range digests, got %d", len(mine), len(remote.Children))
+	}
+
+	pulled := 0
+	for i := range mine {
+		if mine[i] == remote.Children[i] {
+			continue
+		}
+
+		childBits := bits + n.Config.SyncFanoutBits
+		child := childPrefix(prefix, bits, n.Config.SyncFanoutBits, i)
+
+		var count int
+		if n.isLeafRange(max(mine[i].Count, remote.Children[i].Count), childBits) {
+			count, err = n.syncLeaf(peer, child, childBits)
+		} else {
+			count, err = n.syncRange(peer, child, childBits)
+		}
+		pulled += count
+		if err != nil {
+			return pulled, err
+		}
+	}
+	return pulled, nil
+}
+
+// syncLeaf compares a small range key by key and pulls the keys we should hold but lack
+func (n *Node) syncLeaf(peer Contact, prefix NodeID, bits int) (int, error) {
+	remote, err := n.Network.SendSyncDigest(peer, SyncDigestRequest{Prefix: prefix, Bits: bits, Leaf: true})
+	if err != nil {
+		return 0, err
+	}
+
+	have := make(map[NodeID]bool)
+	for _, d := range n.localKeyDigests(prefix, bits) {
+		have[d.Key] = true
+	}
+
+	var missing []NodeID
+	for _, d := range remote.Keys {
+		if have[d.Key] || !inRange(d.Key, prefix, bits) {
+			continue
+		}
+		// Only pull keys we are responsible for
+		if containsContact(n.closestWithSelf(d.Key), n.Self.ID) {
+			missing = append(missing, d.Key)
+		}
+	}
+
+	return n.pullKeys(peer, missing)
+}
+
+// pullKeys fetches the given keys from a peer in batches and stores them as replicas
+func (n *Node) pullKeys(peer Contact, keys []NodeID) (int, error) {
+	pulled := 0
+	for len(keys) > 0 {
+		batch := keys
+		if len(batch) > n.Config.SyncPullBatch {
+			batch = batch[:n.Config.SyncPullBatch]
+		}
+
+		items, err := n.Network.SendSyncPull(peer, batch)
+		if err != nil {
+			return pulled, err
+		}
+
+		requested := make(map[NodeID]bool, len(batch))
+		for _, key := range batch {
+			requested[key] = true
+		}
+
+		received := make(map[NodeID]bool, len(items))
+		for _, item := range items {
+			if !requested[item.Key] {
+				continue
+			}
+			// We asked for these keys, so they don't count against the peer's rate limit
+			received[item.Key] = true
+			if err := n.storeLocal(peer, StoreRequest{Key: item.Key, Value: item.Value, Owner: item.Owner}); err != nil {
+				continue
+			}
+			pulled++
+		}
+
+		if len(received) == 0 {
+			// The peer no longer has any of these keys
+			keys = keys[len(batch):]
+			continue
+		}
+
+		remaining := keys[:0]
+		for _, key := range keys {
+			if !received[key] {
+				remaining = append(remaining, key)
+			}
+		}
+		keys = remaining
+	}
+	return pulled, nil
+}
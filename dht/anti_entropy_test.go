@@ -0,0 +1,78 @@
+package dht
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"testing"
+
+	"github.com/kutluhann/decentralized-file-sharing-system/constants"
+)
+
+// TestSyncPullsMissingKeys tests that a node pulls the keys a neighbour holds and it lacks
+func TestSyncPullsMissingKeys(t *testing.T) {
+	a := startHonestNode(t, idWithPrefix(0x00, 1))
+	b := startNodeWithoutHandoff(t, idWithPrefix(0x80, 1)) // Only anti-entropy may deliver the keys
+	a.RoutingTable.Update(b.Self)
+
+	// Enough keys that the digests are compared over several levels
+	b.StorageMux.Lock()
+	for i := 0; i < 200; i++ {
+		key := NodeID(sha256.Sum256([]byte(fmt.Sprintf("key-%d", i))))
+		b.Storage[key] = []byte(fmt.Sprintf("value-%d", i))
+	}
+	b.StorageMux.Unlock()
+
+	if pulled := a.SyncWithNeighbours(); pulled != 200 {
+		t.Fatalf("Expected to pull 200 keys, pulled %d", pulled)
+	}
+
+	a.StorageMux.RLock()
+	stored := len(a.Storage)
+	a.StorageMux.RUnlock()
+	if stored != 200 {
+		t.Fatalf("Expected 200 stored keys after sync, got %d", stored)
+	}
+
+	// The digests now match, nothing left to pull
+	if pulled := a.SyncWithNeighbours(); pulled != 0 {
+		t.Fatalf("Expected converged replicas, pulled %d more keys", pulled)
+	}
+}
+
+// TestSyncSkipsKeysOfOthers tests that keys we are not among the k closest for are not pulled
+func TestSyncSkipsKeysOfOthers(t *testing.T) {
+	a := startHonestNode(t, idWithPrefix(0x00, 1))
+	b := startNodeWithoutHandoff(t, idWithPrefix(0x80, 1))
+
+	key := idWithPrefix(0xF0, 0)
+	b.StorageMux.Lock()
+	b.Storage[key] = []byte("not ours")
+	b.StorageMux.Unlock()
+
+	// Three known nodes are closer to the key than we are
+	for i := byte(1); i <= 3; i++ {
+		a.RoutingTable.Update(Contact{ID: idWithPrefix(0xF0, i), IP: "127.0.0.1", Port: 1})
+	}
+
+	pulled, err := a.syncRange(b.Self, a.Self.ID, 0)
+	if err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if pulled != 0 {
+		t.Fatalf("Pulled %d keys we are not responsible for", pulled)
+	}
+}
+
+// TestChildPrefix tests that sub-range prefixes and key indexes agree
+func TestChildPrefix(t *testing.T) {
+	key := NodeID(sha256.Sum256([]byte("some key")))
+
+	for _, bits := range []int{0, 3, 8, 252} {
+		idx := childIndex(key, bits, constants.SyncFanoutBits)
+		child := childPrefix(NodeID{}, bits, constants.SyncFanoutBits, idx)
+		if childIndex(child, bits, constants.SyncFanoutBits) != idx {
+			t.Errorf("bits=%d: child prefix has index %d, expected %d",
+				bits, childIndex(child, bits, constants.SyncFanoutBits), idx)
+		}
+	}
+}
@@ -3,22 +3,24 @@ package dht
 import (
 	"sync"
 	"time"
-
-	"github.com/kutluhann/decentralized-file-sharing-system/constants"
 )
 
 type Bucket struct {
 	contacts []Contact
+	size     int // Contacts the bucket holds at most (k)
 	mutex    sync.RWMutex
 }
 
-func NewBucket() *Bucket {
+func NewBucket(size int) *Bucket {
 	return &Bucket{
-		contacts: make([]Contact, 0, constants.K),
+		contacts: make([]Contact, 0, size),
+		size:     size,
 	}
 }
 
-func (b *Bucket) Update(contact Contact) {
+// Update moves a known contact to the tail of the bucket, or appends it if there is room.
+// It returns true if the contact was not in the bucket before.
+func (b *Bucket) Update(contact Contact) bool {
 	b.mutex.Lock()
 	defer b.mutex.Unlock()
 
@@ -34,14 +36,30 @@ func (b *Bucket) Update(contact Contact) {
 		b.contacts = append(b.contacts[:foundIndex], b.contacts[foundIndex+1:]...)
 		contact.LastSeen = time.Now()
 		b.contacts = append(b.contacts, contact)
-		return
+		return false
 	}
 
-	if len(b.contacts) < constants.K {
+	if len(b.contacts) < b.size {
 		contact.LastSeen = time.Now()
 		b.contacts = append(b.contacts, contact)
-		return
+		return true
+	}
+
+	return false
+}
+
+// Remove drops a contact from the bucket if it is there and reachable at the same address
+func (b *Bucket) Remove(contact Contact) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for i, existing := range b.contacts {
+		if existing.ID == contact.ID && existing.IP == contact.IP && existing.Port == contact.Port {
+			b.contacts = append(b.contacts[:i], b.contacts[i+1:]...)
+			return true
+		}
 	}
+	return false
 }
 
 func (b *Bucket) GetContacts() []Contact {
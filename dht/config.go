@@ -0,0 +1,173 @@
+package dht
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/kutluhann/decentralized-file-sharing-system/constants"
+)
+
+// ---------------------------------------------------------
+// CONFIGURATION
+// The protocol parameters and timeouts of a node. Defaults come
+// from the constants package; nodes of one network should agree
+// on K, the anti-entropy fanout and the Proof of Space parameters,
+// the rest is local.
+// ---------------------------------------------------------
+
+// Config holds the tunable parameters of a node
+type Config struct {
+	K             int // Bucket size and number of replicas of a key
+	DisjointPaths int // Number of disjoint lookup paths (d)
+
+	RPCTimeout  time.Duration // Wait for the response to a request
+	JoinTimeout time.Duration // Each step of the join handshake
+
+	ReplicationInterval      time.Duration // Between two republishes of the same key
+	ReplicationCheckInterval time.Duration // Between two replication scheduler runs
+	SyncInterval             time.Duration // Between two anti-entropy rounds
+	ProviderInterval         time.Duration // Between two announcements of a provided key
+	ProviderTTL              time.Duration // Lifetime of a provider record unless announced again
+	TombstoneTTL             time.Duration // Lifetime of a tombstone, must outlive ReplicationInterval
+	CacheTTL                 time.Duration // Expiry of a value cached right next to the replicas
+	CacheMinTTL              time.Duration // Expiry of a value cached farthest from the key
+
+	HotKeyWindow       time.Duration // Sliding window FIND_VALUE hits are counted over
+	HotKeyBuckets      int           // Number of buckets the window is split into
+	HotKeyThreshold    int           // FIND_VALUE hits per window for a key to count as hot
+	HotKeyMaxReplicas  int           // Upper bound on the replica count of a hot key
+	HotKeyPushInterval time.Duration // Between two pushes of extra replicas for the same key
+	HotKeyReplicaTTL   time.Duration // Expiry of an extra replica of a hot key
+
+	SyncFanoutBits int // Each digest level splits a range into 2^SyncFanoutBits sub-ranges
+	SyncLeafKeys   int // Ranges with at most this many keys are compared key by key
+	SyncPullBatch  int // Keys requested per SYNC_PULL
+
+	MaxProvidersPerKey      int // Providers kept per key
+	EvictionHeadroomPercent int // Share of the quota a full node frees on top of what a value needs
+
+	MaxInboxNotices int           // Notices an inbox keeps, all of them must fit in one datagram
+	MaxOwnerNotices int           // Notices of a single owner an inbox keeps
+	InboxNoticeTTL  time.Duration // Lifetime of a share notice unless the owner grants again
+
+	ErasureDataShards  int // Shards needed to rebuild an erasure-coded value
+	ErasureTotalShards int // Shards stored per erasure-coded value
+
+	StreamChunkBytes int // Chunk size of streamed values, at most constants.StreamChunkBytes
+	StreamWindow     int // Chunks of an upload or download in flight
+
+	PlotDir             string        // Directory of the Proof of Space plot
+	PosPrefixBits       int           // Prefix bits (T) a joining node must match
+	PosNumEntries       int           // Hash entries in our plot
+	PosChallengeTimeout time.Duration // Time a joining node has to answer a PoS challenge
+}
+
+// DefaultConfig returns the parameters from the constants package
+func DefaultConfig() Config {
+	return Config{
+		K:                        constants.K,
+		DisjointPaths:            constants.DisjointPaths,
+		RPCTimeout:               constants.RPCTimeout * time.Second,
+		JoinTimeout:              constants.JoinTimeout * time.Second,
+		ReplicationInterval:      constants.ReplicationInterval * time.Second,
+		ReplicationCheckInterval: constants.ReplicationCheckInterval * time.Second,
+		SyncInterval:             constants.SyncInterval * time.Second,
+		ProviderInterval:         constants.ProviderInterval * time.Second,
+		ProviderTTL:              constants.ProviderTTL * time.Second,
+		TombstoneTTL:             constants.TombstoneTTL * time.Second,
+		CacheTTL:                 constants.CacheTTL * time.Second,
+		CacheMinTTL:              constants.CacheMinTTL * time.Second,
+		HotKeyWindow:             constants.HotKeyWindow * time.Second,
+		HotKeyBuckets:            constants.HotKeyBuckets,
+		HotKeyThreshold:          constants.HotKeyThreshold,
+		HotKeyMaxReplicas:        constants.HotKeyMaxReplicas,
+		HotKeyPushInterval:       constants.HotKeyPushInterval * time.Second,
+		HotKeyReplicaTTL:         constants.HotKeyReplicaTTL * time.Second,
+		SyncFanoutBits:           constants.SyncFanoutBits,
+		SyncLeafKeys:             constants.SyncLeafKeys,
+		SyncPullBatch:            constants.SyncPullBatch,
+		MaxProvidersPerKey:       constants.MaxProvidersPerKey,
+		EvictionHeadroomPercent:  constants.EvictionHeadroomPercent,
+		MaxInboxNotices:          constants.MaxInboxNotices,
+		MaxOwnerNotices:          constants.MaxOwnerNotices,
+		InboxNoticeTTL:           constants.InboxNoticeTTL * time.Second,
+		ErasureDataShards:        constants.ErasureDataShards,
+		ErasureTotalShards:       constants.ErasureTotalShards,
+		StreamChunkBytes:         constants.StreamChunkBytes,
+		StreamWindow:             constants.StreamWindow,
+		PlotDir:                  constants.PosPlotDataDir,
+		PosPrefixBits:            constants.PosPrefixBits,
+		PosNumEntries:            constants.PosNumEntries,
+		PosChallengeTimeout:      constants.PosChallengeTimeout * time.Second,
+	}
+}
+
+// Validate returns the first parameter that is out of range, or nil
+func (c Config) Validate() error {
+	switch {
+	case c.K < 1:
+		return fmt.Errorf("k must be at least 1, got %d", c.K)
+	case c.DisjointPaths < 1:
+		return fmt.Errorf("disjoint paths must be at least 1, got %d", c.DisjointPaths)
+	case c.RPCTimeout <= 0:
+		return fmt.Errorf("rpc timeout must be positive, got %s", c.RPCTimeout)
+	case c.JoinTimeout <= 0:
+		return fmt.Errorf("join timeout must be positive, got %s", c.JoinTimeout)
+	case c.ReplicationInterval <= 0 || c.ReplicationCheckInterval <= 0:
+		return fmt.Errorf("replication intervals must be positive")
+	case c.ReplicationCheckInterval > c.ReplicationInterval:
+		return fmt.Errorf("replication check interval %s is longer than the replication interval %s",
+			c.ReplicationCheckInterval, c.ReplicationInterval)
+	case c.SyncInterval <= 0 || c.ProviderInterval <= 0:
+		return fmt.Errorf("sync and provider intervals must be positive")
+	case c.ProviderTTL < c.ProviderInterval:
+		return fmt.Errorf("provider ttl %s expires before the next announcement after %s",
+			c.ProviderTTL, c.ProviderInterval)
+	case c.TombstoneTTL <= c.ReplicationInterval:
+		return fmt.Errorf("tombstone ttl %s must outlive the replication interval %s",
+			c.TombstoneTTL, c.ReplicationInterval)
+	case c.CacheMinTTL <= 0 || c.CacheTTL < c.CacheMinTTL:
+		return fmt.Errorf("cache ttl must be at least the minimum cache ttl, which must be positive")
+	case c.HotKeyThreshold < 1:
+		return fmt.Errorf("hot key threshold must be at least 1, got %d", c.HotKeyThreshold)
+	case c.HotKeyMaxReplicas < c.K:
+		return fmt.Errorf("hot key max replicas %d is less than k %d", c.HotKeyMaxReplicas, c.K)
+	case c.HotKeyReplicaTTL <= 0:
+		return fmt.Errorf("hot key replica ttl must be positive, got %s", c.HotKeyReplicaTTL)
+	case c.HotKeyBuckets < 1 || c.HotKeyWindow < time.Duration(c.HotKeyBuckets)*time.Second:
+		return fmt.Errorf("hot key window %s must be split into buckets of at least a second, got %d buckets",
+			c.HotKeyWindow, c.HotKeyBuckets)
+	case c.HotKeyPushInterval <= 0:
+		return fmt.Errorf("hot key push interval must be positive, got %s", c.HotKeyPushInterval)
+	case c.SyncFanoutBits < 1 || c.SyncFanoutBits > 8:
+		return fmt.Errorf("sync fanout bits must be between 1 and 8, got %d", c.SyncFanoutBits)
+	case c.SyncLeafKeys < 1 || c.SyncPullBatch < 1:
+		return fmt.Errorf("sync leaf keys and pull batch must be at least 1")
+	case c.MaxProvidersPerKey < 1:
+		return fmt.Errorf("max providers per key must be at least 1, got %d", c.MaxProvidersPerKey)
+	case c.EvictionHeadroomPercent < 0 || c.EvictionHeadroomPercent > 100:
+		return fmt.Errorf("eviction headroom must be between 0 and 100 percent, got %d", c.EvictionHeadroomPercent)
+	case c.MaxInboxNotices < 1 || c.MaxOwnerNotices < 1:
+		return fmt.Errorf("inbox notice limits must be at least 1")
+	case c.InboxNoticeTTL <= 0:
+		return fmt.Errorf("inbox notice ttl must be positive, got %s", c.InboxNoticeTTL)
+	case c.ErasureDataShards < 1 || c.ErasureTotalShards <= c.ErasureDataShards || c.ErasureTotalShards > 256:
+		return fmt.Errorf("erasure coding needs at least 1 data shard and more, but at most 256, shards in total, got %d of %d",
+			c.ErasureDataShards, c.ErasureTotalShards)
+	case c.StreamChunkBytes < 1 || c.StreamChunkBytes > constants.StreamChunkBytes:
+		return fmt.Errorf("stream chunk size must be between 1 and %d bytes to fit in a datagram, got %d",
+			constants.StreamChunkBytes, c.StreamChunkBytes)
+	case c.StreamWindow < 1:
+		return fmt.Errorf("stream window must be at least 1, got %d", c.StreamWindow)
+	case c.PlotDir == "":
+		return fmt.Errorf("plot directory must not be empty")
+	case c.PosPrefixBits < 1 || c.PosPrefixBits > 32:
+		return fmt.Errorf("pos prefix bits must be between 1 and 32, got %d", c.PosPrefixBits)
+	case c.PosNumEntries < 1<<c.PosPrefixBits:
+		return fmt.Errorf("a plot of %d entries fails most challenges of %d prefix bits, use at least %d",
+			c.PosNumEntries, c.PosPrefixBits, 1<<c.PosPrefixBits)
+	case c.PosChallengeTimeout <= 0:
+		return fmt.Errorf("pos challenge timeout must be positive, got %s", c.PosChallengeTimeout)
+	}
+	return nil
+}
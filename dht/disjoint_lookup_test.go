@@ -0,0 +1,229 @@
+package dht
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"net"
+	"testing"
+
+	"github.com/kutluhann/decentralized-file-sharing-system/constants"
+)
+
+// maliciousHandler answers every lookup with attacker-controlled contacts
+// that are closer to the target than any honest node and all point back to
+// itself, and returns a forged value for every FIND_VALUE.
+type maliciousHandler struct {
+	Self Contact
+}
+
+func (m *maliciousHandler) HandlePing(sender Contact) {}
+
+func (m *maliciousHandler) HandleFindNode(sender Contact, targetID NodeID) []Contact {
+	return m.fakeContacts(targetID)
+}
+
+func (m *maliciousHandler) HandleStore(sender Contact, req StoreRequest) error { return nil }
+func (m *maliciousHandler) HandleStoreBatch(sender Contact, items []StoreRequest) (int, error) {
+	return len(items), nil
+}
+
+func (m *maliciousHandler) HandleFindValue(sender Contact, key NodeID) ([]byte, []Contact) {
+	return []byte("forged value"), nil
+}
+
+func (m *maliciousHandler) HandleDelete(sender Contact, tombstone Tombstone) error {
+	return nil
+}
+
+func (m *maliciousHandler) HandleAddProvider(sender Contact, req AddProviderRequest) error {
+	return nil
+}
+
+func (m *maliciousHandler) HandleGetProviders(sender Contact, key NodeID) ([]ProviderRecord, []Contact) {
+	return nil, m.fakeContacts(key)
+}
+
+func (m *maliciousHandler) HandleSyncDigest(sender Contact, req SyncDigestRequest) SyncDigestResponse {
+	return SyncDigestResponse{}
+}
+
+func (m *maliciousHandler) HandleSyncPull(sender Contact, req SyncPullRequest) SyncPullResponse {
+	return SyncPullResponse{}
+}
+
+func (m *maliciousHandler) HandleLeave(sender Contact) bool { return false }
+
+func (m *maliciousHandler) HandleJoinRequest(sender Contact, payload JoinRequestPayload) (JoinChallengePayload, error) {
+	return JoinChallengePayload{}, nil
+}
+
+func (m *maliciousHandler) HandleJoinResponse(sender Contact, payload JoinResponsePayload) (JoinAckPayload, error) {
+	return JoinAckPayload{}, nil
+}
+
+// fakeContacts returns K sybil contacts that differ from the target only in the last byte
+func (m *maliciousHandler) fakeContacts(targetID NodeID) []Contact {
+	fakes := make([]Contact, 0, constants.K)
+	for i := 0; i < constants.K; i++ {
+		id := targetID
+		id[len(id)-1] ^= byte(i + 1)
+		fakes = append(fakes, Contact{ID: id, IP: m.Self.IP, Port: m.Self.Port})
+	}
+	return fakes
+}
+
+// startTestNetwork opens a UDP socket on a random local port and serves the
+// handler until the test ends
+func startTestNetwork(t *testing.T, id NodeID, handler func(self Contact, network *Network) MessageHandler) Contact {
+	t.Helper()
+
+	network, err := NewNetwork("127.0.0.1:0", id)
+	if err != nil {
+		t.Fatalf("Failed to start network: %v", err)
+	}
+	t.Cleanup(func() { network.Close() })
+
+	self := Contact{
+		ID:   id,
+		IP:   "127.0.0.1",
+		Port: network.Conn.LocalAddr().(*net.UDPAddr).Port,
+	}
+	network.SetHandler(handler(self, network))
+	go network.Listen()
+
+	return self
+}
+
+// startHonestNode starts a regular Node listening on a random local port
+func startHonestNode(t *testing.T, id NodeID) *Node {
+	t.Helper()
+
+	var node *Node
+	startTestNetwork(t, id, func(self Contact, network *Network) MessageHandler {
+		node = NewNode(self, nil)
+		node.Network = network
+		return node
+	})
+	return node
+}
+
+// startMaliciousNode starts a maliciousHandler listening on a random local port
+func startMaliciousNode(t *testing.T, id NodeID) Contact {
+	t.Helper()
+
+	return startTestNetwork(t, id, func(self Contact, network *Network) MessageHandler {
+		return &maliciousHandler{Self: self}
+	})
+}
+
+// idWithPrefix returns a NodeID whose first byte is prefix, so XOR distances
+// to the all-zero target are ordered by prefix.
+func idWithPrefix(prefix byte, tail byte) NodeID {
+	var id NodeID
+	id[0] = prefix
+	id[len(id)-1] = tail
+	return id
+}
+
+// eclipseTopology builds a querier that knows one malicious node (closest to the
+// target) and two honest nodes, which both know an honest node even closer.
+func eclipseTopology(t *testing.T) (querier *Node, closest *Node, honest []*Node) {
+	t.Helper()
+
+	malicious := startMaliciousNode(t, idWithPrefix(0x10, 1))
+	h1 := startHonestNode(t, idWithPrefix(0x20, 1))
+	h2 := startHonestNode(t, idWithPrefix(0x30, 1))
+	closest = startHonestNode(t, idWithPrefix(0x01, 1))
+
+	h1.RoutingTable.Update(closest.Self)
+	h2.RoutingTable.Update(closest.Self)
+	closest.RoutingTable.Update(h1.Self)
+	closest.RoutingTable.Update(h2.Self)
+
+	querier = startHonestNode(t, idWithPrefix(0xF0, 1))
+	querier.RoutingTable.Update(malicious)
+	querier.RoutingTable.Update(h1.Self)
+	querier.RoutingTable.Update(h2.Self)
+
+	return querier, closest, []*Node{h1, h2, closest}
+}
+
+// TestSinglePathIsEclipsed shows the attack the disjoint paths defend against
+func TestSinglePathIsEclipsed(t *testing.T) {
+	querier, closest, _ := eclipseTopology(t)
+	querier.Config.DisjointPaths = 1
+
+	target := NodeID{}
+	contacts, _ := querier.NodeLookup(target)
+
+	for _, c := range contacts {
+		if c.ID == closest.Self.ID {
+			t.Fatal("Expected single-path lookup to be captured by the malicious node")
+		}
+	}
+}
+
+// TestDisjointLookupFindsHonestNodes tests that a malicious node cannot capture all paths
+func TestDisjointLookupFindsHonestNodes(t *testing.T) {
+	querier, closest, _ := eclipseTopology(t)
+	querier.Config.DisjointPaths = 3
+
+	target := NodeID{}
+	contacts, hops := querier.NodeLookup(target)
+
+	found := false
+	for _, c := range contacts {
+		if c.ID == closest.Self.ID {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("Honest closest node missing from lookup result (hops: %d): %v", hops, contacts)
+	}
+}
+
+// TestDisjointFindValueRejectsForgedValue tests that the forged value loses the path vote
+func TestDisjointFindValueRejectsForgedValue(t *testing.T) {
+	querier, _, honest := eclipseTopology(t)
+	querier.Config.DisjointPaths = 3
+
+	key := NodeID{}
+	value := []byte("honest value")
+	for _, h := range honest {
+		h.StorageMux.Lock()
+		h.Storage[key] = value
+		h.StorageMux.Unlock()
+	}
+
+	got, _, err := querier.FindValue(key)
+	if err != nil {
+		t.Fatalf("FindValue failed: %v", err)
+	}
+	if !bytes.Equal(got, value) {
+		t.Fatalf("Expected %q, got %q", value, got)
+	}
+}
+
+// TestAgreeOnValue tests the quorum rules used by FindValue
+func TestAgreeOnValue(t *testing.T) {
+	key := NodeID{1}
+
+	// Majority wins
+	results := []PathResult{{Value: []byte("a")}, {Value: []byte("a")}, {Value: []byte("b")}}
+	if v, err := agreeOnValue(key, results); err != nil || string(v) != "a" {
+		t.Errorf("Expected majority value 'a', got %q (%v)", v, err)
+	}
+
+	// No majority
+	results = []PathResult{{Value: []byte("a")}, {Value: []byte("b")}, {}}
+	if _, err := agreeOnValue(key, results); err == nil {
+		t.Error("Expected conflicting values to be rejected")
+	}
+
+	// A content-addressed value verifies itself
+	content := []byte("self-verifying")
+	results = []PathResult{{Value: []byte("forged")}, {Value: content}, {}}
+	if v, err := agreeOnValue(NodeID(sha256.Sum256(content)), results); err != nil || !bytes.Equal(v, content) {
+		t.Errorf("Expected self-verifying value to be accepted, got %q (%v)", v, err)
+	}
+}
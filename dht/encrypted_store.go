@@ -0,0 +1,237 @@
+package dht
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/kutluhann/decentralized-file-sharing-system/encryption"
+	"github.com/kutluhann/decentralized-file-sharing-system/id_tools"
+)
+
+// ---------------------------------------------------------
+// END-TO-END ENCRYPTION
+// A value is encrypted on the uploading node before it enters the
+// DHT. The ciphertext is stored at its own SHA-256, chunked like a
+// streamed value when it is larger than a chunk, and a manifest
+// with the content key wrapped for each recipient is stored at the
+// value's key. Replicas only ever see ciphertext and wrapped keys.
+// With convergent encryption the content key is derived from the
+// plaintext, so identical values share one ciphertext; such a
+// ciphertext may belong to several manifests and is never deleted.
+// The manifest is signed by its owner and versioned like a named
+// record, so only the owner can change who may read the value.
+// ---------------------------------------------------------
+
+const encryptedFormat = "dfss-encrypted-v1"
+
+// EncryptedManifest is stored at the key of an encrypted value
+type EncryptedManifest struct {
+	Format     string      `json:"format"`               // Always encryptedFormat, must stay the first field
+	Size       int         `json:"size"`                 // Size of the plaintext
+	Ciphertext NodeID      `json:"ciphertext"`           // Key of the ciphertext, its SHA256
+	Keys       []SharedKey `json:"keys"`                 // Content key wrapped for each recipient
+	Owner      []byte      `json:"owner"`                // PKIX public key of the owner
+	Convergent bool        `json:"convergent,omitempty"` // Ciphertext may be shared with other manifests
+	Version    uint64      `json:"version"`
+	Signature  []byte      `json:"signature"` // Owner's signature over the key, ciphertext, recipients and version
+}
+
+// SharedKey is the content key wrapped for one recipient
+type SharedKey struct {
+	PeerID NodeID                `json:"peer_id"`
+	Key    encryption.WrappedKey `json:"key"`
+}
+
+// ParseEncryptedManifest returns the manifest if value is one
+func ParseEncryptedManifest(value []byte) (*EncryptedManifest, bool) {
+	if !bytes.HasPrefix(value, []byte(`{"format":"`+encryptedFormat+`"`)) {
+		return nil, false
+	}
+
+	var manifest EncryptedManifest
+	if err := json.Unmarshal(value, &manifest); err != nil {
+		return nil, false
+	}
+	return &manifest, true
+}
+
+// manifestMessage returns the message an owner signs to publish the manifest stored at key
+func manifestMessage(key NodeID, m *EncryptedManifest) string {
+	keys, _ := json.Marshal(m.Keys)
+	return fmt.Sprintf("SHARE|%s|%s|%d|%t|%d|%x",
+		key.String(), m.Ciphertext.String(), m.Size, m.Convergent, m.Version, sha256.Sum256(keys))
+}
+
+// verifyManifest checks the owner's signature and that every key is wrapped for the PeerID it names
+func verifyManifest(key NodeID, m *EncryptedManifest) error {
+	ownerKey, err := parseOwnerKey(m.Owner)
+	if err != nil {
+		return err
+	}
+	for _, k := range m.Keys {
+		peerID, err := peerIDOf(k.Key.Recipient)
+		if err != nil || peerID != k.PeerID {
+			return fmt.Errorf("wrapped key does not belong to %s", k.PeerID.String()[:16])
+		}
+	}
+	if !id_tools.VerifySignature(*ownerKey, manifestMessage(key, m), m.Signature) {
+		return fmt.Errorf("invalid manifest signature")
+	}
+	return nil
+}
+
+// checkManifestUpdate decides whether value may replace a current manifest:
+// only a higher version from the same owner may. The caller verifies the new manifest first.
+func checkManifestUpdate(current []byte, value []byte) error {
+	existing, hadManifest := ParseEncryptedManifest(current)
+	if !hadManifest || bytes.Equal(current, value) {
+		return nil
+	}
+
+	manifest, isManifest := ParseEncryptedManifest(value)
+	if !isManifest {
+		return fmt.Errorf("an encrypted manifest cannot be overwritten by a plain value")
+	}
+	if !bytes.Equal(manifest.Owner, existing.Owner) {
+		return fmt.Errorf("manifest belongs to another owner")
+	}
+	if manifest.Version <= existing.Version {
+		return fmt.Errorf("manifest version %d is not newer than %d", manifest.Version, existing.Version)
+	}
+	return nil
+}
+
+// wrapFor wraps a content key for each recipient
+func wrapFor(contentKey []byte, recipients []*ecdsa.PublicKey) ([]SharedKey, error) {
+	keys := make([]SharedKey, 0, len(recipients))
+	for _, recipient := range recipients {
+		wrapped, err := encryption.WrapKey(contentKey, recipient)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, SharedKey{
+			PeerID: NodeID(id_tools.GeneratePeerIDFromPublicKey(recipient)),
+			Key:    wrapped,
+		})
+	}
+	return keys, nil
+}
+
+// StoreEncrypted encrypts a value and stores it so that only we and the
+// recipients can read it. Retrieve decrypts it for any of them. A convergent
+// upload is deduplicated with identical values, at the cost of letting anyone
+// who can guess the value confirm that it is stored.
+func (n *Node) StoreEncrypted(key NodeID, value []byte, recipients []*ecdsa.PublicKey, convergent bool) error {
+	if n.PrivKey == nil {
+		return fmt.Errorf("node has no identity key to encrypt for")
+	}
+
+	seal := encryption.Seal
+	if convergent {
+		seal = encryption.SealConvergent
+	}
+	contentKey, ciphertext, err := seal(value)
+	if err != nil {
+		return err
+	}
+
+	keys, err := wrapFor(contentKey, append([]*ecdsa.PublicKey{&n.PrivKey.PublicKey}, recipients...))
+	if err != nil {
+		return err
+	}
+
+	manifest := &EncryptedManifest{
+		Format:     encryptedFormat,
+		Size:       len(value),
+		Keys:       keys,
+		Owner:      n.ownerKey(),
+		Convergent: convergent,
+	}
+
+	fmt.Printf("[ENCRYPT] Storing key %s (%d bytes) encrypted for %d recipients (convergent: %t)\n",
+		key.String()[:16], len(value), len(manifest.Keys), convergent)
+
+	if manifest.Ciphertext, err = n.storeCiphertext(ciphertext); err != nil {
+		return err
+	}
+
+	version := uint64(1)
+	if current, _, err := n.FindValue(key); err == nil {
+		if existing, ok := ParseEncryptedManifest(current); ok {
+			version = existing.Version + 1
+		}
+	}
+	return n.publishManifest(key, manifest, version)
+}
+
+// storeCiphertext stores a ciphertext at its SHA-256, in chunks if it does not
+// fit in one. Returns the key of the ciphertext.
+func (n *Node) storeCiphertext(ciphertext []byte) (NodeID, error) {
+	key := NodeID(sha256.Sum256(ciphertext))
+	if _, _, err := n.StoreStream(key, bytes.NewReader(ciphertext)); err != nil {
+		return key, fmt.Errorf("failed to store ciphertext: %v", err)
+	}
+	return key, nil
+}
+
+// publishManifest signs a manifest with the given version and stores it at key
+func (n *Node) publishManifest(key NodeID, manifest *EncryptedManifest, version uint64) error {
+	manifest.Version = version
+	manifest.Signature = id_tools.SignMessage(*n.PrivKey, manifestMessage(key, manifest))
+
+	manifestBytes, _ := json.Marshal(manifest)
+	return n.Store(key, manifestBytes)
+}
+
+// contentKey unwraps the content key of a manifest with our identity key
+func (n *Node) contentKey(manifest *EncryptedManifest) ([]byte, error) {
+	if n.PrivKey == nil {
+		return nil, encryption.ErrNotRecipient
+	}
+	keys := make([]encryption.WrappedKey, len(manifest.Keys))
+	for i, k := range manifest.Keys {
+		keys[i] = k.Key
+	}
+	return encryption.FindKey(keys, n.PrivKey)
+}
+
+// openEncrypted fetches the ciphertext of a manifest and decrypts it with our identity key.
+// Returns: plaintext, hopCount, error
+func (n *Node) openEncrypted(manifest *EncryptedManifest) ([]byte, int, error) {
+	contentKey, err := n.contentKey(manifest)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ciphertext, hopCount, err := n.FindValue(manifest.Ciphertext)
+	if err != nil {
+		return nil, hopCount, fmt.Errorf("ciphertext not found: %v", err)
+	}
+	// A ciphertext larger than a chunk is stored chunked
+	if chunked, ok := ParseChunkManifest(ciphertext); ok {
+		reader, err := n.openChunks(chunked, hopCount)
+		if err != nil {
+			return nil, hopCount, fmt.Errorf("ciphertext not found: %v", err)
+		}
+		ciphertext, err = io.ReadAll(reader)
+		reader.Close()
+		hopCount = reader.HopCount
+		if err != nil {
+			return nil, hopCount, fmt.Errorf("ciphertext not found: %v", err)
+		}
+	}
+	if sha256.Sum256(ciphertext) != manifest.Ciphertext {
+		return nil, hopCount, fmt.Errorf("ciphertext does not match its key")
+	}
+
+	value, err := encryption.Open(contentKey, ciphertext)
+	if err != nil {
+		return nil, hopCount, err
+	}
+	fmt.Printf("[ENCRYPT] ✓ Decrypted %d bytes\n", len(value))
+	return value, hopCount, nil
+}
@@ -0,0 +1,113 @@
+package dht
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"github.com/kutluhann/decentralized-file-sharing-system/constants"
+	"github.com/kutluhann/decentralized-file-sharing-system/encryption"
+)
+
+// TestEncryptedStoreRetrieve tests that recipients decrypt a value that replicas only see as ciphertext
+func TestEncryptedStoreRetrieve(t *testing.T) {
+	uploader := startOwnerNode(t, idWithPrefix(0x80, 1))
+	recipient := startOwnerNode(t, idWithPrefix(0x40, 1))
+	outsider := startOwnerNode(t, idWithPrefix(0xC0, 1))
+	r1 := startHonestNode(t, idWithPrefix(0x01, 1))
+	r2 := startHonestNode(t, idWithPrefix(0x02, 1))
+	connect(uploader, recipient, outsider, r1, r2)
+
+	key := idWithPrefix(0x00, 1)
+	plaintext := []byte("only for the two of us")
+	if err := uploader.StoreEncrypted(key, plaintext, []*ecdsa.PublicKey{&recipient.PrivKey.PublicKey}, false); err != nil {
+		t.Fatalf("StoreEncrypted failed: %v", err)
+	}
+
+	// No node stores the plaintext
+	for _, n := range []*Node{uploader, recipient, outsider, r1, r2} {
+		n.StorageMux.RLock()
+		for _, value := range n.Storage {
+			if bytes.Contains(value, plaintext) {
+				t.Errorf("Node %x stores the plaintext", n.Self.ID[0])
+			}
+		}
+		n.StorageMux.RUnlock()
+	}
+
+	for _, n := range []*Node{uploader, recipient} {
+		value, _, err := n.Retrieve(key)
+		if err != nil || !bytes.Equal(value, plaintext) {
+			t.Fatalf("Node %x could not decrypt: %q, %v", n.Self.ID[0], value, err)
+		}
+	}
+
+	if _, _, err := outsider.Retrieve(key); !errors.Is(err, encryption.ErrNotRecipient) {
+		t.Errorf("Expected ErrNotRecipient for an outsider, got %v", err)
+	}
+}
+
+// TestEncryptedLargeValue tests that a ciphertext larger than a chunk is stored in chunks
+func TestEncryptedLargeValue(t *testing.T) {
+	uploader := startOwnerNode(t, idWithPrefix(0x80, 1))
+	recipient := startOwnerNode(t, idWithPrefix(0x40, 1))
+	r1 := startHonestNode(t, idWithPrefix(0x01, 1))
+	r2 := startHonestNode(t, idWithPrefix(0x02, 1))
+	connect(uploader, recipient, r1, r2)
+
+	key := idWithPrefix(0x00, 1)
+	plaintext := make([]byte, 3*constants.StreamChunkBytes)
+	rand.Read(plaintext)
+	if err := uploader.StoreEncrypted(key, plaintext, []*ecdsa.PublicKey{&recipient.PrivKey.PublicKey}, false); err != nil {
+		t.Fatalf("StoreEncrypted failed: %v", err)
+	}
+
+	// Every stored value fits in one STORE
+	r1.StorageMux.RLock()
+	for k, value := range r1.Storage {
+		if len(value) > constants.StreamChunkBytes {
+			t.Errorf("Key %s holds %d bytes", k.String()[:16], len(value))
+		}
+	}
+	r1.StorageMux.RUnlock()
+
+	value, _, err := recipient.Retrieve(key)
+	if err != nil || !bytes.Equal(value, plaintext) {
+		t.Fatalf("Recipient could not decrypt: %d bytes, %v", len(value), err)
+	}
+}
+
+// TestConvergentDeduplicates tests that owners of the same value share one ciphertext that outlives a delete
+func TestConvergentDeduplicates(t *testing.T) {
+	a := startOwnerNode(t, idWithPrefix(0x80, 1))
+	b := startOwnerNode(t, idWithPrefix(0x40, 1))
+	r1 := startHonestNode(t, idWithPrefix(0x01, 1))
+	r2 := startHonestNode(t, idWithPrefix(0x02, 1))
+	connect(a, b, r1, r2)
+
+	plaintext := []byte("a widely shared file")
+	keyA, keyB := idWithPrefix(0x00, 1), idWithPrefix(0x03, 1)
+	if err := a.StoreEncrypted(keyA, plaintext, nil, true); err != nil {
+		t.Fatalf("StoreEncrypted failed: %v", err)
+	}
+	if err := b.StoreEncrypted(keyB, plaintext, nil, true); err != nil {
+		t.Fatalf("StoreEncrypted failed: %v", err)
+	}
+
+	valueA, _, _ := a.FindValue(keyA)
+	valueB, _, _ := b.FindValue(keyB)
+	manifestA, _ := ParseEncryptedManifest(valueA)
+	manifestB, _ := ParseEncryptedManifest(valueB)
+	if manifestA == nil || manifestB == nil || manifestA.Ciphertext != manifestB.Ciphertext {
+		t.Fatalf("Expected both manifests to point to one ciphertext")
+	}
+
+	if err := a.Delete(keyA); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if value, _, err := b.Retrieve(keyB); err != nil || !bytes.Equal(value, plaintext) {
+		t.Errorf("Shared ciphertext was deleted with the other owner's value: %q, %v", value, err)
+	}
+}
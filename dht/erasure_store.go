@@ -0,0 +1,283 @@
+package dht
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/kutluhann/decentralized-file-sharing-system/constants"
+	"github.com/kutluhann/decentralized-file-sharing-system/erasure"
+)
+
+// ---------------------------------------------------------
+// ERASURE-CODED STORAGE
+// Instead of K full copies, a value is split into n shards of
+// which any m rebuild it. Each shard is stored prefixed with its
+// index at its own key (the SHA-256 of the stored bytes) on the
+// single closest node, and a manifest with the coding parameters
+// is stored at the value's key. A shard has to fit in a chunk, which
+// limits erasure coding to values of a few chunks.
+// ---------------------------------------------------------
+
+const manifestFormat = "dfss-erasure-v1"
+
+// ErasureManifest is stored, fully replicated, at the key of an erasure-coded value
+type ErasureManifest struct {
+	Format      string   `json:"format"` // Always manifestFormat, must stay the first field
+	Size        int      `json:"size"`   // Size of the original value
+	DataShards  int      `json:"data_shards"`
+	TotalShards int      `json:"total_shards"`
+	ShardKeys   []NodeID `json:"shard_keys"`      // SHA256 of each stored shard, in shard order
+	Hash        [32]byte `json:"hash"`            // SHA256 of the original value
+	Owner       []byte   `json:"owner,omitempty"` // Owner of the shards, so repaired shards can still be deleted
+}
+
+// ParseManifest returns the manifest if value is one
+func ParseManifest(value []byte) (*ErasureManifest, bool) {
+	if !bytes.HasPrefix(value, []byte(`{"format":"`+manifestFormat+`"`)) {
+		return nil, false
+	}
+
+	var manifest ErasureManifest
+	if err := json.Unmarshal(value, &manifest); err != nil {
+		return nil, false
+	}
+	if manifest.TotalShards != len(manifest.ShardKeys) {
+		return nil, false
+	}
+	return &manifest, true
+}
+
+// StoreErasure erasure codes a value into totalShards shards, any dataShards of
+// which rebuild it, and stores the manifest at key with the usual K-fold replication.
+func (n *Node) StoreErasure(key NodeID, value []byte, dataShards, totalShards int) error {
+	coder, err := erasure.New(dataShards, totalShards)
+	if err != nil {
+		return err
+	}
+	// A shard is sent in one STORE, so it has to fit in the largest chunk with its index
+	if coder.ShardSize(len(value))+1 > constants.StreamChunkBytes {
+		return fmt.Errorf("%w: erasure coding is limited to %d bytes",
+			ErrValueTooLarge, MaxErasureBytes(dataShards))
+	}
+
+	fmt.Printf("[ERASURE] Storing key %s (%d bytes) as %d shards, any %d rebuild it\n",
+		key.String()[:16], len(value), totalShards, dataShards)
+
+	shards := coder.Encode(value)
+	manifest := ErasureManifest{
+		Format:      manifestFormat,
+		Size:        len(value),
+		DataShards:  dataShards,
+		TotalShards: totalShards,
+		ShardKeys:   make([]NodeID, totalShards),
+		Hash:        sha256.Sum256(value),
+		Owner:       n.ownerKey(),
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, totalShards)
+	for i, shard := range shards {
+		stored := shardValue(i, shard)
+		manifest.ShardKeys[i] = NodeID(sha256.Sum256(stored))
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = n.storeShard(manifest.ShardKeys[i], stored, manifest.Owner)
+		}(i)
+	}
+	wg.Wait()
+
+	stored := 0
+	for i, err := range errs {
+		if err != nil {
+			fmt.Printf("[ERASURE] ✗ Failed to store shard %d: %v\n", i, err)
+			continue
+		}
+		stored++
+	}
+	if stored < dataShards {
+		return fmt.Errorf("only %d of %d shards stored, %d needed", stored, totalShards, dataShards)
+	}
+
+	manifestBytes, _ := json.Marshal(manifest)
+	return n.Store(key, manifestBytes)
+}
+
+// MaxErasureBytes returns the size of the largest value StoreErasure can split
+// into dataShards shards that each fit in one datagram, like the largest chunk
+func MaxErasureBytes(dataShards int) int {
+	return dataShards * (constants.StreamChunkBytes - 1)
+}
+
+// shardValue prefixes a shard with its index, so equal shards of a
+// repetitive value still get distinct keys
+func shardValue(index int, shard []byte) []byte {
+	return append([]byte{byte(index)}, shard...)
+}
+
+// storeShard places a shard on the single node closest to its key
+func (n *Node) storeShard(shardKey NodeID, shard []byte, owner []byte) error {
+	closest, _ := n.NodeLookup(shardKey)
+
+	target := n.Self
+	for _, contact := range closest {
+		if contact.ID.Xor(shardKey).Less(target.ID.Xor(shardKey)) {
+			target = contact
+		}
+	}
+
+	if target.ID == n.Self.ID {
+		return n.storeLocal(n.Self, StoreRequest{Key: shardKey, Value: shard, Shard: true, Owner: owner})
+	}
+	return n.Network.SendShardStore(target, shardKey, shard, owner)
+}
+
+// Retrieve finds a value in the DHT and, if it is erasure coded, fetches the
+// shards and rebuilds it. Encrypted values are decrypted if we are a recipient
+// and chunked values are reassembled.
+// Returns: value, hopCount, error
+func (n *Node) Retrieve(key NodeID) ([]byte, int, error) {
+	value, hopCount, err := n.FindValue(key)
+	if err != nil {
+		return nil, hopCount, err
+	}
+	return n.decodeValue(key, value, hopCount)
+}
+
+// decodeValue turns the value found at key into the value that was stored
+// Returns: value, hopCount, error
+func (n *Node) decodeValue(key NodeID, value []byte, hopCount int) ([]byte, int, error) {
+	if chunked, ok := ParseChunkManifest(value); ok {
+		reader, err := n.openChunks(chunked, hopCount)
+		if err != nil {
+			return nil, hopCount, err
+		}
+		defer reader.Close()
+		value, err := io.ReadAll(reader)
+		return value, reader.HopCount, err
+	}
+
+	if encrypted, ok := ParseEncryptedManifest(value); ok {
+		value, hops, err := n.openEncrypted(encrypted)
+		return value, hopCount + hops, err
+	}
+
+	manifest, ok := ParseManifest(value)
+	if !ok {
+		return value, hopCount, nil
+	}
+
+	shards, hops := n.fetchShards(manifest)
+	hopCount += hops
+
+	coder, err := erasure.New(manifest.DataShards, manifest.TotalShards)
+	if err != nil {
+		return nil, hopCount, err
+	}
+
+	missing := 0
+	for _, shard := range shards {
+		if shard == nil {
+			missing++
+		}
+	}
+
+	value, err = coder.Decode(shards, manifest.Size)
+	if err != nil {
+		return nil, hopCount, fmt.Errorf("cannot rebuild value, %d of %d shards missing: %w",
+			missing, manifest.TotalShards, err)
+	}
+	if sha256.Sum256(value) != manifest.Hash {
+		return nil, hopCount, fmt.Errorf("rebuilt value does not match manifest hash")
+	}
+
+	fmt.Printf("[ERASURE] ✓ Rebuilt key %s from %d of %d shards\n",
+		key.String()[:16], manifest.TotalShards-missing, manifest.TotalShards)
+
+	if missing > 0 {
+		go n.repairShards(manifest, coder, shards)
+	}
+
+	return value, hopCount, nil
+}
+
+// fetchShards looks up all shards of a manifest in parallel.
+// Missing or corrupted shards are left nil. Returns the shards and total hops.
+func (n *Node) fetchShards(manifest *ErasureManifest) ([][]byte, int) {
+	shards := make([][]byte, manifest.TotalShards)
+	hops := make([]int, manifest.TotalShards)
+
+	var wg sync.WaitGroup
+	for i, shardKey := range manifest.ShardKeys {
+		wg.Add(1)
+		go func(i int, shardKey NodeID) {
+			defer wg.Done()
+			stored, h, err := n.FindValue(shardKey)
+			hops[i] = h
+			// Shard keys are content hashes, so a shard verifies itself
+			if err == nil && len(stored) > 1 && NodeID(sha256.Sum256(stored)) == shardKey {
+				shards[i] = stored[1:]
+			}
+		}(i, shardKey)
+	}
+	wg.Wait()
+
+	total := 0
+	for _, h := range hops {
+		total += h
+	}
+	return shards, total
+}
+
+// RepairShards checks the shards of a manifest and re-stores the lost ones.
+// Returns the number of shards repaired.
+func (n *Node) RepairShards(manifest *ErasureManifest) (int, error) {
+	coder, err := erasure.New(manifest.DataShards, manifest.TotalShards)
+	if err != nil {
+		return 0, err
+	}
+
+	shards, _ := n.fetchShards(manifest)
+	return n.repairShards(manifest, coder, shards)
+}
+
+// repairShards rebuilds the nil entries of shards and stores them again
+func (n *Node) repairShards(manifest *ErasureManifest, coder *erasure.Coder, shards [][]byte) (int, error) {
+	var lost []int
+	for i, shard := range shards {
+		if shard == nil {
+			lost = append(lost, i)
+		}
+	}
+	if len(lost) == 0 {
+		return 0, nil
+	}
+
+	repaired := make([][]byte, len(shards))
+	copy(repaired, shards)
+	if err := coder.Reconstruct(repaired); err != nil {
+		fmt.Printf("[ERASURE] ✗ Cannot repair, %d of %d shards lost\n", len(lost), manifest.TotalShards)
+		return 0, err
+	}
+
+	count := 0
+	for _, i := range lost {
+		stored := shardValue(i, repaired[i])
+		if NodeID(sha256.Sum256(stored)) != manifest.ShardKeys[i] {
+			return count, fmt.Errorf("repaired shard %d does not match its key", i)
+		}
+		if err := n.storeShard(manifest.ShardKeys[i], stored, manifest.Owner); err != nil {
+			fmt.Printf("[ERASURE] ✗ Failed to store repaired shard %d: %v\n", i, err)
+			continue
+		}
+		count++
+	}
+
+	fmt.Printf("[ERASURE] ✓ Repaired %d of %d lost shards\n", count, len(lost))
+	return count, nil
+}
@@ -0,0 +1,139 @@
+package dht
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"testing"
+	"time"
+)
+
+// startErasureNetwork starts fully connected nodes for erasure coding tests
+func startErasureNetwork(t *testing.T, count int) []*Node {
+	t.Helper()
+
+	nodes := make([]*Node, count)
+	for i := range nodes {
+		nodes[i] = startHonestNode(t, idWithPrefix(byte(i*0x20), 1))
+	}
+	for _, a := range nodes {
+		for _, b := range nodes {
+			if a != b {
+				a.RoutingTable.Update(b.Self)
+			}
+		}
+	}
+	return nodes
+}
+
+// shardHolder returns the node holding a shard, or nil
+func shardHolder(nodes []*Node, key NodeID) *Node {
+	for _, n := range nodes {
+		n.StorageMux.RLock()
+		shard := n.ShardKeys[key]
+		n.StorageMux.RUnlock()
+		if shard {
+			return n
+		}
+	}
+	return nil
+}
+
+// TestErasureStoreAndRepair tests that a value survives the loss of parity-many shards
+// and that the lost shards are stored again after the read.
+func TestErasureStoreAndRepair(t *testing.T) {
+	nodes := startErasureNetwork(t, 6)
+	key := NodeID(sha256.Sum256([]byte("erasure key")))
+	value := bytes.Repeat([]byte("erasure coded value "), 50)
+
+	if err := nodes[0].StoreErasure(key, value, 4, 6); err != nil {
+		t.Fatalf("StoreErasure failed: %v", err)
+	}
+
+	stored, _, err := nodes[0].FindValue(key)
+	if err != nil {
+		t.Fatalf("Manifest not found: %v", err)
+	}
+	manifest, ok := ParseManifest(stored)
+	if !ok {
+		t.Fatalf("Expected a manifest at the value key")
+	}
+
+	// Lose two shards
+	lost := manifest.ShardKeys[:2]
+	for _, shardKey := range lost {
+		holder := shardHolder(nodes, shardKey)
+		if holder == nil {
+			t.Fatalf("Shard %s was not stored", shardKey.String()[:16])
+		}
+		holder.StorageMux.Lock()
+		delete(holder.Storage, shardKey)
+		delete(holder.ShardKeys, shardKey)
+		holder.StorageMux.Unlock()
+	}
+
+	got, _, err := nodes[5].Retrieve(key)
+	if err != nil {
+		t.Fatalf("Retrieve failed: %v", err)
+	}
+	if !bytes.Equal(got, value) {
+		t.Fatalf("Retrieved value does not match")
+	}
+
+	for _, shardKey := range lost {
+		if !waitFor(2*time.Second, func() bool { return shardHolder(nodes, shardKey) != nil }) {
+			t.Errorf("Lost shard %s was not repaired", shardKey.String()[:16])
+		}
+	}
+}
+
+// TestErasureTooManyShardsLost tests that Retrieve fails cleanly below DataShards shards
+func TestErasureTooManyShardsLost(t *testing.T) {
+	nodes := startErasureNetwork(t, 4)
+	key := NodeID(sha256.Sum256([]byte("fragile key")))
+
+	if err := nodes[0].StoreErasure(key, []byte("fragile value"), 2, 3); err != nil {
+		t.Fatalf("StoreErasure failed: %v", err)
+	}
+	stored, _, _ := nodes[0].FindValue(key)
+	manifest, ok := ParseManifest(stored)
+	if !ok {
+		t.Fatalf("Expected a manifest at the value key")
+	}
+
+	for _, shardKey := range manifest.ShardKeys[:2] {
+		holder := shardHolder(nodes, shardKey)
+		holder.StorageMux.Lock()
+		delete(holder.Storage, shardKey)
+		delete(holder.ShardKeys, shardKey)
+		holder.StorageMux.Unlock()
+	}
+
+	if _, _, err := nodes[1].Retrieve(key); err == nil {
+		t.Fatalf("Expected Retrieve to fail with 1 of 3 shards")
+	}
+}
+
+// TestErasureTooLarge tests that a value whose shards do not fit in a chunk is refused
+func TestErasureTooLarge(t *testing.T) {
+	nodes := startErasureNetwork(t, 6)
+	key := NodeID(sha256.Sum256([]byte("erasure key")))
+
+	value := make([]byte, MaxErasureBytes(4)+1)
+	if err := nodes[0].StoreErasure(key, value, 4, 6); !errors.Is(err, ErrValueTooLarge) {
+		t.Fatalf("Expected ErrValueTooLarge, got %v", err)
+	}
+	if err := nodes[0].StoreErasure(key, value[:MaxErasureBytes(4)], 4, 6); err != nil {
+		t.Fatalf("StoreErasure of the largest value failed: %v", err)
+	}
+}
+
+// TestParseManifest tests that plain values are not mistaken for manifests
+func TestParseManifest(t *testing.T) {
+	if _, ok := ParseManifest([]byte(`{"key":"value"}`)); ok {
+		t.Errorf("Plain JSON value parsed as a manifest")
+	}
+	if _, ok := ParseManifest([]byte(`{"format":"dfss-erasure-v1","total_shards":2,"shard_keys":[]}`)); ok {
+		t.Errorf("Manifest with missing shard keys accepted")
+	}
+}
@@ -0,0 +1,209 @@
+package dht
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ---------------------------------------------------------
+// EVICTION
+// When the storage quota is reached, a STORE may evict keys the
+// eviction policy ranks below the incoming one. By default cached
+// copies go first, then keys we are not among the k closest nodes
+// for, then keys farthest from our ID, least recently used first.
+// ---------------------------------------------------------
+
+// Eviction tiers, lowest is evicted first
+const (
+	TierCached         = 0 // Cached copy along a lookup path
+	TierNotResponsible = 1 // Replica of a key we are not among the k closest nodes for
+	TierResponsible    = 2 // Replica or shard we are responsible for
+)
+
+// EvictionCandidate describes a stored key for the eviction policy
+type EvictionCandidate struct {
+	Key        NodeID
+	Size       int
+	Tier       int       // TierCached, TierNotResponsible or TierResponsible
+	Distance   int       // Log2 XOR distance from our ID, larger is farther
+	LastAccess time.Time // Last time the key was stored or read
+}
+
+// EvictionPolicy orders stored keys for eviction
+type EvictionPolicy interface {
+	// Less reports whether a should be evicted before b
+	Less(a, b EvictionCandidate) bool
+}
+
+// CachedFirst evicts cached copies, then keys we are not responsible for
+type CachedFirst struct{}
+
+func (CachedFirst) Less(a, b EvictionCandidate) bool { return a.Tier < b.Tier }
+
+// FarthestFirst evicts keys farthest from our ID first
+type FarthestFirst struct{}
+
+func (FarthestFirst) Less(a, b EvictionCandidate) bool { return a.Distance > b.Distance }
+
+// LeastRecentlyUsed evicts the keys accessed longest ago first
+type LeastRecentlyUsed struct{}
+
+func (LeastRecentlyUsed) Less(a, b EvictionCandidate) bool { return a.LastAccess.Before(b.LastAccess) }
+
+// EvictionChain applies its policies in order, each one breaking the ties of the previous
+type EvictionChain []EvictionPolicy
+
+func (c EvictionChain) Less(a, b EvictionCandidate) bool {
+	for _, p := range c {
+		if p.Less(a, b) {
+			return true
+		}
+		if p.Less(b, a) {
+			return false
+		}
+	}
+	return false
+}
+
+// DefaultEvictionPolicy evicts cached and non-responsible keys first, then the farthest, then the least recently used
+func DefaultEvictionPolicy() EvictionPolicy {
+	return EvictionChain{CachedFirst{}, FarthestFirst{}, LeastRecentlyUsed{}}
+}
+
+// EvictionStats represents the eviction metrics of a node for JSON output
+type EvictionStats struct {
+	Evicted        uint64 `json:"evicted"`         // Keys evicted
+	EvictedBytes   int64  `json:"evicted_bytes"`   // Bytes freed by evictions
+	Cached         uint64 `json:"cached"`          // Evicted cached copies
+	NotResponsible uint64 `json:"not_responsible"` // Evicted keys we were not responsible for
+	Responsible    uint64 `json:"responsible"`     // Evicted keys we were responsible for
+	Refused        uint64 `json:"refused"`         // STOREs refused because nothing could be evicted
+}
+
+// GetEvictionStats returns the eviction metrics of the node
+func (n *Node) GetEvictionStats() EvictionStats {
+	n.StorageMux.RLock()
+	defer n.StorageMux.RUnlock()
+	return n.evictions
+}
+
+// --- Access tracking ---
+
+// accessTracker records when each stored key was last stored or read. It has its
+// own mutex because reads only hold the read lock of StorageMux.
+type accessTracker struct {
+	times map[NodeID]time.Time
+	mutex sync.Mutex
+}
+
+func newAccessTracker() *accessTracker {
+	return &accessTracker{
+		times: make(map[NodeID]time.Time),
+	}
+}
+
+func (a *accessTracker) touch(key NodeID) {
+	a.mutex.Lock()
+	a.times[key] = time.Now()
+	a.mutex.Unlock()
+}
+
+func (a *accessTracker) forget(key NodeID) {
+	a.mutex.Lock()
+	delete(a.times, key)
+	a.mutex.Unlock()
+}
+
+func (a *accessTracker) get(key NodeID) time.Time {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.times[key]
+}
+
+// --- Eviction ---
+
+// evictionCandidate describes a stored key. The caller must hold StorageMux.
+func (n *Node) evictionCandidate(key NodeID, size int, cached bool, lastAccess time.Time) EvictionCandidate {
+	tier := TierResponsible
+	if cached {
+		tier = TierCached
+	} else if !containsContact(n.closestWithSelf(key), n.Self.ID) {
+		tier = TierNotResponsible
+	}
+	return EvictionCandidate{
+		Key:        key,
+		Size:       size,
+		Tier:       tier,
+		Distance:   len(key)*8 - n.Self.ID.PrefixLen(key),
+		LastAccess: lastAccess,
+	}
+}
+
+// evictFor frees at least need bytes for an incoming value by evicting keys the
+// policy ranks before it. Nothing is evicted if that cannot free enough space.
+// The caller must hold StorageMux.
+func (n *Node) evictFor(key NodeID, value []byte, cached bool, need int64) bool {
+	if n.Eviction == nil {
+		return false
+	}
+
+	incoming := n.evictionCandidate(key, len(value), cached, time.Now())
+
+	var candidates []EvictionCandidate
+	for k, v := range n.Storage {
+		if k == key {
+			continue
+		}
+		_, isCached := n.CacheExpiry[k]
+		c := n.evictionCandidate(k, len(v), isCached, n.access.get(k))
+		if n.Eviction.Less(c, incoming) {
+			candidates = append(candidates, c)
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return n.Eviction.Less(candidates[i], candidates[j])
+	})
+
+	// Free some headroom so a full node does not rank its keys on every STORE
+	target := need + n.Limits.MaxBytes*int64(n.Config.EvictionHeadroomPercent)/100
+	var victims []EvictionCandidate
+	var freed int64
+	for _, c := range candidates {
+		if freed >= target {
+			break
+		}
+		victims = append(victims, c)
+		freed += int64(c.Size)
+	}
+	if freed < need {
+		return false
+	}
+
+	for _, c := range victims {
+		n.evictLocked(c)
+	}
+	fmt.Printf("[EVICT] Evicted %d keys (%d bytes) for key %s\n", len(victims), freed, key.String()[:16])
+	return true
+}
+
+// evictLocked drops a key and everything we know about it. The caller must hold StorageMux.
+func (n *Node) evictLocked(c EvictionCandidate) {
+	n.deleteLocked(c.Key)
+	delete(n.CacheExpiry, c.Key)
+	delete(n.ShardKeys, c.Key)
+	delete(n.Owners, c.Key)
+
+	n.evictions.Evicted++
+	n.evictions.EvictedBytes += int64(c.Size)
+	switch c.Tier {
+	case TierCached:
+		n.evictions.Cached++
+	case TierNotResponsible:
+		n.evictions.NotResponsible++
+	default:
+		n.evictions.Responsible++
+	}
+}
@@ -0,0 +1,77 @@
+package dht
+
+import (
+	"sort"
+	"testing"
+	"time"
+)
+
+// TestDefaultEvictionOrder tests that cached, then non-responsible, then far, then old keys go first
+func TestDefaultEvictionOrder(t *testing.T) {
+	now := time.Now()
+	candidates := []EvictionCandidate{
+		{Key: NodeID{1}, Tier: TierResponsible, Distance: 10, LastAccess: now},
+		{Key: NodeID{2}, Tier: TierResponsible, Distance: 10, LastAccess: now.Add(-time.Hour)},
+		{Key: NodeID{3}, Tier: TierResponsible, Distance: 200, LastAccess: now},
+		{Key: NodeID{4}, Tier: TierNotResponsible, Distance: 1, LastAccess: now},
+		{Key: NodeID{5}, Tier: TierCached, Distance: 1, LastAccess: now},
+	}
+
+	policy := DefaultEvictionPolicy()
+	sort.Slice(candidates, func(i, j int) bool {
+		return policy.Less(candidates[i], candidates[j])
+	})
+
+	want := []byte{5, 4, 3, 2, 1}
+	for i, c := range candidates {
+		if c.Key[0] != want[i] {
+			t.Fatalf("Eviction order position %d: expected key %d, got %d", i, want[i], c.Key[0])
+		}
+	}
+}
+
+// TestFullNodeEvicts tests that a full node makes room by evicting lower ranked
+// keys and only refuses values ranked below everything it holds
+func TestFullNodeEvicts(t *testing.T) {
+	node := startHonestNode(t, idWithPrefix(0x01, 1))
+	node.Limits.MaxBytes = 20
+	peer := Contact{ID: idWithPrefix(0x30, 1)}
+	value := []byte("0123456789")
+
+	cachedKey := idWithPrefix(0x40, 1)
+	closest := idWithPrefix(0x01, 0)
+	far := idWithPrefix(0x80, 1)
+	mustStore := func(key NodeID, ttl int64) {
+		t.Helper()
+		if err := node.HandleStore(peer, StoreRequest{Key: key, Value: value, TTL: ttl}); err != nil {
+			t.Fatalf("Store of key %x failed: %v", key[0], err)
+		}
+	}
+
+	mustStore(cachedKey, 60)
+	mustStore(closest, 0)
+	// Full: the cached copy makes room
+	mustStore(far, 0)
+	if hasKey(node, cachedKey, value) || !hasKey(node, closest, value) {
+		t.Fatalf("Expected only the cached copy to be evicted")
+	}
+
+	// A key near our ID displaces the far one
+	mustStore(idWithPrefix(0x01, 3), 0)
+	if hasKey(node, far, value) || !hasKey(node, closest, value) {
+		t.Fatalf("Expected the farthest key to be evicted")
+	}
+
+	// Everything held is nearer than this key, so it is refused
+	if err := node.HandleStore(peer, StoreRequest{Key: idWithPrefix(0x90, 1), Value: value}); err == nil {
+		t.Fatalf("Expected a far key to be refused by a full node")
+	}
+
+	stats := node.GetEvictionStats()
+	if stats.Evicted != 2 || stats.Cached != 1 || stats.Responsible != 1 || stats.Refused != 1 || stats.EvictedBytes != 20 {
+		t.Errorf("Unexpected eviction stats: %+v", stats)
+	}
+	if usage := node.GetStorageUsage(); usage.Bytes != 20 {
+		t.Errorf("Expected 20 bytes stored, got %d", usage.Bytes)
+	}
+}
@@ -0,0 +1,114 @@
+package dht
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"sort"
+)
+
+// ---------------------------------------------------------
+// REPLICA HANDOFF
+// When a new peer shows up that belongs to the k closest nodes
+// of a key we store, it gets the key right away instead of at the
+// next replication tick. If that pushes us out of the k closest,
+// we drop our copy once the new set of replicas is confirmed.
+// ---------------------------------------------------------
+
+// handoffTo is called the first time a peer is added to the routing table
+func (n *Node) handoffTo(newcomer Contact) {
+	if n.Network == nil || newcomer.ID == n.Self.ID {
+		return
+	}
+
+	// Snapshot replicas; cached copies and shards are never handed off
+	n.StorageMux.RLock()
+	replicas := make([]StoreRequest, 0, len(n.Storage))
+	for key, value := range n.Storage {
+		if n.isReplica(key) {
+			replicas = append(replicas, StoreRequest{Key: key, Value: value, Owner: n.Owners[key]})
+		}
+	}
+	n.StorageMux.RUnlock()
+
+	handedOff := 0
+	for _, replica := range replicas {
+		key, value := replica.Key, replica.Value
+		closest := n.closestWithSelf(key)
+		if !containsContact(closest, newcomer.ID) {
+			continue
+		}
+
+		if err := n.Network.SendStore(newcomer, key, value, replica.Owner); err != nil {
+			fmt.Printf("[HANDOFF] ✗ Failed to hand off key %s to %s: %v\n",
+				key.String()[:16], newcomer.ID.String()[:16], err)
+			continue
+		}
+		handedOff++
+
+		if !containsContact(closest, n.Self.ID) {
+			n.dropIfReplicated(key, value, closest)
+		}
+	}
+
+	if handedOff > 0 {
+		fmt.Printf("[HANDOFF] ✓ Handed off %d keys to new peer %s\n", handedOff, newcomer.ID.String()[:16])
+	}
+}
+
+// closestWithSelf returns the k closest nodes to a key among our contacts and ourselves
+func (n *Node) closestWithSelf(key NodeID) []Contact {
+	closest := []Contact{n.Self}
+	for _, c := range n.RoutingTable.GetClosestNodes(key, n.Config.K) {
+		if c.ID != n.Self.ID {
+			closest = append(closest, c)
+		}
+	}
+
+	sort.Slice(closest, func(i, j int) bool {
+		return closest[i].ID.Xor(key).Less(closest[j].ID.Xor(key))
+	})
+
+	if len(closest) > n.Config.K {
+		return closest[:n.Config.K]
+	}
+	return closest
+}
+
+// dropIfReplicated deletes our copy of a key once all of the k closest nodes
+// confirm that they hold the same value.
+func (n *Node) dropIfReplicated(key NodeID, value []byte, closest []Contact) {
+	hash := sha256.Sum256(value)
+	confirmed := 0
+	for _, contact := range closest {
+		if held, err := n.holdsValue(contact, key, hash); err == nil && held {
+			confirmed++
+		}
+	}
+
+	if confirmed < n.Config.K {
+		fmt.Printf("[HANDOFF] Keeping key %s, only %d/%d replicas confirmed\n",
+			key.String()[:16], confirmed, n.Config.K)
+		return
+	}
+
+	n.StorageMux.Lock()
+	if current, exists := n.Storage[key]; exists && bytes.Equal(current, value) {
+		n.deleteLocked(key)
+		delete(n.Owners, key)
+	}
+	n.StorageMux.Unlock()
+	n.Replication.Forget(key)
+
+	fmt.Printf("[HANDOFF] ✓ Dropped key %s, no longer among the %d closest nodes\n",
+		key.String()[:16], n.Config.K)
+}
+
+func containsContact(contacts []Contact, id NodeID) bool {
+	for _, c := range contacts {
+		if c.ID == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,119 @@
+package dht
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+// waitFor polls cond until it holds or the timeout expires
+func waitFor(timeout time.Duration, cond func() bool) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return cond()
+}
+
+// startNodeWithoutHandoff starts a Node that never hands off keys to new contacts
+func startNodeWithoutHandoff(t *testing.T, id NodeID) *Node {
+	t.Helper()
+
+	var node *Node
+	startTestNetwork(t, id, func(self Contact, network *Network) MessageHandler {
+		node = NewNode(self, nil)
+		node.Network = network
+		node.RoutingTable.OnNewContact = nil
+		return node
+	})
+	return node
+}
+
+func hasKey(n *Node, key NodeID, value []byte) bool {
+	n.StorageMux.RLock()
+	defer n.StorageMux.RUnlock()
+	return bytes.Equal(n.Storage[key], value)
+}
+
+// TestHandoffToCloserPeer tests that a new peer among the k closest receives the key
+func TestHandoffToCloserPeer(t *testing.T) {
+	key := NodeID{}
+	value := []byte("handed off")
+
+	holder := startHonestNode(t, idWithPrefix(0x40, 1))
+	holder.StorageMux.Lock()
+	holder.Storage[key] = value
+	holder.StorageMux.Unlock()
+
+	newcomer := startHonestNode(t, idWithPrefix(0x01, 1))
+	holder.RoutingTable.Update(newcomer.Self)
+
+	if !waitFor(2*time.Second, func() bool { return hasKey(newcomer, key, value) }) {
+		t.Fatal("New closer peer did not receive the key")
+	}
+
+	// We are still among the k closest, so our copy stays
+	if !hasKey(holder, key, value) {
+		t.Fatal("Holder dropped a key it is still responsible for")
+	}
+}
+
+// TestHandoffDropsWhenReplicated tests that a node pushed out of the k closest drops its copy
+func TestHandoffDropsWhenReplicated(t *testing.T) {
+	key := NodeID{}
+	value := []byte("handed off")
+
+	holder := startHonestNode(t, idWithPrefix(0x40, 1))
+	r1 := startHonestNode(t, idWithPrefix(0x01, 1))
+	r2 := startHonestNode(t, idWithPrefix(0x02, 1))
+	for _, n := range []*Node{holder, r1, r2} {
+		n.StorageMux.Lock()
+		n.Storage[key] = value
+		n.StorageMux.Unlock()
+	}
+	holder.RoutingTable.Update(r1.Self)
+	holder.RoutingTable.Update(r2.Self)
+
+	newcomer := startHonestNode(t, idWithPrefix(0x03, 1))
+	holder.RoutingTable.Update(newcomer.Self)
+
+	if !waitFor(2*time.Second, func() bool { return hasKey(newcomer, key, value) }) {
+		t.Fatal("New closer peer did not receive the key")
+	}
+	if !waitFor(2*time.Second, func() bool { return !hasKey(holder, key, value) }) {
+		t.Fatal("Holder kept a key after k closer replicas were confirmed")
+	}
+
+	// Confirming the replicas is not demand for the key
+	for _, n := range []*Node{r1, r2, newcomer} {
+		if hits := n.HotKeys.Snapshot(); len(hits) != 0 {
+			t.Errorf("Confirmation counted as key hits: %+v", hits)
+		}
+	}
+}
+
+// TestHandoffSkipsFartherPeer tests that peers outside the k closest get nothing
+func TestHandoffSkipsFartherPeer(t *testing.T) {
+	key := NodeID{}
+	value := []byte("stays put")
+
+	holder := startHonestNode(t, idWithPrefix(0x01, 1))
+	holder.StorageMux.Lock()
+	holder.Storage[key] = value
+	holder.StorageMux.Unlock()
+
+	for i := byte(2); i <= 3; i++ {
+		holder.RoutingTable.Update(Contact{ID: idWithPrefix(i, 1), IP: "127.0.0.1", Port: 1})
+	}
+
+	far := startHonestNode(t, idWithPrefix(0x80, 1))
+	holder.RoutingTable.Update(far.Self)
+
+	time.Sleep(100 * time.Millisecond)
+	if hasKey(far, key, value) {
+		t.Fatal("Peer outside the k closest received the key")
+	}
+}
@@ -0,0 +1,155 @@
+package dht
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// HotKeyInfo represents the demand on a single key for JSON output
+type HotKeyInfo struct {
+	Key           string `json:"key"`
+	Hits          int    `json:"hits"`           // FIND_VALUE hits in the current window
+	ReplicaTarget int    `json:"replica_target"` // Number of replicas the key should have
+}
+
+// hitCounter counts hits for one key in a ring of time buckets
+type hitCounter struct {
+	buckets  []int
+	slot     int64     // Time slot of the most recently written bucket
+	lastPush time.Time // When extra replicas were last pushed for this key
+}
+
+// HotKeyTracker counts FIND_VALUE hits per key over a sliding window
+type HotKeyTracker struct {
+	k            int // Replicas of a key that is not hot
+	threshold    int // Hits per window for a key to count as hot
+	maxReplicas  int
+	buckets      int   // Buckets the window is split into
+	bucketWidth  int64 // Seconds covered by one bucket
+	pushInterval time.Duration
+	counters     map[NodeID]*hitCounter
+	mutex        sync.Mutex
+}
+
+func NewHotKeyTracker(config Config) *HotKeyTracker {
+	return &HotKeyTracker{
+		k:            config.K,
+		threshold:    config.HotKeyThreshold,
+		maxReplicas:  config.HotKeyMaxReplicas,
+		buckets:      config.HotKeyBuckets,
+		bucketWidth:  int64(config.HotKeyWindow/time.Second) / int64(config.HotKeyBuckets),
+		pushInterval: config.HotKeyPushInterval,
+		counters:     make(map[NodeID]*hitCounter),
+	}
+}
+
+// timeSlot returns the index of the window bucket a point in time falls into
+func (t *HotKeyTracker) timeSlot(now time.Time) int64 {
+	return now.Unix() / t.bucketWidth
+}
+
+// advance clears the buckets that slid out of the window since the last write
+func (c *hitCounter) advance(slot int64) {
+	buckets := int64(len(c.buckets))
+	for s := c.slot + 1; s <= slot && s <= c.slot+buckets; s++ {
+		c.buckets[s%buckets] = 0
+	}
+	if slot > c.slot {
+		c.slot = slot
+	}
+}
+
+func (c *hitCounter) total() int {
+	sum := 0
+	for _, b := range c.buckets {
+		sum += b
+	}
+	return sum
+}
+
+// replicaTarget maps the hits in the window to the number of replicas a key should have
+func (t *HotKeyTracker) replicaTarget(hits int) int {
+	if hits < t.threshold {
+		return t.k
+	}
+	target := t.k * (1 + hits/t.threshold)
+	if target > t.maxReplicas {
+		return t.maxReplicas
+	}
+	return target
+}
+
+// Hit records a FIND_VALUE hit for a key. It returns the key's replica target
+// and whether extra replicas should be pushed now.
+func (t *HotKeyTracker) Hit(key NodeID) (int, bool) {
+	return t.hitAt(key, time.Now())
+}
+
+func (t *HotKeyTracker) hitAt(key NodeID, now time.Time) (int, bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	c, exists := t.counters[key]
+	if !exists {
+		c = &hitCounter{buckets: make([]int, t.buckets), slot: t.timeSlot(now)}
+		t.counters[key] = c
+	}
+	c.advance(t.timeSlot(now))
+	c.buckets[c.slot%int64(t.buckets)]++
+
+	target := t.replicaTarget(c.total())
+	if target <= t.k {
+		return target, false
+	}
+
+	if now.Sub(c.lastPush) < t.pushInterval {
+		return target, false
+	}
+	c.lastPush = now
+	return target, true
+}
+
+// Target returns the current replica target for a key
+func (t *HotKeyTracker) Target(key NodeID) int {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	c, exists := t.counters[key]
+	if !exists {
+		return t.k
+	}
+	c.advance(t.timeSlot(time.Now()))
+	return t.replicaTarget(c.total())
+}
+
+// Snapshot returns the keys with hits in the current window, busiest first.
+// Keys without hits are forgotten.
+func (t *HotKeyTracker) Snapshot() []HotKeyInfo {
+	return t.snapshotAt(time.Now())
+}
+
+func (t *HotKeyTracker) snapshotAt(now time.Time) []HotKeyInfo {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	info := make([]HotKeyInfo, 0, len(t.counters))
+	for key, c := range t.counters {
+		c.advance(t.timeSlot(now))
+		hits := c.total()
+		if hits == 0 {
+			delete(t.counters, key)
+			continue
+		}
+		info = append(info, HotKeyInfo{
+			Key:           key.String(),
+			Hits:          hits,
+			ReplicaTarget: t.replicaTarget(hits),
+		})
+	}
+
+	sort.Slice(info, func(i, j int) bool {
+		return info[i].Hits > info[j].Hits
+	})
+	return info
+}
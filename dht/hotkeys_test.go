@@ -0,0 +1,56 @@
+package dht
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kutluhann/decentralized-file-sharing-system/constants"
+)
+
+// TestHotKeyTarget tests that the replica target grows with demand and is capped
+func TestHotKeyTarget(t *testing.T) {
+	tracker := NewHotKeyTracker(DefaultConfig())
+	if got := tracker.replicaTarget(constants.HotKeyThreshold - 1); got != constants.K {
+		t.Errorf("Cold key: expected %d replicas, got %d", constants.K, got)
+	}
+	if got := tracker.replicaTarget(constants.HotKeyThreshold); got != 2*constants.K {
+		t.Errorf("Hot key: expected %d replicas, got %d", 2*constants.K, got)
+	}
+	if got := tracker.replicaTarget(100 * constants.HotKeyThreshold); got != constants.HotKeyMaxReplicas {
+		t.Errorf("Very hot key: expected cap of %d replicas, got %d", constants.HotKeyMaxReplicas, got)
+	}
+}
+
+// TestHotKeySpreadAndDecay tests that a hot key triggers one push per interval and cools down
+func TestHotKeySpreadAndDecay(t *testing.T) {
+	tracker := NewHotKeyTracker(DefaultConfig())
+	key := NodeID{1}
+	now := time.Unix(1_000_000, 0)
+
+	pushes := 0
+	for i := 0; i < 2*constants.HotKeyThreshold; i++ {
+		if _, spread := tracker.hitAt(key, now); spread {
+			pushes++
+		}
+	}
+	if pushes != 1 {
+		t.Fatalf("Expected exactly one push within the push interval, got %d", pushes)
+	}
+
+	info := tracker.snapshotAt(now)
+	if len(info) != 1 || info[0].Hits != 2*constants.HotKeyThreshold || info[0].ReplicaTarget <= constants.K {
+		t.Fatalf("Unexpected snapshot for hot key: %+v", info)
+	}
+
+	// After the push interval the key is pushed again while still hot
+	later := now.Add(constants.HotKeyPushInterval * time.Second)
+	if _, spread := tracker.hitAt(key, later); !spread {
+		t.Error("Expected a refresh push after the push interval")
+	}
+
+	// Once the window has slid past all hits the key is forgotten
+	cold := later.Add(2 * constants.HotKeyWindow * time.Second)
+	if info := tracker.snapshotAt(cold); len(info) != 0 {
+		t.Errorf("Expected key to cool down, got %+v", info)
+	}
+}
@@ -0,0 +1,118 @@
+package dht
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+
+	"github.com/kutluhann/decentralized-file-sharing-system/id_tools"
+)
+
+// ---------------------------------------------------------
+// IDENTITY RECORDS
+// A PeerID is the hash of a public key, so it cannot be used to
+// encrypt for a peer. Every node publishes its public key at a
+// key derived from its PeerID; the record certifies itself since
+// replicas and readers recompute the PeerID from the key.
+// ---------------------------------------------------------
+
+const identityFormat = "dfss-identity-v1"
+
+// IdentityRecord is stored as JSON at the identity key of its PeerID
+type IdentityRecord struct {
+	Format    string `json:"format"`     // Always identityFormat, must stay the first field
+	PublicKey []byte `json:"public_key"` // PKIX public key
+}
+
+// IdentityKey returns the DHT key of the identity record of peerID
+func IdentityKey(peerID NodeID) NodeID {
+	return NodeID(sha256.Sum256(append([]byte("identity:"), peerID[:]...)))
+}
+
+// ParseIdentity returns the identity record if value is one
+func ParseIdentity(value []byte) (*IdentityRecord, bool) {
+	if !bytes.HasPrefix(value, []byte(`{"format":"`+identityFormat+`"`)) {
+		return nil, false
+	}
+
+	var record IdentityRecord
+	if err := json.Unmarshal(value, &record); err != nil {
+		return nil, false
+	}
+	return &record, true
+}
+
+// parseOwnerKey parses a PKIX encoded ECDSA public key
+func parseOwnerKey(owner []byte) (*ecdsa.PublicKey, error) {
+	pubKey, err := x509.ParsePKIXPublicKey(owner)
+	if err != nil {
+		return nil, fmt.Errorf("invalid owner key")
+	}
+	ecdsaPubKey, ok := pubKey.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("owner key is not ECDSA")
+	}
+	return ecdsaPubKey, nil
+}
+
+// peerIDOf returns the PeerID of a PKIX encoded public key
+func peerIDOf(owner []byte) (NodeID, error) {
+	pubKey, err := parseOwnerKey(owner)
+	if err != nil {
+		return NodeID{}, err
+	}
+	return NodeID(id_tools.GeneratePeerIDFromPublicKey(pubKey)), nil
+}
+
+// verifyIdentity checks that an identity record is stored at the identity key of its public key
+func verifyIdentity(key NodeID, r *IdentityRecord) error {
+	peerID, err := peerIDOf(r.PublicKey)
+	if err != nil {
+		return err
+	}
+	if IdentityKey(peerID) != key {
+		return fmt.Errorf("identity does not belong to this key")
+	}
+	return nil
+}
+
+// PeerID returns the PeerID derived from our identity key
+func (n *Node) PeerID() (NodeID, error) {
+	if n.PrivKey == nil {
+		return NodeID{}, fmt.Errorf("node has no identity key")
+	}
+	return NodeID(id_tools.GeneratePeerIDFromPublicKey(&n.PrivKey.PublicKey)), nil
+}
+
+// PublishIdentity stores our public key in the DHT so that peers can encrypt for us
+func (n *Node) PublishIdentity() error {
+	peerID, err := n.PeerID()
+	if err != nil {
+		return err
+	}
+
+	value, _ := json.Marshal(IdentityRecord{Format: identityFormat, PublicKey: n.ownerKey()})
+	fmt.Printf("[IDENTITY] Publishing public key of %s\n", peerID.String()[:16])
+	return n.Store(IdentityKey(peerID), value)
+}
+
+// LookupPublicKey finds the public key of peerID in the DHT
+func (n *Node) LookupPublicKey(peerID NodeID) (*ecdsa.PublicKey, error) {
+	key := IdentityKey(peerID)
+	value, _, err := n.FindValue(key)
+	if err != nil {
+		return nil, fmt.Errorf("no identity published for %s: %v", peerID.String()[:16], err)
+	}
+
+	record, ok := ParseIdentity(value)
+	if !ok {
+		return nil, fmt.Errorf("value at identity key is not an identity")
+	}
+	if err := verifyIdentity(key, record); err != nil {
+		return nil, err
+	}
+	return parseOwnerKey(record.PublicKey)
+}
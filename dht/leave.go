@@ -0,0 +1,223 @@
+package dht
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/kutluhann/decentralized-file-sharing-system/constants"
+)
+
+// ---------------------------------------------------------
+// GRACEFUL LEAVE
+// A node that shuts down first refuses new STOREs, then pushes
+// each replica to the closest node that does not hold it yet and
+// each shard to the next closest node, and finally tells its
+// contacts to drop it. Otherwise its keys would be one replica
+// short until the next republish elsewhere, and its shards lost.
+// ---------------------------------------------------------
+
+// errLeaving is returned to requests a leaving node no longer serves
+var errLeaving = errors.New("node is leaving the network")
+
+// Leave hands off the keys we hold and tells our contacts that we are gone.
+// New STOREs and joins are refused from the moment it is called.
+// Returns the number of keys handed off.
+func (n *Node) Leave() int {
+	n.leaving.Store(true)
+	if n.Network == nil {
+		return 0
+	}
+
+	fmt.Printf("[LEAVE] Leaving the network, handing off stored keys\n")
+
+	handedOff := n.handOffReplicas() + n.handOffShards()
+	dropped := n.announceLeave()
+
+	fmt.Printf("[LEAVE] ✓ Handed off %d keys, %d contacts dropped us\n", handedOff, dropped)
+	return handedOff
+}
+
+// HandleLeave drops a leaving contact from the routing table. The address
+// must match the one we know, so a peer can't remove others.
+func (n *Node) HandleLeave(sender Contact) bool {
+	removed := n.RoutingTable.Remove(sender)
+	if removed {
+		fmt.Printf("[LEAVE] Dropped leaving contact %s\n", sender.ID.String()[:16])
+	}
+	return removed
+}
+
+// handOffReplicas pushes every replica to the closest of its remaining k
+// closest nodes that does not hold it yet, batched per destination
+func (n *Node) handOffReplicas() int {
+	n.StorageMux.RLock()
+	replicas := make(map[NodeID]StoreRequest, len(n.Storage))
+	var keys []NodeID
+	for key, value := range n.Storage {
+		if n.isReplica(key) {
+			replicas[key] = StoreRequest{Key: key, Value: value, Owner: n.Owners[key]}
+			keys = append(keys, key)
+		}
+	}
+	n.StorageMux.RUnlock()
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].Less(keys[j])
+	})
+
+	// Group keys whose closest known nodes are the same, one lookup per group
+	groups := make(map[string][]NodeID)
+	var order []string
+	for _, key := range keys {
+		sig := neighbourhood(n.RoutingTable.GetClosestNodes(key, n.Config.K))
+		if _, exists := groups[sig]; !exists {
+			order = append(order, sig)
+		}
+		groups[sig] = append(groups[sig], key)
+	}
+
+	batches := make(map[NodeID][]StoreRequest)
+	destinations := make(map[NodeID]Contact)
+	for _, sig := range order {
+		group := groups[sig]
+		found, _ := n.NodeLookupN(group[0], n.Config.K+1)
+		var candidates []Contact
+		for _, contact := range found {
+			if contact.ID != n.Self.ID {
+				candidates = append(candidates, contact)
+			}
+		}
+
+		for _, key := range group {
+			target, ok := n.firstLacking(key, replicas[key].Value, candidates)
+			if !ok {
+				continue
+			}
+			destinations[target.ID] = target
+			batches[target.ID] = append(batches[target.ID], replicas[key])
+		}
+	}
+
+	handedOff := 0
+	for id, items := range batches {
+		contact := destinations[id]
+		for _, chunk := range chunkStoreItems(items, constants.ReplicationBatchBytes) {
+			if err := n.Network.SendStoreBatch(contact, chunk); err != nil {
+				fmt.Printf("[LEAVE] ✗ Failed to hand off %d keys to %s: %v\n",
+					len(chunk), contact.ID.String()[:16], err)
+				continue
+			}
+			handedOff += len(chunk)
+		}
+	}
+	return handedOff
+}
+
+// firstLacking returns the contact closest to key, among its k closest
+// candidates, that does not hold the value
+func (n *Node) firstLacking(key NodeID, value []byte, candidates []Contact) (Contact, bool) {
+	sorted := append([]Contact(nil), candidates...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].ID.Xor(key).Less(sorted[j].ID.Xor(key))
+	})
+	if len(sorted) > n.Config.K {
+		sorted = sorted[:n.Config.K]
+	}
+
+	hash := sha256.Sum256(value)
+	for _, contact := range sorted {
+		if held, err := n.holdsValue(contact, key, hash); err == nil && !held {
+			return contact, true
+		}
+	}
+	return Contact{}, false
+}
+
+// holdsValue asks a contact whether it holds the value with the given hash at key.
+// Unlike FIND_VALUE this does not count as demand for the key.
+func (n *Node) holdsValue(contact Contact, key NodeID, hash [32]byte) (bool, error) {
+	// The key list of the range holding only this key tells whether the contact has it
+	remote, err := n.Network.SendSyncDigest(contact, SyncDigestRequest{
+		Prefix: key,
+		Bits:   constants.KeySizeBytes * 8,
+		Leaf:   true,
+	})
+	if err != nil {
+		return false, err
+	}
+	for _, d := range remote.Keys {
+		if d.Key == key && d.Hash == hash {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// handOffShards moves every shard to the node closest to its key after us,
+// as shards are held by a single node
+func (n *Node) handOffShards() int {
+	n.StorageMux.RLock()
+	var shards []StoreRequest
+	for key := range n.ShardKeys {
+		if value, exists := n.Storage[key]; exists {
+			shards = append(shards, StoreRequest{Key: key, Value: value, Owner: n.Owners[key]})
+		}
+	}
+	n.StorageMux.RUnlock()
+
+	handedOff := 0
+	for _, shard := range shards {
+		found, _ := n.NodeLookup(shard.Key)
+		var target *Contact
+		for i, contact := range found {
+			if contact.ID != n.Self.ID && (target == nil || contact.ID.Xor(shard.Key).Less(target.ID.Xor(shard.Key))) {
+				target = &found[i]
+			}
+		}
+		if target == nil {
+			fmt.Printf("[LEAVE] ✗ No node left to take shard %s\n", shard.Key.String()[:16])
+			continue
+		}
+		if err := n.Network.SendShardStore(*target, shard.Key, shard.Value, shard.Owner); err != nil {
+			fmt.Printf("[LEAVE] ✗ Failed to hand off shard %s to %s: %v\n",
+				shard.Key.String()[:16], target.ID.String()[:16], err)
+			continue
+		}
+		handedOff++
+	}
+	return handedOff
+}
+
+// announceLeave sends LEAVE to every contact in the routing table at once.
+// Returns the number of contacts that dropped us.
+func (n *Node) announceLeave() int {
+	var contacts []Contact
+	for _, bucket := range n.GetRoutingTableInfo() {
+		contacts = append(contacts, bucket.Contacts...)
+	}
+
+	var wg sync.WaitGroup
+	removed := make([]bool, len(contacts))
+	for i, contact := range contacts {
+		wg.Add(1)
+		go func(i int, c Contact) {
+			defer wg.Done()
+			var err error
+			if removed[i], err = n.Network.SendLeave(c); err != nil {
+				fmt.Printf("[LEAVE] ✗ Failed to notify %s: %v\n", c.ID.String()[:16], err)
+			}
+		}(i, contact)
+	}
+	wg.Wait()
+
+	dropped := 0
+	for _, r := range removed {
+		if r {
+			dropped++
+		}
+	}
+	return dropped
+}
@@ -0,0 +1,76 @@
+package dht
+
+import (
+	"testing"
+)
+
+// TestLeaveHandsOffKeys tests that a leaving node pushes its replica to the node
+// taking its place, moves its shard, and is dropped from routing tables
+func TestLeaveHandsOffKeys(t *testing.T) {
+	key := NodeID{}
+	value := []byte("survives the leave")
+	shardKey := idWithPrefix(0x40, 2)
+	shard := []byte{0, 1, 2, 3}
+
+	leaver := startNodeWithoutHandoff(t, idWithPrefix(0x01, 1))
+	r1 := startNodeWithoutHandoff(t, idWithPrefix(0x02, 1))
+	r2 := startNodeWithoutHandoff(t, idWithPrefix(0x03, 1))
+	next := startNodeWithoutHandoff(t, idWithPrefix(0x40, 1))
+	nodes := []*Node{leaver, r1, r2, next}
+	for _, a := range nodes {
+		for _, b := range nodes {
+			if a != b {
+				a.RoutingTable.Update(b.Self)
+			}
+		}
+	}
+
+	// The value lives on the k closest nodes, the shard only on the leaver
+	for _, n := range []*Node{leaver, r1, r2} {
+		n.StorageMux.Lock()
+		n.Storage[key] = value
+		n.StorageMux.Unlock()
+	}
+	leaver.StorageMux.Lock()
+	leaver.Storage[shardKey] = shard
+	leaver.ShardKeys[shardKey] = true
+	leaver.StorageMux.Unlock()
+
+	if handedOff := leaver.Leave(); handedOff != 2 {
+		t.Errorf("Expected the replica and the shard to be handed off, got %d keys", handedOff)
+	}
+	if !hasKey(next, key, value) {
+		t.Errorf("Next closest node did not receive the replica")
+	}
+	if !hasKey(next, shardKey, shard) {
+		t.Errorf("Next closest node did not receive the shard")
+	}
+
+	for _, n := range []*Node{r1, r2, next} {
+		for _, c := range n.RoutingTable.GetClosestNodes(leaver.Self.ID, len(nodes)) {
+			if c.ID == leaver.Self.ID {
+				t.Errorf("%s still knows the leaving node", n.Self.ID.String()[:4])
+			}
+		}
+	}
+
+	if err := r1.Network.SendStore(leaver.Self, idWithPrefix(0x01, 9), value, nil); err == nil {
+		t.Errorf("Leaving node accepted a STORE")
+	}
+}
+
+// TestLeaveIgnoresSpoofedAddress tests that a LEAVE only removes a contact from its own address
+func TestLeaveIgnoresSpoofedAddress(t *testing.T) {
+	victim := startNodeWithoutHandoff(t, idWithPrefix(0x02, 1))
+	receiver := startNodeWithoutHandoff(t, idWithPrefix(0x03, 1))
+	receiver.RoutingTable.Update(victim.Self)
+
+	spoofed := victim.Self
+	spoofed.Port++
+	if receiver.HandleLeave(spoofed) {
+		t.Errorf("Contact was removed by a LEAVE from another address")
+	}
+	if !receiver.HandleLeave(victim.Self) {
+		t.Errorf("Contact was not removed by its own LEAVE")
+	}
+}
@@ -22,6 +22,30 @@ const (
 	// Proof of Space for Sybil Resistance
 	POS_CHALLENGE // Genesis -> NewNode (Prove you have allocated space)
 	POS_PROOF     // NewNode -> Genesis (Here is my PoS proof)
+
+	// Batched replication
+	STORE_BATCH
+	STORE_BATCH_RES
+
+	// Anti-entropy between neighbouring replicas
+	SYNC_DIGEST     // Ask for Merkle digests of a keyspace range
+	SYNC_DIGEST_RES // Child range digests, or the key list of a small range
+	SYNC_PULL       // Ask for the values of keys we are missing
+	SYNC_PULL_RES   // The requested key-value pairs
+
+	// Owner-signed deletion
+	DELETE     // Tombstone for a key, replaces the value on every replica
+	DELETE_RES // Whether the tombstone was accepted
+
+	// Provider records
+	ADD_PROVIDER      // Announce that the sender holds the content of a key
+	ADD_PROVIDER_RES  // Acknowledgement
+	GET_PROVIDERS     // Ask for the providers of a key
+	GET_PROVIDERS_RES // Known providers and the closest nodes to the key
+
+	// Graceful leave
+	LEAVE     // The sender is shutting down and should be dropped from routing tables
+	LEAVE_RES // Whether the sender was dropped
 )
 
 type Message struct {
@@ -42,10 +66,23 @@ type PingResponse struct {
 type StoreRequest struct {
 	Key   NodeID `json:"key"`
 	Value []byte `json:"value"`
+	TTL   int64  `json:"ttl,omitempty"`   // Seconds until a cached copy expires, 0 for a replica
+	Shard bool   `json:"shard,omitempty"` // Erasure-coded shard: held by one node, repaired instead of replicated
+	Owner []byte `json:"owner,omitempty"` // PKIX public key of the node that stored the value, may delete it
 }
 
 type StoreResponse struct {
-	Success bool `json:"success"`
+	Success bool   `json:"success"`
+	Message string `json:"message,omitempty"` // Why the value was refused
+}
+
+type StoreBatchRequest struct {
+	Items []StoreRequest `json:"items"`
+}
+
+type StoreBatchResponse struct {
+	Stored  int    `json:"stored"`
+	Message string `json:"message,omitempty"` // Why values were refused, if any
 }
 
 type FindNodeRequest struct {
@@ -94,3 +131,78 @@ type PosProofPayload struct {
 	Index    uint64   `json:"index"`     // The index value
 	Hash     [32]byte `json:"hash"`      // SHA256(RawValue) for verification
 }
+
+// RangeDigest summarizes the keys stored in one keyspace range
+type RangeDigest struct {
+	Hash  [32]byte `json:"hash"`  // Merkle hash over the range's key digests
+	Count int      `json:"count"` // Number of keys in the range
+}
+
+// KeyDigest identifies a stored value without sending it
+type KeyDigest struct {
+	Key  NodeID   `json:"key"`
+	Hash [32]byte `json:"hash"` // SHA256 of the value
+}
+
+type SyncDigestRequest struct {
+	Prefix NodeID `json:"prefix"`
+	Bits   int    `json:"bits"` // Number of leading bits of Prefix that define the range
+	Leaf   bool   `json:"leaf"` // Ask for the key list instead of child digests
+}
+
+type SyncDigestResponse struct {
+	Children []RangeDigest `json:"children,omitempty"` // Digests of the sub-ranges, in order
+	Keys     []KeyDigest   `json:"keys,omitempty"`     // Key list, for leaf requests
+}
+
+type SyncPullRequest struct {
+	Keys []NodeID `json:"keys"`
+}
+
+type SyncPullResponse struct {
+	Items []StoreRequest `json:"items"`
+}
+
+// Tombstone marks a key as deleted by its owner. It is replicated like a
+// value until it expires, so stale replicas cannot bring the value back.
+type Tombstone struct {
+	Key       NodeID `json:"key"`
+	Owner     []byte `json:"owner"`     // PKIX public key of the value's owner
+	Deleted   int64  `json:"deleted"`   // Unix time of the deletion
+	Expires   int64  `json:"expires"`   // Unix time after which the tombstone is dropped
+	Signature []byte `json:"signature"` // Owner's signature over key, deleted and expires
+}
+
+type DeleteRequest struct {
+	Tombstone Tombstone `json:"tombstone"`
+}
+
+type DeleteResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message,omitempty"`
+}
+
+type AddProviderRequest struct {
+	Key      NodeID `json:"key"`
+	HTTPPort int    `json:"http_port,omitempty"` // Port clients fetch the content from
+}
+
+type AddProviderResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message,omitempty"`
+}
+
+type GetProvidersRequest struct {
+	Key NodeID `json:"key"`
+}
+
+type GetProvidersResponse struct {
+	Providers []ProviderRecord `json:"providers,omitempty"`
+	Nodes     []Contact        `json:"nodes,omitempty"`
+}
+
+type LeaveRequest struct{}
+
+type LeaveResponse struct {
+	Removed bool `json:"removed"`
+}
@@ -0,0 +1,228 @@
+package dht
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/kutluhann/decentralized-file-sharing-system/id_tools"
+)
+
+// ---------------------------------------------------------
+// NAMED RECORDS
+// A mutable pointer, similar to IPNS. The key is derived from
+// the owner's PeerID and a label, so only the owner can publish
+// under it. The record points to immutable content and carries
+// a version; replicas keep the highest validly signed version and
+// lookups ask several replicas and return the newest one.
+// ---------------------------------------------------------
+
+const recordFormat = "dfss-record-v1"
+
+// NamedRecord is stored as JSON at the key of its name
+type NamedRecord struct {
+	Format    string `json:"format"` // Always recordFormat, must stay the first field
+	Owner     []byte `json:"owner"`  // PKIX public key of the owner
+	Label     string `json:"label"`
+	Target    NodeID `json:"target"` // Key of the immutable content the name points to
+	Version   uint64 `json:"version"`
+	Signature []byte `json:"signature"` // Owner's signature over label, target and version
+}
+
+// NameKey returns the DHT key of the name label owned by peerID
+func NameKey(peerID NodeID, label string) NodeID {
+	data := append(peerID[:], []byte("/"+label)...)
+	return NodeID(sha256.Sum256(data))
+}
+
+// ParseRecord returns the record if value is one
+func ParseRecord(value []byte) (*NamedRecord, bool) {
+	if !bytes.HasPrefix(value, []byte(`{"format":"`+recordFormat+`"`)) {
+		return nil, false
+	}
+
+	var record NamedRecord
+	if err := json.Unmarshal(value, &record); err != nil {
+		return nil, false
+	}
+	return &record, true
+}
+
+// recordMessage returns the message an owner signs to publish a record
+func recordMessage(r *NamedRecord) string {
+	return fmt.Sprintf("RECORD|%s|%s|%d", r.Label, r.Target.String(), r.Version)
+}
+
+// verifyRecord checks that a record is signed by its owner and stored under the owner's name key
+func verifyRecord(key NodeID, r *NamedRecord) error {
+	ecdsaPubKey, err := parseOwnerKey(r.Owner)
+	if err != nil {
+		return err
+	}
+
+	peerID := id_tools.GeneratePeerIDFromPublicKey(ecdsaPubKey)
+	if NameKey(NodeID(peerID), r.Label) != key {
+		return fmt.Errorf("record does not belong to this name")
+	}
+
+	if !id_tools.VerifySignature(*ecdsaPubKey, recordMessage(r), r.Signature) {
+		return fmt.Errorf("invalid record signature")
+	}
+	return nil
+}
+
+// checkRecordUpdate decides whether value may replace current. A named record or
+// an encrypted manifest may only be replaced by a higher version, an inbox only by
+// another inbox, and a rotation not at all. The caller verifies the new value first.
+func checkRecordUpdate(current []byte, value []byte) error {
+	if err := checkManifestUpdate(current, value); err != nil {
+		return err
+	}
+	if err := checkRotationUpdate(current, value); err != nil {
+		return err
+	}
+	if _, hadInbox := ParseInbox(current); hadInbox {
+		if _, isInbox := ParseInbox(value); !isInbox {
+			return fmt.Errorf("an inbox cannot be overwritten by a plain value")
+		}
+	}
+
+	existing, hadRecord := ParseRecord(current)
+	if !hadRecord || bytes.Equal(current, value) {
+		return nil
+	}
+
+	record, isRecord := ParseRecord(value)
+	if !isRecord {
+		return fmt.Errorf("a named record cannot be overwritten by a plain value")
+	}
+	if record.Version <= existing.Version {
+		return fmt.Errorf("record version %d is not newer than %d", record.Version, existing.Version)
+	}
+	return nil
+}
+
+// PublishName points the name label of this node to target.
+// The version is one higher than the newest version found in the DHT.
+// Returns the name key.
+func (n *Node) PublishName(label string, target NodeID) (NodeID, error) {
+	if n.PrivKey == nil {
+		return NodeID{}, fmt.Errorf("node has no private key to sign the record")
+	}
+
+	peerID := id_tools.GeneratePeerIDFromPublicKey(&n.PrivKey.PublicKey)
+	key := NameKey(NodeID(peerID), label)
+
+	version := uint64(1)
+	if value, _, err := n.FindValue(key); err == nil {
+		if current, ok := ParseRecord(value); ok {
+			version = current.Version + 1
+		}
+	}
+
+	record := &NamedRecord{
+		Format:  recordFormat,
+		Owner:   n.ownerKey(),
+		Label:   label,
+		Target:  target,
+		Version: version,
+	}
+	record.Signature = id_tools.SignMessage(*n.PrivKey, recordMessage(record))
+
+	fmt.Printf("[NAME] Publishing %s/%s -> %s (version %d)\n",
+		NodeID(peerID).String()[:16], label, target.String()[:16], version)
+
+	value, _ := json.Marshal(record)
+	return key, n.Store(key, value)
+}
+
+// ResolveName finds the newest record of the name label owned by peerID. A
+// name the owner has not published since rotating its key is looked up under
+// the old PeerID.
+// Returns: record, hopCount, error
+func (n *Node) ResolveName(peerID NodeID, label string) (*NamedRecord, int, error) {
+	current, err := n.FollowRotations(peerID)
+	if err != nil {
+		current = peerID
+	}
+	key := NameKey(current, label)
+
+	value, hopCount, err := n.FindValue(key)
+	if errors.Is(err, ErrNotFound) && current != peerID {
+		key = NameKey(peerID, label)
+		value, hopCount, err = n.FindValue(key)
+	}
+	if err != nil {
+		return nil, hopCount, err
+	}
+
+	record, ok := ParseRecord(value)
+	if !ok {
+		return nil, hopCount, fmt.Errorf("value at name is not a record")
+	}
+	if err := verifyRecord(key, record); err != nil {
+		return nil, hopCount, err
+	}
+	return record, hopCount, nil
+}
+
+// versionOf returns the version of a validly signed named record or encrypted manifest stored at key
+func versionOf(key NodeID, value []byte) (uint64, bool) {
+	if record, ok := ParseRecord(value); ok && verifyRecord(key, record) == nil {
+		return record.Version, true
+	}
+	if manifest, ok := ParseEncryptedManifest(value); ok && verifyManifest(key, manifest) == nil {
+		return manifest.Version, true
+	}
+	return 0, false
+}
+
+// newestRecord returns the highest valid version of a named record or encrypted manifest
+// among the values found by the lookup paths and those held by the k closest nodes to the key.
+func (n *Node) newestRecord(key NodeID, local []byte, results []PathResult) ([]byte, bool) {
+	var best []byte
+	var bestVersion uint64
+	consider := func(value []byte) {
+		version, ok := versionOf(key, value)
+		if !ok {
+			return
+		}
+		if best == nil || version > bestVersion {
+			best, bestVersion = value, version
+		}
+	}
+
+	consider(local)
+	for _, r := range results {
+		consider(r.Value)
+	}
+	if best == nil {
+		return nil, false
+	}
+
+	// A FIND_VALUE path stops at the first copy it finds, which may be stale.
+	// Ask every one of the k closest nodes as well.
+	closest, _ := n.NodeLookup(key)
+
+	values := make([][]byte, len(closest))
+	var wg sync.WaitGroup
+	for i, c := range closest {
+		if c.ID == n.Self.ID {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, c Contact) {
+			defer wg.Done()
+			values[i], _, _ = n.Network.SendFindValue(c, key)
+		}(i, c)
+	}
+	wg.Wait()
+
+	for _, value := range values {
+		consider(value)
+	}
+	return best, true
+}
@@ -0,0 +1,117 @@
+package dht
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/kutluhann/decentralized-file-sharing-system/id_tools"
+)
+
+// signedRecord builds a record for the name label of owner
+func signedRecord(owner *Node, label string, target NodeID, version uint64) []byte {
+	record := &NamedRecord{
+		Format:  recordFormat,
+		Owner:   owner.ownerKey(),
+		Label:   label,
+		Target:  target,
+		Version: version,
+	}
+	record.Signature = id_tools.SignMessage(*owner.PrivKey, recordMessage(record))
+	value, _ := json.Marshal(record)
+	return value
+}
+
+// ownerName returns the name key of label for owner
+func ownerName(owner *Node, label string) NodeID {
+	peerID := id_tools.GeneratePeerIDFromPublicKey(&owner.PrivKey.PublicKey)
+	return NameKey(NodeID(peerID), label)
+}
+
+// TestPublishAndResolveName tests that updates bump the version and resolve to the newest target
+func TestPublishAndResolveName(t *testing.T) {
+	owner := startOwnerNode(t, idWithPrefix(0x80, 1))
+	r1 := startHonestNode(t, idWithPrefix(0x01, 1))
+	r2 := startHonestNode(t, idWithPrefix(0x02, 1))
+	reader := startHonestNode(t, idWithPrefix(0x40, 1))
+	connect(owner, r1, r2, reader)
+
+	v1, v2 := NodeID{1}, NodeID{2}
+	if _, err := owner.PublishName("site", v1); err != nil {
+		t.Fatalf("First publish failed: %v", err)
+	}
+	if _, err := owner.PublishName("site", v2); err != nil {
+		t.Fatalf("Second publish failed: %v", err)
+	}
+
+	peerID := id_tools.GeneratePeerIDFromPublicKey(&owner.PrivKey.PublicKey)
+	record, _, err := reader.ResolveName(NodeID(peerID), "site")
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if record.Version != 2 || record.Target != v2 {
+		t.Fatalf("Expected version 2 -> %s, got version %d -> %s",
+			v2.String()[:4], record.Version, record.Target.String()[:4])
+	}
+}
+
+// TestFindValueReturnsNewestRecord tests that a stale replica cannot hide a newer version
+func TestFindValueReturnsNewestRecord(t *testing.T) {
+	owner := startOwnerNode(t, idWithPrefix(0x80, 1))
+	r1 := startHonestNode(t, idWithPrefix(0x01, 1))
+	r2 := startHonestNode(t, idWithPrefix(0x02, 1))
+	reader := startHonestNode(t, idWithPrefix(0x40, 1))
+
+	key := ownerName(owner, "site")
+	old := signedRecord(owner, "site", NodeID{1}, 1)
+	current := signedRecord(owner, "site", NodeID{2}, 2)
+
+	// The reader only knows the stale replica, which knows the up to date one
+	r1.StorageMux.Lock()
+	r1.Storage[key] = old
+	r1.StorageMux.Unlock()
+	r2.StorageMux.Lock()
+	r2.Storage[key] = current
+	r2.StorageMux.Unlock()
+	reader.RoutingTable.Update(r1.Self)
+	r1.RoutingTable.Update(r2.Self)
+
+	value, _, err := reader.FindValue(key)
+	if err != nil {
+		t.Fatalf("FindValue failed: %v", err)
+	}
+	record, ok := ParseRecord(value)
+	if !ok || record.Version != 2 {
+		t.Fatalf("Expected the version 2 record, got %s", value)
+	}
+}
+
+// TestRecordUpdatesAreChecked tests that forged and outdated records are refused
+func TestRecordUpdatesAreChecked(t *testing.T) {
+	owner := startOwnerNode(t, idWithPrefix(0x80, 1))
+	attacker := startOwnerNode(t, idWithPrefix(0x40, 1))
+	replica := startHonestNode(t, idWithPrefix(0x01, 1))
+
+	key := ownerName(owner, "site")
+	v2 := signedRecord(owner, "site", NodeID{2}, 2)
+	replica.HandleStore(owner.Self, StoreRequest{Key: key, Value: v2})
+
+	// Signed by another key: the name does not belong to the attacker
+	forged := signedRecord(attacker, "site", NodeID{9}, 3)
+	replica.HandleStore(attacker.Self, StoreRequest{Key: key, Value: forged})
+
+	// Validly signed but older
+	replica.HandleStore(owner.Self, StoreRequest{Key: key, Value: signedRecord(owner, "site", NodeID{1}, 1)})
+
+	// A plain value cannot replace a record
+	replica.HandleStore(attacker.Self, StoreRequest{Key: key, Value: []byte("plain")})
+
+	if !hasKey(replica, key, v2) {
+		t.Fatalf("Version 2 record was replaced")
+	}
+
+	v3 := signedRecord(owner, "site", NodeID{3}, 3)
+	replica.HandleStore(owner.Self, StoreRequest{Key: key, Value: v3})
+	if !hasKey(replica, key, v3) {
+		t.Fatalf("Newer record was refused")
+	}
+}
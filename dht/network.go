@@ -2,17 +2,34 @@ package dht
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/kutluhann/decentralized-file-sharing-system/constants"
 )
 
 type MessageHandler interface {
 	HandlePing(sender Contact)
 	HandleFindNode(sender Contact, targetID NodeID) []Contact
-	HandleStore(sender Contact, key NodeID, value []byte)
+	HandleStore(sender Contact, req StoreRequest) error
+	HandleStoreBatch(sender Contact, items []StoreRequest) (int, error)
 	HandleFindValue(sender Contact, key NodeID) ([]byte, []Contact)
+	HandleDelete(sender Contact, tombstone Tombstone) error
+
+	// Provider records
+	HandleAddProvider(sender Contact, req AddProviderRequest) error
+	HandleGetProviders(sender Contact, key NodeID) ([]ProviderRecord, []Contact)
+
+	// Anti-entropy
+	HandleSyncDigest(sender Contact, req SyncDigestRequest) SyncDigestResponse
+	HandleSyncPull(sender Contact, req SyncPullRequest) SyncPullResponse
+
+	// Graceful leave
+	HandleLeave(sender Contact) bool
 
 	// Handshake
 	HandleJoinRequest(sender Contact, payload JoinRequestPayload) (JoinChallengePayload, error)
@@ -25,6 +42,7 @@ type Network struct {
 	SelfID           NodeID
 	ResponseChannels map[string]chan Message // RPCID -> Response Channel
 	ResponseMutex    sync.RWMutex
+	Timeout          time.Duration // Wait for the response to a request
 }
 
 func NewNetwork(address string, selfID NodeID) (*Network, error) {
@@ -42,6 +60,7 @@ func NewNetwork(address string, selfID NodeID) (*Network, error) {
 		Conn:             conn,
 		SelfID:           selfID,
 		ResponseChannels: make(map[string]chan Message),
+		Timeout:          constants.RPCTimeout * time.Second,
 	}, nil
 }
 
@@ -59,16 +78,25 @@ func (s *Network) UnregisterResponseChannel(rpcID string) {
 	delete(s.ResponseChannels, rpcID)
 }
 
+// Close closes the socket, which ends Listen and fails every later send
+func (s *Network) Close() error {
+	return s.Conn.Close()
+}
+
 func (s *Network) SetHandler(h MessageHandler) {
 	s.Handler = h
 }
 
+// Listen handles incoming packets until the network is closed
 func (s *Network) Listen() {
 	fmt.Println("Listening for UDP packets on", s.Conn.LocalAddr().String())
 	buf := make([]byte, 65535) // buffer size is increased to maximum to avoid network failures
 
 	for {
 		n, remoteAddr, err := s.Conn.ReadFromUDP(buf)
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
 		if err != nil {
 			fmt.Println("Error reading from UDP:", err)
 			continue
@@ -97,7 +125,9 @@ func (s *Network) handlePacket(data []byte, addr *net.UDPAddr) {
 
 	// Check if this is a response to a pending RPC call (client-side handling)
 	isResponse := msg.Type == PING_RES || msg.Type == FIND_NODE_RES ||
-		msg.Type == FIND_VALUE_RES || msg.Type == STORE_RES ||
+		msg.Type == FIND_VALUE_RES || msg.Type == STORE_RES || msg.Type == STORE_BATCH_RES ||
+		msg.Type == SYNC_DIGEST_RES || msg.Type == SYNC_PULL_RES || msg.Type == DELETE_RES ||
+		msg.Type == ADD_PROVIDER_RES || msg.Type == GET_PROVIDERS_RES || msg.Type == LEAVE_RES ||
 		msg.Type == JOIN_CHALLENGE || msg.Type == JOIN_ACK ||
 		msg.Type == POS_CHALLENGE
 
@@ -144,8 +174,23 @@ func (s *Network) handlePacket(data []byte, addr *net.UDPAddr) {
 		var req StoreRequest
 		json.Unmarshal(payloadBytes, &req)
 
-		s.Handler.HandleStore(sender, req.Key, req.Value)
-		s.sendResponse(msg.RPCID, STORE_RES, StoreResponse{Success: true}, addr)
+		res := StoreResponse{Success: true}
+		if err := s.Handler.HandleStore(sender, req); err != nil {
+			res = StoreResponse{Success: false, Message: err.Error()}
+		}
+		s.sendResponse(msg.RPCID, STORE_RES, res, addr)
+
+	case STORE_BATCH:
+		payloadBytes, _ := json.Marshal(msg.Payload)
+		var req StoreBatchRequest
+		json.Unmarshal(payloadBytes, &req)
+
+		stored, err := s.Handler.HandleStoreBatch(sender, req.Items)
+		res := StoreBatchResponse{Stored: stored}
+		if err != nil {
+			res.Message = err.Error()
+		}
+		s.sendResponse(msg.RPCID, STORE_BATCH_RES, res, addr)
 
 	case FIND_VALUE:
 		payloadBytes, _ := json.Marshal(msg.Payload)
@@ -160,6 +205,53 @@ func (s *Network) handlePacket(data []byte, addr *net.UDPAddr) {
 		}
 		s.sendResponse(msg.RPCID, FIND_VALUE_RES, res, addr)
 
+	case SYNC_DIGEST:
+		payloadBytes, _ := json.Marshal(msg.Payload)
+		var req SyncDigestRequest
+		json.Unmarshal(payloadBytes, &req)
+
+		s.sendResponse(msg.RPCID, SYNC_DIGEST_RES, s.Handler.HandleSyncDigest(sender, req), addr)
+
+	case SYNC_PULL:
+		payloadBytes, _ := json.Marshal(msg.Payload)
+		var req SyncPullRequest
+		json.Unmarshal(payloadBytes, &req)
+
+		s.sendResponse(msg.RPCID, SYNC_PULL_RES, s.Handler.HandleSyncPull(sender, req), addr)
+
+	case DELETE:
+		payloadBytes, _ := json.Marshal(msg.Payload)
+		var req DeleteRequest
+		json.Unmarshal(payloadBytes, &req)
+
+		if err := s.Handler.HandleDelete(sender, req.Tombstone); err != nil {
+			s.sendResponse(msg.RPCID, DELETE_RES, DeleteResponse{Success: false, Message: err.Error()}, addr)
+			return
+		}
+		s.sendResponse(msg.RPCID, DELETE_RES, DeleteResponse{Success: true}, addr)
+
+	case ADD_PROVIDER:
+		payloadBytes, _ := json.Marshal(msg.Payload)
+		var req AddProviderRequest
+		json.Unmarshal(payloadBytes, &req)
+
+		if err := s.Handler.HandleAddProvider(sender, req); err != nil {
+			s.sendResponse(msg.RPCID, ADD_PROVIDER_RES, AddProviderResponse{Success: false, Message: err.Error()}, addr)
+			return
+		}
+		s.sendResponse(msg.RPCID, ADD_PROVIDER_RES, AddProviderResponse{Success: true}, addr)
+
+	case GET_PROVIDERS:
+		payloadBytes, _ := json.Marshal(msg.Payload)
+		var req GetProvidersRequest
+		json.Unmarshal(payloadBytes, &req)
+
+		providers, nodes := s.Handler.HandleGetProviders(sender, req.Key)
+		s.sendResponse(msg.RPCID, GET_PROVIDERS_RES, GetProvidersResponse{Providers: providers, Nodes: nodes}, addr)
+
+	case LEAVE:
+		s.sendResponse(msg.RPCID, LEAVE_RES, LeaveResponse{Removed: s.Handler.HandleLeave(sender)}, addr)
+
 	// --- Secure Join Handshake (Server-Side) ---
 
 	case JOIN_REQ:
@@ -290,23 +382,34 @@ func (s *Network) SendFindNode(target Contact, searchID NodeID) ([]Contact, erro
 
 		return findNodeResp.Nodes, nil
 
-	case <-time.After(5 * time.Second):
+	case <-time.After(s.Timeout):
 		return nil, fmt.Errorf("timeout waiting for FIND_NODE response from %s", addr)
 	}
 }
 
 // SendStore sends a STORE request to store a key-value pair on a remote node
-func (s *Network) SendStore(target Contact, key NodeID, value []byte) error {
+func (s *Network) SendStore(target Contact, key NodeID, value []byte, owner []byte) error {
+	return s.sendStore(target, StoreRequest{Key: key, Value: value, Owner: owner})
+}
+
+// SendCacheStore sends a STORE request for a cached copy that expires after ttl
+func (s *Network) SendCacheStore(target Contact, key NodeID, value []byte, ttl time.Duration) error {
+	return s.sendStore(target, StoreRequest{Key: key, Value: value, TTL: int64(ttl / time.Second)})
+}
+
+// SendShardStore sends a STORE request for an erasure shard, which is kept but not replicated
+func (s *Network) SendShardStore(target Contact, key NodeID, shard []byte, owner []byte) error {
+	return s.sendStore(target, StoreRequest{Key: key, Value: shard, Shard: true, Owner: owner})
+}
+
+func (s *Network) sendStore(target Contact, req StoreRequest) error {
 	rpcID := generateRPCID()
 
 	msg := Message{
 		Type:     STORE,
 		RPCID:    rpcID,
 		SenderID: s.SelfID,
-		Payload: StoreRequest{
-			Key:   key,
-			Value: value,
-		},
+		Payload:  req,
 	}
 
 	// Register response channel
@@ -337,16 +440,73 @@ func (s *Network) SendStore(target Contact, key NodeID, value []byte) error {
 		}
 
 		if !storeResp.Success {
+			if storeResp.Message != "" {
+				return fmt.Errorf("remote node refused: %s", storeResp.Message)
+			}
 			return fmt.Errorf("remote node failed to store value")
 		}
 
 		return nil
 
-	case <-time.After(5 * time.Second):
+	case <-time.After(s.Timeout):
 		return fmt.Errorf("timeout waiting for STORE response from %s", addr)
 	}
 }
 
+// SendStoreBatch sends several key-value pairs to a remote node in a single STORE_BATCH message
+func (s *Network) SendStoreBatch(target Contact, items []StoreRequest) error {
+	rpcID := generateRPCID()
+
+	msg := Message{
+		Type:     STORE_BATCH,
+		RPCID:    rpcID,
+		SenderID: s.SelfID,
+		Payload: StoreBatchRequest{
+			Items: items,
+		},
+	}
+
+	// Register response channel
+	respChan := make(chan Message, 1)
+	s.RegisterResponseChannel(rpcID, respChan)
+	defer s.UnregisterResponseChannel(rpcID)
+
+	// Send request
+	addr := fmt.Sprintf("%s:%d", target.IP, target.Port)
+	err := s.SendMessage(msg, addr)
+	if err != nil {
+		return fmt.Errorf("failed to send STORE_BATCH: %v", err)
+	}
+
+	// Wait for response with timeout
+	select {
+	case resp := <-respChan:
+		if resp.Type != STORE_BATCH_RES {
+			return fmt.Errorf("expected STORE_BATCH_RES, got %v", resp.Type)
+		}
+
+		// Parse response payload
+		payloadBytes, _ := json.Marshal(resp.Payload)
+		var batchResp StoreBatchResponse
+		err := json.Unmarshal(payloadBytes, &batchResp)
+		if err != nil {
+			return fmt.Errorf("failed to parse STORE_BATCH response: %v", err)
+		}
+
+		if batchResp.Stored != len(items) {
+			if batchResp.Message != "" {
+				return fmt.Errorf("remote node stored %d of %d values: %s", batchResp.Stored, len(items), batchResp.Message)
+			}
+			return fmt.Errorf("remote node stored %d of %d values", batchResp.Stored, len(items))
+		}
+
+		return nil
+
+	case <-time.After(s.Timeout):
+		return fmt.Errorf("timeout waiting for STORE_BATCH response from %s", addr)
+	}
+}
+
 // SendFindValue sends a FIND_VALUE request to retrieve a value from a remote node
 // Returns: value (if found), nodes (closest nodes if not found), error
 func (s *Network) SendFindValue(target Contact, key NodeID) ([]byte, []Contact, error) {
@@ -389,19 +549,121 @@ func (s *Network) SendFindValue(target Contact, key NodeID) ([]byte, []Contact,
 		}
 
 		if findValueResp.Found {
-			// Value found! Return it (nodes will be nil)
+			// Value found! Return it (nodes will be nil), an empty value is omitted from the reply
+			if findValueResp.Value == nil {
+				return []byte{}, nil, nil
+			}
 			return findValueResp.Value, nil, nil
 		}
 
 		// Value not found, return closest nodes instead
 		return nil, findValueResp.Nodes, nil
 
-	case <-time.After(5 * time.Second):
+	case <-time.After(s.Timeout):
 		return nil, nil, fmt.Errorf("timeout waiting for FIND_VALUE response from %s", addr)
 	}
 }
 
+// rpcCounter keeps RPC IDs unique when several lookup paths send in the same nanosecond
+var rpcCounter atomic.Uint64
+
+// SendSyncDigest asks a remote node for the digests (or key list) of a keyspace range
+func (s *Network) SendSyncDigest(target Contact, req SyncDigestRequest) (SyncDigestResponse, error) {
+	var res SyncDigestResponse
+	err := s.call(target, SYNC_DIGEST, req, SYNC_DIGEST_RES, &res)
+	return res, err
+}
+
+// SendSyncPull asks a remote node for the values of the given keys
+func (s *Network) SendSyncPull(target Contact, keys []NodeID) ([]StoreRequest, error) {
+	var res SyncPullResponse
+	err := s.call(target, SYNC_PULL, SyncPullRequest{Keys: keys}, SYNC_PULL_RES, &res)
+	return res.Items, err
+}
+
+// SendDelete sends a tombstone to a remote node
+func (s *Network) SendDelete(target Contact, tombstone Tombstone) error {
+	var res DeleteResponse
+	if err := s.call(target, DELETE, DeleteRequest{Tombstone: tombstone}, DELETE_RES, &res); err != nil {
+		return err
+	}
+	if !res.Success {
+		return fmt.Errorf("tombstone rejected: %s", res.Message)
+	}
+	return nil
+}
+
+// SendAddProvider announces to a remote node that we provide a key
+func (s *Network) SendAddProvider(target Contact, key NodeID, httpPort int) error {
+	var res AddProviderResponse
+	if err := s.call(target, ADD_PROVIDER, AddProviderRequest{Key: key, HTTPPort: httpPort}, ADD_PROVIDER_RES, &res); err != nil {
+		return err
+	}
+	if !res.Success {
+		return fmt.Errorf("provider record refused: %s", res.Message)
+	}
+	return nil
+}
+
+// SendGetProviders asks a remote node for the providers of a key
+// Returns: providers, closest nodes to the key, error
+func (s *Network) SendGetProviders(target Contact, key NodeID) ([]ProviderRecord, []Contact, error) {
+	var res GetProvidersResponse
+	err := s.call(target, GET_PROVIDERS, GetProvidersRequest{Key: key}, GET_PROVIDERS_RES, &res)
+	return res.Providers, res.Nodes, err
+}
+
+// SendLeave tells a remote node that we are shutting down
+// Returns: whether the node dropped us from its routing table, error
+func (s *Network) SendLeave(target Contact) (bool, error) {
+	var res LeaveResponse
+	err := s.call(target, LEAVE, LeaveRequest{}, LEAVE_RES, &res)
+	return res.Removed, err
+}
+
+// call sends a request, waits for the response of the expected type and decodes its payload into out
+func (s *Network) call(target Contact, msgType MessageType, payload interface{}, resType MessageType, out interface{}) error {
+	rpcID := generateRPCID()
+
+	msg := Message{
+		Type:     msgType,
+		RPCID:    rpcID,
+		SenderID: s.SelfID,
+		Payload:  payload,
+	}
+
+	// Register response channel
+	respChan := make(chan Message, 1)
+	s.RegisterResponseChannel(rpcID, respChan)
+	defer s.UnregisterResponseChannel(rpcID)
+
+	// Send request
+	addr := fmt.Sprintf("%s:%d", target.IP, target.Port)
+	err := s.SendMessage(msg, addr)
+	if err != nil {
+		return fmt.Errorf("failed to send %v: %v", msgType, err)
+	}
+
+	// Wait for response with timeout
+	select {
+	case resp := <-respChan:
+		if resp.Type != resType {
+			return fmt.Errorf("expected %v, got %v", resType, resp.Type)
+		}
+
+		// Parse response payload
+		payloadBytes, _ := json.Marshal(resp.Payload)
+		if err := json.Unmarshal(payloadBytes, out); err != nil {
+			return fmt.Errorf("failed to parse %v response: %v", resType, err)
+		}
+		return nil
+
+	case <-time.After(s.Timeout):
+		return fmt.Errorf("timeout waiting for %v response from %s", resType, addr)
+	}
+}
+
 // generateRPCID creates a simple RPC ID (we could use the id_tools function, but keeping it simple)
 func generateRPCID() string {
-	return fmt.Sprintf("rpc-%d", time.Now().UnixNano())
+	return fmt.Sprintf("rpc-%d-%d", time.Now().UnixNano(), rpcCounter.Add(1))
 }
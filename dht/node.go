@@ -1,21 +1,27 @@
 package dht
 
 import (
+	"bytes"
 	"crypto/ecdsa"
+	"crypto/sha256"
 	"crypto/x509"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/kutluhann/decentralized-file-sharing-system/constants"
 	"github.com/kutluhann/decentralized-file-sharing-system/id_tools"
 	"github.com/kutluhann/decentralized-file-sharing-system/pos"
 )
 
+// ErrNotFound is returned when no node in the network holds a key
+var ErrNotFound = errors.New("key not found")
+
 type Contact struct {
 	ID       NodeID
 	IP       string
@@ -30,12 +36,6 @@ type PendingChallenge struct {
 	PubKey    []byte
 }
 
-// ReplicationTimer tracks the ticker and cancel channel for a key's replication
-type ReplicationTimer struct {
-	Ticker *time.Ticker
-	Stop   chan bool
-}
-
 type Node struct {
 	Self              Contact
 	RoutingTable      *RoutingTable
@@ -45,21 +45,61 @@ type Node struct {
 	Network           *Network
 	PendingChallenges map[NodeID]PendingChallenge // For server side: track challenges sent to peers
 	ChallengeMutex    sync.RWMutex
-	ReplicationTimers map[NodeID]*ReplicationTimer // Timers for periodic re-replication of stored keys
-	TimerMutex        sync.RWMutex                 // Mutex for thread-safe timer access
-	PosPlot           *pos.Plot                    // Proof of Space plot for Sybil resistance
+	Replication       *ReplicationScheduler // Periodic re-replication of stored keys
+	PosPlot           *pos.Plot             // Proof of Space plot for Sybil resistance
+	Config            Config                // Protocol parameters and timeouts
+	CacheExpiry       map[NodeID]time.Time  // Expiry of values cached along a lookup path (guarded by StorageMux)
+	HotKeys           *HotKeyTracker        // FIND_VALUE demand per key, drives adaptive replication
+	ShardKeys         map[NodeID]bool       // Erasure-coded shards we hold (guarded by StorageMux)
+	Owners            map[NodeID][]byte     // Public key of the owner of each stored value (guarded by StorageMux)
+	Tombstones        map[NodeID]Tombstone  // Deleted keys, until the tombstone expires (guarded by StorageMux)
+	Providers         *ProviderStore        // Provider records we hold for others
+	HTTPPort          int                   // Port of our HTTP API, announced with provider records
+	Limits            StoreLimits           // Storage quotas and STORE rate limits
+	Eviction          EvictionPolicy        // Which keys make room once the quota is reached, nil to refuse instead
+	provided          map[NodeID]bool       // Keys we announce as a provider
+	providedMux       sync.Mutex
+	account           storageAccount // Stored bytes in total and per peer (guarded by StorageMux)
+	storeRate         *rateLimiter   // STORE requests per peer
+	access            *accessTracker // Last access of each stored key, for LRU eviction
+	evictions         EvictionStats  // Eviction metrics (guarded by StorageMux)
+	leaving           atomic.Bool    // Set by Leave, new STOREs and joins are refused
 }
 
-// CreateNode initializes the DHT node using the identity from config.
+// NewNode initializes a DHT node with the default parameters
 func NewNode(contact Contact, privateKey *ecdsa.PrivateKey) *Node {
-	return &Node{
+	return NewNodeWithConfig(contact, privateKey, DefaultConfig())
+}
+
+// NewNodeWithConfig initializes a DHT node with the given parameters
+func NewNodeWithConfig(contact Contact, privateKey *ecdsa.PrivateKey, config Config) *Node {
+	node := &Node{
 		Self:              contact,
-		RoutingTable:      NewRoutingTable(contact),
+		RoutingTable:      NewRoutingTable(contact, config.K),
 		Storage:           make(map[NodeID][]byte), // Initialize storage map
 		PrivKey:           privateKey,
 		PendingChallenges: make(map[NodeID]PendingChallenge),
-		ReplicationTimers: make(map[NodeID]*ReplicationTimer), // Initialize replication timers map
+		Config:            config,
+		CacheExpiry:       make(map[NodeID]time.Time),
+		HotKeys:           NewHotKeyTracker(config),
+		ShardKeys:         make(map[NodeID]bool),
+		Owners:            make(map[NodeID][]byte),
+		Tombstones:        make(map[NodeID]Tombstone),
+		Providers:         NewProviderStore(config.MaxProvidersPerKey),
+		Limits:            DefaultStoreLimits(),
+		Eviction:          DefaultEvictionPolicy(),
+		provided:          make(map[NodeID]bool),
+		account:           newStorageAccount(),
+		storeRate:         newRateLimiter(),
+		access:            newAccessTracker(),
 	}
+
+	node.Replication = NewReplicationScheduler(node)
+
+	// Hand off keys to peers that are closer to them than we are
+	node.RoutingTable.OnNewContact = node.handoffTo
+
+	return node
 }
 
 // JoinNetwork initiates the bootstrap process with full handshake
@@ -92,7 +132,7 @@ func (n *Node) JoinNetwork(bootstrapAddr string) (Contact, error) {
 		return Contact{}, fmt.Errorf("failed to send JOIN_REQ: %v", err)
 	}
 
-	// Step 2: Wait for JOIN_CHALLENGE (10 seconds timeout)
+	// Step 2: Wait for JOIN_CHALLENGE
 	var bootstrapContact Contact
 
 	select {
@@ -214,15 +254,15 @@ func (n *Node) JoinNetwork(bootstrapAddr string) (Contact, error) {
 					return Contact{}, fmt.Errorf("[JOIN] Step 6/6: ✗ Join rejected: %s", ack.Message)
 				}
 
-			case <-time.After(10 * time.Second):
+			case <-time.After(n.Config.JoinTimeout):
 				return Contact{}, fmt.Errorf("[JOIN] timeout waiting for final JOIN_ACK")
 			}
 
-		case <-time.After(10 * time.Second):
+		case <-time.After(n.Config.JoinTimeout):
 			return Contact{}, fmt.Errorf("[JOIN] timeout waiting for POS_CHALLENGE")
 		}
 
-	case <-time.After(10 * time.Second):
+	case <-time.After(n.Config.JoinTimeout):
 		return Contact{}, fmt.Errorf("[JOIN] timeout waiting for JOIN_CHALLENGE")
 	}
 }
@@ -235,7 +275,7 @@ func (n *Node) HandleFindNode(sender Contact, targetID NodeID) []Contact {
 	n.RoutingTable.Update(sender)
 
 	// Get closest nodes from routing table
-	allNodes := n.RoutingTable.GetClosestNodes(targetID, constants.K)
+	allNodes := n.RoutingTable.GetClosestNodes(targetID, n.Config.K)
 
 	// Filter out the sender (they already know about themselves)
 	var nodes []Contact
@@ -256,97 +296,251 @@ func (n *Node) HandlePing(sender Contact) {
 	n.RoutingTable.Update(sender)
 }
 
-func (n *Node) HandleStore(sender Contact, key NodeID, value []byte) {
+func (n *Node) HandleStore(sender Contact, req StoreRequest) error {
 	n.RoutingTable.Update(sender)
+	if err := n.allowStore(sender); err != nil {
+		fmt.Printf("[SERVER] ✗ Refused key %s: %v (from %s)\n",
+			req.Key.String()[:16], err, sender.ID.String()[:16])
+		return err
+	}
+	return n.storeLocal(sender, req)
+}
+
+// HandleStoreBatch stores the values of a STORE_BATCH, which counts as a single request
+// against the rate limit. Returns the number of values stored and the first refusal.
+func (n *Node) HandleStoreBatch(sender Contact, items []StoreRequest) (int, error) {
+	n.RoutingTable.Update(sender)
+	if err := n.allowStore(sender); err != nil {
+		fmt.Printf("[SERVER] ✗ Refused batch of %d keys: %v (from %s)\n",
+			len(items), err, sender.ID.String()[:16])
+		return 0, err
+	}
+
+	stored := 0
+	var firstErr error
+	for _, item := range items {
+		if err := n.storeLocal(sender, item); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		stored++
+	}
+	return stored, firstErr
+}
+
+// storeLocal stores a value received from sender (or ourselves) in local storage.
+// Returns why the value was refused: its key was deleted, it is an outdated
+// record, or it exceeds a storage quota.
+func (n *Node) storeLocal(sender Contact, req StoreRequest) error {
+	key, value := req.Key, req.Value
+	ttl := time.Duration(req.TTL) * time.Second
+
+	// Named records, manifests, identities and inboxes must carry valid signatures
+	if err := n.verifyValue(key, value); err != nil {
+		fmt.Printf("[SERVER] ✗ Refused record for key %s: %v (from %s)\n",
+			key.String()[:16], err, sender.ID.String()[:16])
+		return err
+	}
+
+	// A cached copy (ttl > 0) is only kept until it expires and is never re-replicated
+	if ttl > 0 {
+		n.StorageMux.Lock()
+		current, exists := n.Storage[key]
+		_, cached := n.CacheExpiry[key]
+		var err error
+		switch {
+		case n.tombstoned(key, nil):
+			err = fmt.Errorf("key was deleted")
+		case exists && !cached:
+			// We already hold a replica
+			err = fmt.Errorf("key is already stored")
+		default:
+			if err = checkRecordUpdate(current, value); err == nil {
+				err = n.checkQuota(sender.ID, key, value, true)
+			}
+		}
+		if err != nil {
+			n.StorageMux.Unlock()
+			return err
+		}
+		n.putLocked(sender.ID, key, value)
+		n.CacheExpiry[key] = time.Now().Add(ttl)
+		n.StorageMux.Unlock()
+
+		fmt.Printf("[SERVER] ✓ Cached %d bytes for key %s for %v (from %s)\n",
+			len(value), key.String()[:16], ttl, sender.ID.String()[:16])
+		return nil
+	}
 
-	// Actually store the data in local storage
 	n.StorageMux.Lock()
-	n.Storage[key] = value
+	if n.tombstoned(key, req.Owner) {
+		n.StorageMux.Unlock()
+		fmt.Printf("[SERVER] ✗ Refused key %s, deleted by its owner (from %s)\n",
+			key.String()[:16], sender.ID.String()[:16])
+		return fmt.Errorf("key was deleted by its owner")
+	}
+	if err := checkRecordUpdate(n.Storage[key], value); err != nil {
+		n.StorageMux.Unlock()
+		fmt.Printf("[SERVER] ✗ Refused record for key %s: %v (from %s)\n",
+			key.String()[:16], err, sender.ID.String()[:16])
+		return err
+	}
+	// Inboxes grow by merging, every replica keeps the notices it was sent
+	value = n.mergeInbox(n.Storage[key], value)
+	if err := n.checkQuota(sender.ID, key, value, false); err != nil {
+		n.StorageMux.Unlock()
+		fmt.Printf("[SERVER] ✗ Refused key %s: %v (from %s)\n",
+			key.String()[:16], err, sender.ID.String()[:16])
+		return err
+	}
+	n.putLocked(sender.ID, key, value)
+	delete(n.CacheExpiry, key)
+	n.dropTombstoneLocked(key)
+	if len(req.Owner) > 0 {
+		n.Owners[key] = req.Owner
+	} else {
+		delete(n.Owners, key)
+	}
+
+	// A shard is kept by this node only, lost shards are rebuilt from the others
+	if req.Shard {
+		n.ShardKeys[key] = true
+		n.StorageMux.Unlock()
+
+		fmt.Printf("[SERVER] ✓ Stored %d byte shard for key %s (from %s)\n",
+			len(value), key.String()[:16], sender.ID.String()[:16])
+		return nil
+	}
+
+	delete(n.ShardKeys, key)
 	n.StorageMux.Unlock()
 
 	fmt.Printf("[SERVER] ✓ Stored %d bytes for key %s (from %s)\n",
 		len(value), key.String()[:16], sender.ID.String()[:16])
 
-	// Start or restart the replication timer for this key
-	n.startReplicationTimer(key, value)
+	// Another replica just republished this key, so we can skip the next round
+	n.Replication.Track(key)
+	return nil
 }
 
-// startReplicationTimer starts or restarts a recurring timer for re-replicating a key-value pair
-func (n *Node) startReplicationTimer(key NodeID, value []byte) {
-	n.TimerMutex.Lock()
-	defer n.TimerMutex.Unlock()
-
-	// Stop existing timer if one exists for this key
-	if existingTimer, exists := n.ReplicationTimers[key]; exists {
-		existingTimer.Ticker.Stop()
-		close(existingTimer.Stop)
-		fmt.Printf("[TIMER] Stopped existing replication timer for key %s\n", key.String()[:16])
+// verifyValue checks the self-certifying values: named records, encrypted
+// manifests, identity records, rotations and inboxes. Other values are accepted as they are.
+func (n *Node) verifyValue(key NodeID, value []byte) error {
+	if record, ok := ParseRecord(value); ok {
+		return verifyRecord(key, record)
 	}
-
-	// Create a new recurring timer using a ticker
-	ticker := time.NewTicker(10 * time.Minute)
-	stopChan := make(chan bool)
-
-	// Store the timer tracking structure
-	n.ReplicationTimers[key] = &ReplicationTimer{
-		Ticker: ticker,
-		Stop:   stopChan,
+	if manifest, ok := ParseEncryptedManifest(value); ok {
+		return verifyManifest(key, manifest)
 	}
+	if identity, ok := ParseIdentity(value); ok {
+		return verifyIdentity(key, identity)
+	}
+	if rotation, ok := ParseRotation(value); ok {
+		return verifyRotation(key, rotation)
+	}
+	if inbox, ok := ParseInbox(value); ok {
+		return n.verifyInbox(key, inbox)
+	}
+	return nil
+}
 
-	// Start the replication goroutine
-	go func(k NodeID) {
-		for {
-			select {
-			case <-ticker.C:
-				// Get the current value from storage (it might have been updated)
-				n.StorageMux.RLock()
-				currentValue, exists := n.Storage[k]
-				n.StorageMux.RUnlock()
-
-				if !exists {
-					// Key was deleted, stop the ticker
-					ticker.Stop()
-					n.TimerMutex.Lock()
-					delete(n.ReplicationTimers, k)
-					n.TimerMutex.Unlock()
-					fmt.Printf("[TIMER] Key %s no longer in storage, stopping replication\n", k.String()[:16])
-					return
-				}
+// isReplica reports whether a stored key is a full replica rather than a
+// cached copy or an erasure shard. The caller must hold StorageMux.
+func (n *Node) isReplica(key NodeID) bool {
+	_, cached := n.CacheExpiry[key]
+	return !cached && !n.ShardKeys[key]
+}
 
-				fmt.Printf("[TIMER] Replication timer triggered for key %s, re-storing to network...\n", k.String()[:16])
-				// Call the Store function which will send STORE messages to k closest nodes
-				n.Store(k, currentValue)
+// getLocal returns a value from local storage, dropping cached copies that have expired
+func (n *Node) getLocal(key NodeID) ([]byte, bool) {
+	n.StorageMux.RLock()
+	value, exists := n.Storage[key]
+	expiry, cached := n.CacheExpiry[key]
+	n.StorageMux.RUnlock()
 
-			case <-stopChan:
-				// Received stop signal
-				ticker.Stop()
-				fmt.Printf("[TIMER] Replication timer stopped for key %s\n", k.String()[:16])
-				return
-			}
+	if exists && cached && time.Now().After(expiry) {
+		n.StorageMux.Lock()
+		// Re-check under the write lock, a replica may have replaced the cache entry
+		if expiry, cached := n.CacheExpiry[key]; cached && time.Now().After(expiry) {
+			n.deleteLocked(key)
+			delete(n.CacheExpiry, key)
+			fmt.Printf("[CACHE] Cached copy of key %s expired\n", key.String()[:16])
 		}
-	}(key)
+		value, exists = n.Storage[key]
+		n.StorageMux.Unlock()
+	}
 
-	fmt.Printf("[TIMER] Started replication timer for key %s (will trigger every 10 minutes)\n", key.String()[:16])
+	if exists {
+		n.access.touch(key)
+	}
+	return value, exists
 }
 
 func (n *Node) HandleFindValue(sender Contact, key NodeID) ([]byte, []Contact) {
 	n.RoutingTable.Update(sender)
 
 	// Check if we have the value locally
-	n.StorageMux.RLock()
-	value, exists := n.Storage[key]
-	n.StorageMux.RUnlock()
+	value, exists := n.getLocal(key)
 
 	if exists {
 		fmt.Printf("[SERVER] ✓ Found value for key %s (returning %d bytes to %s)\n",
 			key.String()[:16], len(value), sender.ID.String()[:16])
+
+		// Track demand and spread extra replicas while the key is hot
+		if target, spread := n.HotKeys.Hit(key); spread {
+			go n.spreadHotKey(key, value, target)
+		}
+
+		// A nil value means not found, an empty one is still a value
+		if value == nil {
+			value = []byte{}
+		}
 		return value, nil // Return the value, no contacts needed
 	}
 
 	// Don't have it - return closest nodes who might have it
 	fmt.Printf("[SERVER] ✗ Key %s not found locally, returning closest nodes to %s\n",
 		key.String()[:16], sender.ID.String()[:16])
-	return nil, n.RoutingTable.GetClosestNodes(key, constants.K)
+	return nil, n.RoutingTable.GetClosestNodes(key, n.Config.K)
+}
+
+// spreadHotKey pushes expiring copies of a hot key to the nodes ranked K+1..target
+// around the key. The copies expire unless the key stays hot and they are pushed again,
+// so the replica count falls back to K when demand drops.
+func (n *Node) spreadHotKey(key NodeID, value []byte, target int) {
+	if n.Network == nil {
+		return
+	}
+
+	fmt.Printf("[HOT] Key %s is hot, raising replica count to %d\n", key.String()[:16], target)
+
+	closest, _ := n.NodeLookupN(key, target)
+	if len(closest) <= n.Config.K {
+		fmt.Printf("[HOT] Not enough nodes around key %s for extra replicas\n", key.String()[:16])
+		return
+	}
+
+	pushed := 0
+	for _, contact := range closest[n.Config.K:] {
+		if contact.ID == n.Self.ID {
+			continue
+		}
+		err := n.Network.SendCacheStore(contact, key, value, n.Config.HotKeyReplicaTTL)
+		if err != nil {
+			fmt.Printf("[HOT] ✗ Failed to push key %s to %s: %v\n", key.String()[:16], contact.ID.String()[:16], err)
+			continue
+		}
+		pushed++
+	}
+
+	fmt.Printf("[HOT] ✓ Pushed %d extra replicas of key %s\n", pushed, key.String()[:16])
+}
+
+// GetHotKeyInfo returns the keys with recent FIND_VALUE hits and their replica targets
+func (n *Node) GetHotKeyInfo() []HotKeyInfo {
+	return n.HotKeys.Snapshot()
 }
 
 // BucketInfo represents a single bucket for JSON output
@@ -380,6 +574,10 @@ func (n *Node) GetRoutingTableInfo() []BucketInfo {
 func (n *Node) HandleJoinRequest(sender Contact, payload JoinRequestPayload) (JoinChallengePayload, error) {
 	fmt.Printf("[SERVER] Received JOIN_REQ from %s\n", payload.PeerID.String()[:16])
 
+	if n.leaving.Load() {
+		return JoinChallengePayload{}, errLeaving
+	}
+
 	// 1. Verify PubKey -> PeerID match (Sybil attack prevention)
 	pubKey, err := x509.ParsePKIXPublicKey(payload.PublicKey)
 	if err != nil {
@@ -432,8 +630,8 @@ func (n *Node) HandleJoinResponse(sender Contact, payload JoinResponsePayload) (
 		return JoinAckPayload{Success: false, Message: "No pending challenge found"}, fmt.Errorf("no pending challenge")
 	}
 
-	// 2. Check if challenge expired (10 seconds timeout)
-	if time.Since(challenge.Timestamp) > 10*time.Second {
+	// 2. Check if challenge expired
+	if time.Since(challenge.Timestamp) > n.Config.JoinTimeout {
 		n.ChallengeMutex.Lock()
 		delete(n.PendingChallenges, sender.ID)
 		n.ChallengeMutex.Unlock()
@@ -488,23 +686,48 @@ func (n *Node) HandleJoinResponse(sender Contact, payload JoinResponsePayload) (
 
 // Store stores a key-value pair in the DHT by replicating it to K closest nodes
 func (n *Node) Store(key NodeID, value []byte) error {
+	_, err := n.storeReplicas(key, value)
+	return err
+}
+
+// storeReplicas stores a key-value pair like Store.
+// Returns: the number of other nodes that accepted it, error
+func (n *Node) storeReplicas(key NodeID, value []byte) (int, error) {
 	fmt.Printf("[DHT-STORE] Storing key %s (%d bytes)...\n", key.String()[:16], len(value))
 
-	// 1. Find K closest nodes to this key using NodeLookup
-	closestNodes, _ := n.NodeLookup(key)
+	// We own what we store, so we can delete it later
+	owner := n.ownerKey()
+	n.StorageMux.Lock()
+	deleted := n.tombstoned(key, owner)
+	n.StorageMux.Unlock()
+	if deleted {
+		return 0, fmt.Errorf("key was deleted, it can be stored again once its tombstone expires")
+	}
+
+	// 1. Find the closest nodes to this key, with spares for nodes that refuse it
+	closestNodes, _ := n.NodeLookupN(key, 2*n.Config.K)
 
 	if len(closestNodes) == 0 {
 		fmt.Printf("[DHT-STORE] ✗ No nodes found in network, storing only locally\n")
 		// Store locally at least
-		n.StorageMux.Lock()
-		n.Storage[key] = value
-		n.StorageMux.Unlock()
-		return fmt.Errorf("no nodes available for replication")
+		n.storeLocal(n.Self, StoreRequest{Key: key, Value: value, Owner: owner})
+		return 0, fmt.Errorf("no nodes available for replication")
+	}
+
+	// 2. Replicate to the K closest nodes, a node that fails or refuses is
+	// replaced by the next closest one
+	want := 0
+	for i, contact := range closestNodes {
+		if i < n.Config.K && contact.ID != n.Self.ID {
+			want++
+		}
 	}
 
-	// 2. Replicate to all K closest nodes
 	successCount := 0
 	for _, contact := range closestNodes {
+		if successCount == want {
+			break
+		}
 		// Don't send to ourselves
 		if contact.ID == n.Self.ID {
 			continue
@@ -513,7 +736,7 @@ func (n *Node) Store(key NodeID, value []byte) error {
 		fmt.Printf("[DHT-STORE] Replicating to node %s at %s:%d\n",
 			contact.ID.String()[:16], contact.IP, contact.Port)
 
-		err := n.Network.SendStore(contact, key, value)
+		err := n.Network.SendStore(contact, key, value, owner)
 		if err == nil {
 			successCount++
 			fmt.Printf("[DHT-STORE] ✓ Successfully replicated to %s\n", contact.ID.String()[:16])
@@ -522,105 +745,167 @@ func (n *Node) Store(key NodeID, value []byte) error {
 		}
 	}
 
-	// 3. Also store locally (we might be one of the closest nodes)
-	n.StorageMux.Lock()
-	n.Storage[key] = value
-	n.StorageMux.Unlock()
-	fmt.Printf("[DHT-STORE] ✓ Stored locally\n")
-
-	// Start replication timer for this key
-	n.startReplicationTimer(key, value)
+	// 3. Also store locally (we might be one of the closest nodes), this republishes the key periodically
+	replicas := successCount
+	if err := n.storeLocal(n.Self, StoreRequest{Key: key, Value: value, Owner: owner}); err != nil {
+		fmt.Printf("[DHT-STORE] ✗ Not stored locally: %v\n", err)
+		if successCount == 0 {
+			return 0, fmt.Errorf("no node accepted the value: %v", err)
+		}
+	} else {
+		fmt.Printf("[DHT-STORE] ✓ Stored locally\n")
+		successCount++
+	}
 
-	fmt.Printf("[DHT-STORE] ✓ Complete: stored at %d remote nodes + local = %d total locations\n",
-		successCount, successCount+1)
+	fmt.Printf("[DHT-STORE] ✓ Complete: stored at %d total locations\n", successCount)
 
-	return nil
+	return replicas, nil
 }
 
 // FindValue retrieves a value from the DHT using Kademlia iterative lookup
+// over d disjoint paths. The value is only accepted if it verifies itself
+// (its SHA-256 is the key) or a majority of the paths returned it.
 // Returns: value, hopCount, error
 func (n *Node) FindValue(key NodeID) ([]byte, int, error) {
 	fmt.Printf("[DHT-FIND] Searching for key %s...\n", key.String()[:16])
 
-	// 1. Check locally first (hop count = 0)
-	n.StorageMux.RLock()
-	value, exists := n.Storage[key]
-	n.StorageMux.RUnlock()
+	// 1. Check locally first (hop count = 0). A versioned value may have newer versions elsewhere.
+	local, exists := n.getLocal(key)
+	_, isRecord := versionOf(key, local)
 
-	if exists {
-		fmt.Printf("[DHT-FIND] ✓ Found locally (%d bytes)\n", len(value))
-		return value, 0, nil
+	if exists && !isRecord {
+		fmt.Printf("[DHT-FIND] ✓ Found locally (%d bytes)\n", len(local))
+		return local, 0, nil
 	}
 
 	fmt.Printf("[DHT-FIND] Not found locally, starting iterative FIND_VALUE lookup...\n")
 
-	// 2. Initialize lookup state with closest known nodes
-	localCandidates := n.RoutingTable.GetClosestNodes(key, constants.K)
-	if len(localCandidates) == 0 {
-		return nil, 0, fmt.Errorf("key not found: no nodes in network")
+	// 2. ITERATIVE FIND_VALUE over disjoint paths
+	// Unlike NodeLookup which uses FIND_NODE, this uses FIND_VALUE
+	results := n.disjointLookup("DHT-FIND", key, n.Network.SendFindValue)
+	if len(results) == 0 {
+		if isRecord {
+			return local, 0, nil
+		}
+		return nil, 0, fmt.Errorf("%w: no nodes in network", ErrNotFound)
 	}
 
-	state := NewLookupState(key, localCandidates)
 	hopCount := 0
+	for _, r := range results {
+		hopCount += r.Hops
+	}
 
-	// 3. ITERATIVE FIND_VALUE LOOP (Kademlia protocol)
-	// Unlike NodeLookup which uses FIND_NODE, this uses FIND_VALUE
-	for {
-		// A. Pick the next closest unqueried node
-		candidate := state.PickNextBest()
+	// 3. Versioned values verify themselves, take the newest version any replica has
+	if value, ok := n.newestRecord(key, local, results); ok {
+		version, _ := versionOf(key, value)
+		fmt.Printf("[DHT-FIND] ✓ Found record version %d [hops: %d]\n", version, hopCount)
+		return value, hopCount, nil
+	}
 
-		// TERMINATION: No more nodes to query
-		if candidate == nil {
-			fmt.Printf("[DHT-FIND] ✗ No more nodes to query, key not found (hops: %d)\n", hopCount)
-			break
-		}
+	// 4. Cross-check the values returned by each path
+	value, err := agreeOnValue(key, results)
+	if err != nil {
+		fmt.Printf("[DHT-FIND] ✗ %v (hops: %d)\n", err, hopCount)
+		return nil, hopCount, err
+	}
 
-		// B. Send FIND_VALUE RPC
-		fmt.Printf("[DHT-FIND] [Hop %d] Querying node %s at %s:%d\n",
-			hopCount+1, candidate.ID.String()[:16], candidate.IP, candidate.Port)
+	fmt.Printf("[DHT-FIND] ✓ Found value (%d bytes) [hops: %d]\n", len(value), hopCount)
 
-		hopCount++
-		value, nodes, err := n.Network.SendFindValue(*candidate, key)
+	// 5. Cache on the path so the next lookup stops before reaching the replicas
+	n.cacheAlongPath(key, value, results)
 
-		// Mark as contacted to avoid re-querying
-		state.MarkContacted(candidate.ID)
+	return value, hopCount, nil
+}
 
-		// C. Handle errors
-		if err != nil {
-			fmt.Printf("[DHT-FIND] ✗ Failed to query %s: %v\n", candidate.ID.String()[:16], err)
+// cacheAlongPath STOREs the value, with an expiry, at the closest node observed
+// during the lookup that answered without it.
+func (n *Node) cacheAlongPath(key NodeID, value []byte, results []PathResult) {
+	var holder, target *Contact
+	for i := range results {
+		r := &results[i]
+		if !bytes.Equal(r.Value, value) {
 			continue
 		}
+		if holder == nil || r.Holder.ID.Xor(key).Less(holder.ID.Xor(key)) {
+			holder = &r.Holder
+		}
+		for j := range r.Missed {
+			if target == nil || r.Missed[j].ID.Xor(key).Less(target.ID.Xor(key)) {
+				target = &r.Missed[j]
+			}
+		}
+	}
+
+	if holder == nil || target == nil {
+		return
+	}
+
+	ttl := n.cacheTTL(key, holder.ID, target.ID)
+	cache := *target
+
+	fmt.Printf("[CACHE] Caching key %s at %s for %v\n", key.String()[:16], cache.ID.String()[:16], ttl)
+
+	go func() {
+		if err := n.Network.SendCacheStore(cache, key, value, ttl); err != nil {
+			fmt.Printf("[CACHE] ✗ Failed to cache key %s at %s: %v\n", key.String()[:16], cache.ID.String()[:16], err)
+		}
+	}()
+}
 
-		// D. VALUE FOUND! (success case)
-		if value != nil {
-			fmt.Printf("[DHT-FIND] ✓ Found value at node %s (%d bytes) [hops: %d]\n",
-				candidate.ID.String()[:16], len(value), hopCount)
+// cacheTTL returns the expiry for a copy cached at node cache. It is inversely
+// proportional to the cache node's distance from the key, relative to the node
+// that held the value: twice as far from the key means half the expiry.
+func (n *Node) cacheTTL(key, holder, cache NodeID) time.Duration {
+	ratio := 1.0
+	holderDist := holder.Xor(key).Approx()
+	cacheDist := cache.Xor(key).Approx()
+	if cacheDist > holderDist {
+		ratio = holderDist / cacheDist
+	}
 
-			// Cache locally for future lookups
-			// n.StorageMux.Lock()
-			// n.Storage[key] = value
-			// n.StorageMux.Unlock()
+	ttl := time.Duration(float64(n.Config.CacheTTL) * ratio)
+	if ttl < n.Config.CacheMinTTL {
+		ttl = n.Config.CacheMinTTL
+	}
+	return ttl
+}
 
-			return value, hopCount, nil
+// agreeOnValue picks the value to return from the disjoint path results.
+// A value whose SHA-256 equals the key is content-addressed and accepted as is,
+// anything else needs identical copies from a majority of the paths.
+func agreeOnValue(key NodeID, results []PathResult) ([]byte, error) {
+	votes := make(map[string]int)
+	for _, r := range results {
+		if r.Value == nil {
+			continue
+		}
+		if sha256.Sum256(r.Value) == key {
+			return r.Value, nil
 		}
+		votes[string(r.Value)]++
+	}
 
-		// E. VALUE NOT FOUND, but got closer nodes
-		// Add returned nodes to shortlist and continue iteration
-		if len(nodes) > 0 {
-			fmt.Printf("[DHT-FIND] Node %s doesn't have key, returned %d closer nodes\n",
-				candidate.ID.String()[:16], len(nodes))
-			state.Append(nodes)
-		} else {
-			fmt.Printf("[DHT-FIND] Node %s doesn't have key, no new nodes returned\n",
-				candidate.ID.String()[:16])
+	if len(votes) == 0 {
+		return nil, fmt.Errorf("%w in DHT", ErrNotFound)
+	}
+
+	var best string
+	bestVotes := 0
+	for v, count := range votes {
+		if count > bestVotes {
+			best, bestVotes = v, count
 		}
+	}
 
-		// Update routing table (node is alive)
-		n.RoutingTable.Update(*candidate)
+	quorum := len(results)/2 + 1
+	if bestVotes < quorum {
+		if len(votes) > 1 {
+			return nil, fmt.Errorf("conflicting values across %d disjoint paths", len(results))
+		}
+		return nil, fmt.Errorf("value returned by only %d of %d disjoint paths", bestVotes, len(results))
 	}
 
-	// 4. Key not found after exhausting all nodes
-	return nil, hopCount, fmt.Errorf("key not found in DHT")
+	return []byte(best), nil
 }
 
 // ---------------------------------------------------------
@@ -634,7 +919,8 @@ func (n *Node) InitializePosPlot() error {
 	startTime := time.Now()
 	plot, err := pos.GeneratePlot(
 		id_tools.PeerID(n.Self.ID),
-		constants.PosPlotDataDir,
+		n.Config.PlotDir,
+		n.Config.PosNumEntries,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to generate PoS plot: %w", err)
@@ -667,9 +953,9 @@ func (n *Node) GeneratePosProof(challenge *PosChallengePayload) (*PosProofPayloa
 
 // HandlePosChallenge is called by server to create a PoS challenge for joining node
 func (n *Node) HandlePosChallenge(sender Contact) (*PosChallengePayload, error) {
-	fmt.Printf("[SERVER] Creating PoS challenge for %s (T=%d bits)\n", sender.ID.String()[:16], constants.PosPrefixBits)
+	fmt.Printf("[SERVER] Creating PoS challenge for %s (T=%d bits)\n", sender.ID.String()[:16], n.Config.PosPrefixBits)
 
-	challenge, err := pos.GenerateChallenge()
+	challenge, err := pos.GenerateChallenge(n.Config.PosPrefixBits)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate PoS challenge: %w", err)
 	}
@@ -708,8 +994,8 @@ func (n *Node) HandlePosProof(sender Contact, payload PosProofPayload) (JoinAckP
 		return JoinAckPayload{Success: false, Message: "No pending challenge found"}, fmt.Errorf("no pending challenge")
 	}
 
-	// Check timeout (5 seconds)
-	if time.Since(pendingChallenge.Timestamp) > time.Duration(constants.PosChallengeTimeout)*time.Second {
+	// Check timeout
+	if time.Since(pendingChallenge.Timestamp) > n.Config.PosChallengeTimeout {
 		n.ChallengeMutex.Lock()
 		delete(n.PendingChallenges, sender.ID)
 		n.ChallengeMutex.Unlock()
@@ -728,7 +1014,7 @@ func (n *Node) HandlePosProof(sender Contact, payload PosProofPayload) (JoinAckP
 
 	// Recreate challenge
 	challenge := &pos.Challenge{
-		PrefixBits: uint8(constants.PosPrefixBits),
+		PrefixBits: uint8(n.Config.PosPrefixBits),
 		Prefix:     prefix,
 	}
 
@@ -1,7 +1,9 @@
 package dht
 
 import (
+	"encoding/binary"
 	"encoding/hex"
+	"fmt"
 	"math/bits"
 
 	"github.com/kutluhann/decentralized-file-sharing-system/id_tools"
@@ -37,6 +39,26 @@ func (id NodeID) Less(other NodeID) bool {
 	return false
 }
 
+// Approx returns the leading 64 bits of the ID as a float.
+// It is precise enough to compare XOR distances by ratio.
+func (id NodeID) Approx() float64 {
+	return float64(binary.BigEndian.Uint64(id[:8]))
+}
+
 func (id NodeID) String() string {
 	return hex.EncodeToString(id[:])
 }
+
+// ParseNodeID parses the hex form returned by String
+func ParseNodeID(s string) (NodeID, error) {
+	var id NodeID
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return id, err
+	}
+	if len(b) != len(id) {
+		return id, fmt.Errorf("expected %d bytes, got %d", len(id), len(b))
+	}
+	copy(id[:], b)
+	return id, nil
+}
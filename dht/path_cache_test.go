@@ -0,0 +1,107 @@
+package dht
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/kutluhann/decentralized-file-sharing-system/constants"
+)
+
+// TestFindValueCachesOnPath tests that the closest node without the value receives a cached copy
+func TestFindValueCachesOnPath(t *testing.T) {
+	holder := startHonestNode(t, idWithPrefix(0x01, 1))
+	middle := startHonestNode(t, idWithPrefix(0x20, 1))
+	middle.RoutingTable.Update(holder.Self)
+
+	querier := startHonestNode(t, idWithPrefix(0xF0, 1))
+	querier.Config.DisjointPaths = 1
+	querier.RoutingTable.Update(middle.Self)
+
+	key := NodeID{}
+	value := []byte("popular file")
+	holder.StorageMux.Lock()
+	holder.Storage[key] = value
+	holder.StorageMux.Unlock()
+
+	got, hops, err := querier.FindValue(key)
+	if err != nil {
+		t.Fatalf("FindValue failed: %v", err)
+	}
+	if !bytes.Equal(got, value) || hops != 2 {
+		t.Fatalf("Expected %q in 2 hops, got %q in %d hops", value, got, hops)
+	}
+
+	// The cache STORE is sent in the background
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		middle.StorageMux.RLock()
+		cached := middle.Storage[key]
+		expiry, hasExpiry := middle.CacheExpiry[key]
+		middle.StorageMux.RUnlock()
+
+		if cached != nil {
+			if !bytes.Equal(cached, value) || !hasExpiry || !expiry.After(time.Now()) {
+				t.Fatalf("Expected cached copy with future expiry, got %q (expiry %v)", cached, expiry)
+			}
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("Value was not cached on the lookup path")
+}
+
+// TestCachedValueExpires tests that an expired cache entry is no longer served
+func TestCachedValueExpires(t *testing.T) {
+	node := NewNode(Contact{ID: idWithPrefix(0x20, 1)}, nil)
+	key := NodeID{}
+
+	node.HandleStore(Contact{ID: idWithPrefix(0x30, 1)}, StoreRequest{Key: key, Value: []byte("cached"), TTL: 60})
+	if value, _ := node.HandleFindValue(Contact{}, key); value == nil {
+		t.Fatal("Expected cached value to be served before expiry")
+	}
+
+	node.StorageMux.Lock()
+	node.CacheExpiry[key] = time.Now().Add(-time.Second)
+	node.StorageMux.Unlock()
+
+	if value, _ := node.HandleFindValue(Contact{}, key); value != nil {
+		t.Fatal("Expected expired cached value to be dropped")
+	}
+}
+
+// TestCacheDoesNotReplaceReplica tests that a cache STORE never downgrades a replica
+func TestCacheDoesNotReplaceReplica(t *testing.T) {
+	node := NewNode(Contact{ID: idWithPrefix(0x20, 1)}, nil)
+	key := NodeID{}
+
+	node.StorageMux.Lock()
+	node.Storage[key] = []byte("replica")
+	node.StorageMux.Unlock()
+
+	node.HandleStore(Contact{ID: idWithPrefix(0x30, 1)}, StoreRequest{Key: key, Value: []byte("replica"), TTL: 60})
+
+	node.StorageMux.RLock()
+	_, cached := node.CacheExpiry[key]
+	node.StorageMux.RUnlock()
+	if cached {
+		t.Fatal("Replica was turned into an expiring cache entry")
+	}
+}
+
+// TestCacheTTL tests that the expiry is inversely proportional to the distance from the key
+func TestCacheTTL(t *testing.T) {
+	node := NewNode(Contact{ID: idWithPrefix(0x20, 1)}, nil)
+	key := NodeID{}
+	holder := idWithPrefix(0x10, 0)
+
+	if ttl := node.cacheTTL(key, holder, idWithPrefix(0x08, 0)); ttl != constants.CacheTTL*time.Second {
+		t.Errorf("Cache closer than holder: expected full TTL, got %v", ttl)
+	}
+	if ttl := node.cacheTTL(key, holder, idWithPrefix(0x20, 0)); ttl != constants.CacheTTL/2*time.Second {
+		t.Errorf("Cache twice as far: expected half TTL, got %v", ttl)
+	}
+	if ttl := node.cacheTTL(key, idWithPrefix(0x00, 1), idWithPrefix(0xFF, 0)); ttl != constants.CacheMinTTL*time.Second {
+		t.Errorf("Cache far away: expected minimum TTL, got %v", ttl)
+	}
+}
@@ -0,0 +1,302 @@
+package dht
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ---------------------------------------------------------
+// PROVIDER RECORDS
+// Instead of the content itself, a key can map to the set of
+// peers that hold it. Peers announce themselves to the k closest
+// nodes with ADD_PROVIDER; those keep a bounded set of providers
+// per key that expires unless it is announced again. Announcements
+// are rate limited like STOREs and each peer may only be the
+// provider of a bounded number of keys. Expired records are swept
+// by the replication scheduler.
+// ---------------------------------------------------------
+
+// ProviderRecord is a peer that announced it holds the content of a key
+type ProviderRecord struct {
+	ID       NodeID `json:"id"`
+	IP       string `json:"ip"`        // Taken from the announcing packet, not self-reported
+	Port     int    `json:"port"`      // UDP port of the provider's DHT node
+	HTTPPort int    `json:"http_port"` // Port clients fetch the content from, 0 if unknown
+	Expires  int64  `json:"expires"`   // Unix time the record expires unless announced again
+}
+
+// ProviderStore keeps a bounded set of expiring provider records per key
+type ProviderStore struct {
+	providers map[NodeID]map[NodeID]ProviderRecord // Key -> provider ID -> record
+	perPeer   map[NodeID]int                       // Provider ID -> number of keys it provides
+	maxPerKey int
+	mutex     sync.Mutex
+}
+
+// NewProviderStore creates a store that keeps up to maxPerKey providers per key
+func NewProviderStore(maxPerKey int) *ProviderStore {
+	return &ProviderStore{
+		maxPerKey: maxPerKey,
+		providers: make(map[NodeID]map[NodeID]ProviderRecord),
+		perPeer:   make(map[NodeID]int),
+	}
+}
+
+// Add records a provider for a key. When the set is full, the record
+// closest to expiry makes room. A provider already recorded for peerLimit
+// other keys is refused, zero disables the limit.
+func (ps *ProviderStore) Add(key NodeID, record ProviderRecord, peerLimit int) error {
+	return ps.addAt(key, record, peerLimit, time.Now())
+}
+
+func (ps *ProviderStore) addAt(key NodeID, record ProviderRecord, peerLimit int, now time.Time) error {
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+
+	set := ps.live(key, now)
+	_, exists := set[record.ID]
+	if !exists && peerLimit > 0 && ps.perPeer[record.ID] >= peerLimit {
+		return fmt.Errorf("peer provider quota exceeded (%d keys)", peerLimit)
+	}
+	if set == nil {
+		set = make(map[NodeID]ProviderRecord)
+		ps.providers[key] = set
+	}
+
+	if !exists && len(set) >= ps.maxPerKey {
+		var oldest NodeID
+		first := true
+		for id, r := range set {
+			if first || r.Expires < set[oldest].Expires {
+				oldest, first = id, false
+			}
+		}
+		if set[oldest].Expires > record.Expires {
+			return nil
+		}
+		ps.remove(set, oldest)
+	}
+	if !exists {
+		ps.perPeer[record.ID]++
+	}
+	set[record.ID] = record
+	return nil
+}
+
+// Get returns the unexpired providers of a key, latest announcement first
+func (ps *ProviderStore) Get(key NodeID) []ProviderRecord {
+	return ps.getAt(key, time.Now())
+}
+
+func (ps *ProviderStore) getAt(key NodeID, now time.Time) []ProviderRecord {
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+
+	var records []ProviderRecord
+	for _, r := range ps.live(key, now) {
+		records = append(records, r)
+	}
+	sortProviders(records)
+	return records
+}
+
+// live drops the expired providers of a key and returns the rest.
+// The caller must hold the mutex.
+func (ps *ProviderStore) live(key NodeID, now time.Time) map[NodeID]ProviderRecord {
+	set := ps.providers[key]
+	for id, r := range set {
+		if now.Unix() >= r.Expires {
+			ps.remove(set, id)
+		}
+	}
+	if set != nil && len(set) == 0 {
+		delete(ps.providers, key)
+		return nil
+	}
+	return set
+}
+
+// remove deletes a provider from the set of a key. The caller must hold the mutex.
+func (ps *ProviderStore) remove(set map[NodeID]ProviderRecord, id NodeID) {
+	delete(set, id)
+	if ps.perPeer[id]--; ps.perPeer[id] <= 0 {
+		delete(ps.perPeer, id)
+	}
+}
+
+// Expire drops the expired providers of every key, including keys nobody looks up again
+func (ps *ProviderStore) Expire() {
+	ps.expireAt(time.Now())
+}
+
+func (ps *ProviderStore) expireAt(now time.Time) {
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+
+	for key := range ps.providers {
+		ps.live(key, now)
+	}
+}
+
+func sortProviders(records []ProviderRecord) {
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Expires != records[j].Expires {
+			return records[i].Expires > records[j].Expires
+		}
+		return records[i].ID.Less(records[j].ID)
+	})
+}
+
+// --- Server side ---
+
+// HandleAddProvider records the sender as a provider of a key.
+// Peers can only announce themselves, so the record is built from the sender.
+func (n *Node) HandleAddProvider(sender Contact, req AddProviderRequest) error {
+	n.RoutingTable.Update(sender)
+
+	err := n.allowStore(sender)
+	if err == nil {
+		err = n.Providers.Add(req.Key, ProviderRecord{
+			ID:       sender.ID,
+			IP:       sender.IP,
+			Port:     sender.Port,
+			HTTPPort: req.HTTPPort,
+			Expires:  time.Now().Add(n.Config.ProviderTTL).Unix(),
+		}, n.Limits.PeerMaxProviders)
+	}
+	if err != nil {
+		fmt.Printf("[SERVER] ✗ Refused provider of key %s: %v (from %s)\n",
+			req.Key.String()[:16], err, sender.ID.String()[:16])
+		return err
+	}
+
+	fmt.Printf("[SERVER] ✓ %s provides key %s\n", sender.ID.String()[:16], req.Key.String()[:16])
+	return nil
+}
+
+// HandleGetProviders returns the providers we know for a key and our closest nodes to it
+func (n *Node) HandleGetProviders(sender Contact, key NodeID) ([]ProviderRecord, []Contact) {
+	n.RoutingTable.Update(sender)
+	return n.Providers.Get(key), n.RoutingTable.GetClosestNodes(key, n.Config.K)
+}
+
+// --- Client side ---
+
+// Provide announces to the k closest nodes of a key that we hold its content.
+// The announcement is repeated by the replication scheduler until StopProviding.
+func (n *Node) Provide(key NodeID) error {
+	n.providedMux.Lock()
+	n.provided[key] = true
+	n.providedMux.Unlock()
+
+	return n.announce(key)
+}
+
+// StopProviding stops re-announcing a key, our records expire after ProviderTTL
+func (n *Node) StopProviding(key NodeID) {
+	n.providedMux.Lock()
+	delete(n.provided, key)
+	n.providedMux.Unlock()
+}
+
+// announce sends ADD_PROVIDER for a key to its k closest nodes
+func (n *Node) announce(key NodeID) error {
+	fmt.Printf("[PROVIDE] Announcing key %s\n", key.String()[:16])
+
+	closest := n.closestWithSelf(key)
+	if found, _ := n.NodeLookup(key); len(found) > 0 {
+		closest = found
+	}
+
+	announced := 0
+	for _, contact := range closest {
+		if contact.ID == n.Self.ID {
+			n.Providers.Add(key, ProviderRecord{
+				ID:       n.Self.ID,
+				IP:       n.Self.IP,
+				Port:     n.Self.Port,
+				HTTPPort: n.HTTPPort,
+				Expires:  time.Now().Add(n.Config.ProviderTTL).Unix(),
+			}, 0)
+			announced++
+			continue
+		}
+		if err := n.Network.SendAddProvider(contact, key, n.HTTPPort); err != nil {
+			fmt.Printf("[PROVIDE] ✗ Failed to announce to %s: %v\n", contact.ID.String()[:16], err)
+			continue
+		}
+		announced++
+	}
+
+	if announced == 0 {
+		return fmt.Errorf("no node accepted the provider record")
+	}
+	fmt.Printf("[PROVIDE] ✓ Announced key %s to %d nodes\n", key.String()[:16], announced)
+	return nil
+}
+
+// reannounce repeats the announcements of all provided keys
+func (n *Node) reannounce() {
+	n.providedMux.Lock()
+	keys := make([]NodeID, 0, len(n.provided))
+	for key := range n.provided {
+		keys = append(keys, key)
+	}
+	n.providedMux.Unlock()
+
+	for _, key := range keys {
+		if err := n.announce(key); err != nil {
+			fmt.Printf("[PROVIDE] ✗ Re-announcing key %s failed: %v\n", key.String()[:16], err)
+		}
+	}
+}
+
+// FindProviders asks the k closest nodes of a key for its providers and merges the answers.
+// Returns: providers, hopCount, error
+func (n *Node) FindProviders(key NodeID) ([]ProviderRecord, int, error) {
+	fmt.Printf("[PROVIDERS] Searching providers of key %s...\n", key.String()[:16])
+
+	closest, hopCount := n.NodeLookup(key)
+
+	merged := make(map[NodeID]ProviderRecord)
+	add := func(records []ProviderRecord) {
+		for _, r := range records {
+			if current, exists := merged[r.ID]; !exists || r.Expires > current.Expires {
+				merged[r.ID] = r
+			}
+		}
+	}
+	add(n.Providers.Get(key))
+
+	results := make([][]ProviderRecord, len(closest))
+	var wg sync.WaitGroup
+	for i, contact := range closest {
+		if contact.ID == n.Self.ID {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, contact Contact) {
+			defer wg.Done()
+			results[i], _, _ = n.Network.SendGetProviders(contact, key)
+		}(i, contact)
+	}
+	wg.Wait()
+	for _, records := range results {
+		add(records)
+	}
+
+	if len(merged) == 0 {
+		return nil, hopCount, fmt.Errorf("no providers found")
+	}
+
+	providers := make([]ProviderRecord, 0, len(merged))
+	for _, r := range merged {
+		providers = append(providers, r)
+	}
+	sortProviders(providers)
+
+	fmt.Printf("[PROVIDERS] ✓ Found %d providers [hops: %d]\n", len(providers), hopCount)
+	return providers, hopCount, nil
+}
@@ -0,0 +1,130 @@
+package dht
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kutluhann/decentralized-file-sharing-system/constants"
+)
+
+// TestProviderStoreBoundedAndExpiring tests the per-key limit and the expiry of provider records
+func TestProviderStoreBoundedAndExpiring(t *testing.T) {
+	ps := NewProviderStore(constants.MaxProvidersPerKey)
+	key := NodeID{1}
+	now := time.Unix(1_000_000, 0)
+
+	for i := 0; i < constants.MaxProvidersPerKey+5; i++ {
+		ps.addAt(key, ProviderRecord{ID: NodeID{byte(i + 1)}, Expires: now.Unix() + 100 + int64(i)}, 0, now)
+	}
+
+	records := ps.getAt(key, now)
+	if len(records) != constants.MaxProvidersPerKey {
+		t.Fatalf("Expected %d providers, got %d", constants.MaxProvidersPerKey, len(records))
+	}
+	// The records closest to expiry made room for the new ones
+	if records[len(records)-1].ID != (NodeID{6}) {
+		t.Errorf("Expected the oldest remaining provider to be 6, got %d", records[len(records)-1].ID[0])
+	}
+
+	// Re-announcing refreshes instead of adding a duplicate
+	ps.addAt(key, ProviderRecord{ID: NodeID{6}, Expires: now.Unix() + 1000}, 0, now)
+	if records := ps.getAt(key, now); len(records) != constants.MaxProvidersPerKey || records[0].ID != (NodeID{6}) {
+		t.Errorf("Re-announcement did not refresh the provider")
+	}
+
+	if records := ps.getAt(key, now.Add(2000*time.Second)); len(records) != 0 {
+		t.Errorf("Expected all providers to expire, %d left", len(records))
+	}
+}
+
+// TestProvideAndFindProviders tests that several announcers are found from another node
+func TestProvideAndFindProviders(t *testing.T) {
+	key := idWithPrefix(0x00, 1)
+	r1 := startHonestNode(t, idWithPrefix(0x01, 1))
+	r2 := startHonestNode(t, idWithPrefix(0x02, 1))
+	p1 := startHonestNode(t, idWithPrefix(0x80, 1))
+	p2 := startHonestNode(t, idWithPrefix(0xC0, 1))
+	reader := startHonestNode(t, idWithPrefix(0x40, 1))
+	connect(r1, r2, p1, p2, reader)
+	p1.HTTPPort = 8001
+
+	for _, p := range []*Node{p1, p2} {
+		if err := p.Provide(key); err != nil {
+			t.Fatalf("Provide failed: %v", err)
+		}
+	}
+
+	providers, _, err := reader.FindProviders(key)
+	if err != nil {
+		t.Fatalf("FindProviders failed: %v", err)
+	}
+	found := make(map[NodeID]ProviderRecord)
+	for _, p := range providers {
+		found[p.ID] = p
+	}
+	if len(found) != 2 {
+		t.Fatalf("Expected 2 providers, got %d", len(found))
+	}
+	if r := found[p1.Self.ID]; r.Port != p1.Self.Port || r.HTTPPort != 8001 {
+		t.Errorf("Provider address was not recorded: %+v", r)
+	}
+
+	if _, _, err := reader.FindProviders(idWithPrefix(0x00, 2)); err == nil {
+		t.Errorf("Expected no providers for an unannounced key")
+	}
+}
+
+// TestProviderStoreSweep tests that expired records of keys nobody asks for
+// again are swept and free the quota of their provider
+func TestProviderStoreSweep(t *testing.T) {
+	ps := NewProviderStore(constants.MaxProvidersPerKey)
+	now := time.Unix(1_000_000, 0)
+	provider := NodeID{1}
+
+	for i := byte(1); i <= 2; i++ {
+		if err := ps.addAt(NodeID{0, i}, ProviderRecord{ID: provider, Expires: now.Unix() + 100}, 2, now); err != nil {
+			t.Fatalf("Record %d was refused: %v", i, err)
+		}
+	}
+	if err := ps.addAt(NodeID{0, 3}, ProviderRecord{ID: provider, Expires: now.Unix() + 100}, 2, now); err == nil {
+		t.Fatalf("Expected a third key of the provider to be refused")
+	}
+	// Re-announcing a key the provider already has is not a new record
+	if err := ps.addAt(NodeID{0, 1}, ProviderRecord{ID: provider, Expires: now.Unix() + 200}, 2, now); err != nil {
+		t.Fatalf("Re-announcement was refused: %v", err)
+	}
+
+	ps.expireAt(now.Add(150 * time.Second))
+	if len(ps.providers) != 1 {
+		t.Fatalf("Expected the expired key to be swept, %d keys left", len(ps.providers))
+	}
+	if err := ps.addAt(NodeID{0, 3}, ProviderRecord{ID: provider, Expires: now.Unix() + 300}, 2, now); err != nil {
+		t.Errorf("Expired record did not free the provider quota: %v", err)
+	}
+}
+
+// TestAddProviderLimits tests that ADD_PROVIDER is rate limited and bounded per peer
+func TestAddProviderLimits(t *testing.T) {
+	node := startHonestNode(t, idWithPrefix(0x01, 1))
+	node.Limits.PeerMaxProviders = 2
+	greedy := Contact{ID: idWithPrefix(0x30, 1)}
+
+	for i := 1; i <= 2; i++ {
+		if err := node.HandleAddProvider(greedy, AddProviderRequest{Key: NodeID{byte(i)}}); err != nil {
+			t.Fatalf("Provider %d was refused: %v", i, err)
+		}
+	}
+	if err := node.HandleAddProvider(greedy, AddProviderRequest{Key: NodeID{3}}); err == nil {
+		t.Fatalf("Expected a third key of a peer to be refused")
+	}
+
+	node.Limits.PeerMaxProviders = 0
+	node.Limits.PeerRate, node.Limits.PeerBurst = 1, 1
+	other := Contact{ID: idWithPrefix(0x31, 1)}
+	if err := node.HandleAddProvider(other, AddProviderRequest{Key: NodeID{1}}); err != nil {
+		t.Fatalf("First announcement was refused: %v", err)
+	}
+	if err := node.HandleAddProvider(other, AddProviderRequest{Key: NodeID{2}}); err == nil {
+		t.Errorf("Expected the second announcement in a burst of 1 to be rate limited")
+	}
+}
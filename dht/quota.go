@@ -0,0 +1,275 @@
+package dht
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kutluhann/decentralized-file-sharing-system/constants"
+)
+
+// ---------------------------------------------------------
+// STORAGE QUOTAS
+// A node caps the total size of the values it stores, the bytes
+// and keys each peer may add, and how fast a peer may send STOREs.
+// Refused STOREs are answered with the reason, so the storing
+// node can pick another replica.
+// ---------------------------------------------------------
+
+// StoreLimits bounds what peers can make us store. Zero disables a limit.
+type StoreLimits struct {
+	MaxBytes         int64   // Total bytes of stored values
+	PeerMaxBytes     int64   // Bytes of the keys a single peer added
+	PeerMaxRecords   int     // Number of keys a single peer added
+	PeerMaxProviders int     // Number of keys a single peer is the provider of
+	PeerRate         float64 // STORE requests per second a peer may send on average
+	PeerBurst        int     // STORE requests a peer may send at once
+}
+
+// DefaultStoreLimits returns the limits from the constants package
+func DefaultStoreLimits() StoreLimits {
+	return StoreLimits{
+		MaxBytes:         constants.StorageQuotaBytes,
+		PeerMaxBytes:     constants.PeerQuotaBytes,
+		PeerMaxRecords:   constants.PeerQuotaRecords,
+		PeerMaxProviders: constants.PeerQuotaProviders,
+		PeerRate:         constants.PeerStoreRate,
+		PeerBurst:        constants.PeerStoreBurst,
+	}
+}
+
+// StorageUsage represents the storage accounting of a node for JSON output
+type StorageUsage struct {
+	Bytes    int64 `json:"bytes"`
+	Keys     int   `json:"keys"`
+	MaxBytes int64 `json:"max_bytes"`
+	Peers    int   `json:"peers"` // Peers that added keys we still store
+}
+
+type peerUsage struct {
+	bytes   int64
+	records int
+}
+
+// storageAccount tracks stored bytes in total and per peer. A key is charged
+// to the peer that first stored it. Guarded by the node's StorageMux.
+type storageAccount struct {
+	total        int64
+	storedBy     map[NodeID]NodeID // Key -> peer charged for it
+	tombstonedBy map[NodeID]NodeID // Key -> peer charged for its tombstone, for keys we did not hold
+	peers        map[NodeID]*peerUsage
+}
+
+// tombstoneBytes is charged for a tombstone of a key we did not hold, about its size
+const tombstoneBytes = 256
+
+func newStorageAccount() storageAccount {
+	return storageAccount{
+		storedBy:     make(map[NodeID]NodeID),
+		tombstonedBy: make(map[NodeID]NodeID),
+		peers:        make(map[NodeID]*peerUsage),
+	}
+}
+
+// checkQuota returns why sender may not store value at key, or nil. When the
+// node is full, keys ranked below the value by the eviction policy make room.
+// The caller must hold StorageMux.
+func (n *Node) checkQuota(sender NodeID, key NodeID, value []byte, cached bool) error {
+	current, exists := n.Storage[key]
+	growth := int64(len(value) - len(current))
+
+	// Overwrites stay charged to the peer that added the key
+	if !exists && sender != n.Self.ID {
+		usage := n.account.peers[sender]
+		if usage == nil {
+			usage = &peerUsage{}
+		}
+		if n.Limits.PeerMaxRecords > 0 && usage.records+1 > n.Limits.PeerMaxRecords {
+			return fmt.Errorf("peer key quota exceeded (%d keys)", n.Limits.PeerMaxRecords)
+		}
+		if n.Limits.PeerMaxBytes > 0 && usage.bytes+int64(len(value)) > n.Limits.PeerMaxBytes {
+			return fmt.Errorf("peer byte quota exceeded (%d bytes)", n.Limits.PeerMaxBytes)
+		}
+	}
+
+	if n.Limits.MaxBytes > 0 && growth > 0 && n.account.total+growth > n.Limits.MaxBytes {
+		if !n.evictFor(key, value, cached, n.account.total+growth-n.Limits.MaxBytes) {
+			n.evictions.Refused++
+			return fmt.Errorf("storage full (%d of %d bytes used)", n.account.total, n.Limits.MaxBytes)
+		}
+	}
+	return nil
+}
+
+// putLocked stores a value and updates the accounting. The caller must hold StorageMux.
+func (n *Node) putLocked(sender NodeID, key NodeID, value []byte) {
+	current, exists := n.Storage[key]
+	growth := int64(len(value) - len(current))
+
+	n.Storage[key] = value
+	n.account.total += growth
+	n.access.touch(key)
+
+	if !exists {
+		n.account.storedBy[key] = sender
+		usage := n.account.peers[sender]
+		if usage == nil {
+			usage = &peerUsage{}
+			n.account.peers[sender] = usage
+		}
+		usage.records++
+		usage.bytes += int64(len(value))
+	} else if usage := n.account.peers[n.account.storedBy[key]]; usage != nil {
+		usage.bytes += growth
+	}
+}
+
+// deleteLocked removes a value and updates the accounting. The caller must hold StorageMux.
+func (n *Node) deleteLocked(key NodeID) {
+	current, exists := n.Storage[key]
+	if !exists {
+		return
+	}
+	delete(n.Storage, key)
+	n.account.total -= int64(len(current))
+	n.access.forget(key)
+
+	peer, charged := n.account.storedBy[key]
+	if !charged {
+		return
+	}
+	delete(n.account.storedBy, key)
+	if usage := n.account.peers[peer]; usage != nil {
+		usage.records--
+		usage.bytes -= int64(len(current))
+		if usage.records <= 0 {
+			delete(n.account.peers, peer)
+		}
+	}
+}
+
+// chargeTombstone charges a tombstone for a key we do not hold to the peer that
+// sent it, like a STORE of a new key. Replacing a charged tombstone is free.
+// The caller must hold StorageMux.
+func (n *Node) chargeTombstone(sender NodeID, key NodeID) error {
+	if _, charged := n.account.tombstonedBy[key]; charged || sender == n.Self.ID {
+		return nil
+	}
+
+	usage := n.account.peers[sender]
+	if usage == nil {
+		usage = &peerUsage{}
+	}
+	if n.Limits.PeerMaxRecords > 0 && usage.records+1 > n.Limits.PeerMaxRecords {
+		return fmt.Errorf("peer key quota exceeded (%d keys)", n.Limits.PeerMaxRecords)
+	}
+	if n.Limits.PeerMaxBytes > 0 && usage.bytes+tombstoneBytes > n.Limits.PeerMaxBytes {
+		return fmt.Errorf("peer byte quota exceeded (%d bytes)", n.Limits.PeerMaxBytes)
+	}
+
+	n.account.peers[sender] = usage
+	usage.records++
+	usage.bytes += tombstoneBytes
+	n.account.tombstonedBy[key] = sender
+	return nil
+}
+
+// dropTombstoneLocked removes a tombstone and releases its charge. The caller must hold StorageMux.
+func (n *Node) dropTombstoneLocked(key NodeID) {
+	delete(n.Tombstones, key)
+
+	peer, charged := n.account.tombstonedBy[key]
+	if !charged {
+		return
+	}
+	delete(n.account.tombstonedBy, key)
+	if usage := n.account.peers[peer]; usage != nil {
+		usage.records--
+		usage.bytes -= tombstoneBytes
+		if usage.records <= 0 {
+			delete(n.account.peers, peer)
+		}
+	}
+}
+
+// GetStorageUsage returns the storage accounting of the node
+func (n *Node) GetStorageUsage() StorageUsage {
+	n.StorageMux.RLock()
+	defer n.StorageMux.RUnlock()
+	return StorageUsage{
+		Bytes:    n.account.total,
+		Keys:     len(n.Storage),
+		MaxBytes: n.Limits.MaxBytes,
+		Peers:    len(n.account.peers),
+	}
+}
+
+// --- Rate limiting ---
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter is a token bucket per peer
+type rateLimiter struct {
+	buckets map[NodeID]*tokenBucket
+	mutex   sync.Mutex
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{
+		buckets: make(map[NodeID]*tokenBucket),
+	}
+}
+
+// allow takes a token from a peer's bucket, refilled at rate per second up to burst
+func (rl *rateLimiter) allow(peer NodeID, rate float64, burst int, now time.Time) bool {
+	if rate <= 0 {
+		return true
+	}
+
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	b := rl.buckets[peer]
+	if b == nil {
+		// Forget peers whose buckets have refilled, so the map stays small
+		if len(rl.buckets) >= constants.RateLimiterPeers {
+			for id, other := range rl.buckets {
+				if now.Sub(other.last).Seconds()*rate >= float64(burst) {
+					delete(rl.buckets, id)
+				}
+			}
+		}
+		b = &tokenBucket{tokens: float64(burst), last: now}
+		rl.buckets[peer] = b
+	}
+
+	b.tokens += now.Sub(b.last).Seconds() * rate
+	if b.tokens > float64(burst) {
+		b.tokens = float64(burst)
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// allowStore checks the STORE rate limit of a sender; our own stores are never limited.
+// A leaving node refuses every STORE.
+func (n *Node) allowStore(sender Contact) error {
+	if sender.ID == n.Self.ID {
+		return nil
+	}
+	if n.leaving.Load() {
+		return errLeaving
+	}
+	if !n.storeRate.allow(sender.ID, n.Limits.PeerRate, n.Limits.PeerBurst, time.Now()) {
+		return fmt.Errorf("rate limited, too many STORE requests")
+	}
+	return nil
+}
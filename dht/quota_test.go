@@ -0,0 +1,111 @@
+package dht
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestStoreRefusalCarriesReason tests that a full node answers STORE with why it refused
+func TestStoreRefusalCarriesReason(t *testing.T) {
+	full := startHonestNode(t, idWithPrefix(0x01, 1))
+	writer := startHonestNode(t, idWithPrefix(0x80, 1))
+	full.Limits.MaxBytes = 10
+	full.Eviction = nil
+
+	if err := writer.Network.SendStore(full.Self, NodeID{1}, []byte("small"), nil); err != nil {
+		t.Fatalf("Store within the quota failed: %v", err)
+	}
+	err := writer.Network.SendStore(full.Self, NodeID{2}, []byte("too large"), nil)
+	if err == nil || !strings.Contains(err.Error(), "storage full") {
+		t.Fatalf("Expected a storage full refusal, got %v", err)
+	}
+
+	// Overwriting with a value of the same size needs no extra space
+	if err := writer.Network.SendStore(full.Self, NodeID{1}, []byte("other"), nil); err != nil {
+		t.Errorf("Overwrite within the quota failed: %v", err)
+	}
+	if usage := full.GetStorageUsage(); usage.Bytes != 5 || usage.Keys != 1 {
+		t.Errorf("Expected 5 bytes in 1 key, got %+v", usage)
+	}
+}
+
+// TestPeerQuotas tests the per-peer key and byte limits and that deletions free them
+func TestPeerQuotas(t *testing.T) {
+	node := startHonestNode(t, idWithPrefix(0x01, 1))
+	node.Limits.PeerMaxRecords = 2
+	node.Limits.PeerMaxBytes = 100
+	greedy := Contact{ID: idWithPrefix(0x30, 1)}
+	other := Contact{ID: idWithPrefix(0x31, 1)}
+
+	for i := byte(1); i <= 2; i++ {
+		if err := node.HandleStore(greedy, StoreRequest{Key: NodeID{i}, Value: []byte("v")}); err != nil {
+			t.Fatalf("Store %d failed: %v", i, err)
+		}
+	}
+	if err := node.HandleStore(greedy, StoreRequest{Key: NodeID{3}, Value: []byte("v")}); err == nil {
+		t.Fatalf("Expected the third key of a peer to be refused")
+	}
+	if err := node.HandleStore(other, StoreRequest{Key: NodeID{3}, Value: []byte("v")}); err != nil {
+		t.Fatalf("Another peer was refused: %v", err)
+	}
+	if err := node.HandleStore(other, StoreRequest{Key: NodeID{4}, Value: make([]byte, 101)}); err == nil {
+		t.Fatalf("Expected a value over the peer byte quota to be refused")
+	}
+
+	node.StorageMux.Lock()
+	node.deleteLocked(NodeID{1})
+	node.StorageMux.Unlock()
+	if err := node.HandleStore(greedy, StoreRequest{Key: NodeID{5}, Value: []byte("v")}); err != nil {
+		t.Errorf("Deleting a key did not free the peer quota: %v", err)
+	}
+}
+
+// TestStoreRateLimit tests the token bucket of a peer
+func TestStoreRateLimit(t *testing.T) {
+	rl := newRateLimiter()
+	peer := NodeID{1}
+	now := time.Unix(1_000_000, 0)
+
+	for i := 0; i < 3; i++ {
+		if !rl.allow(peer, 1, 3, now) {
+			t.Fatalf("Request %d of the burst was refused", i+1)
+		}
+	}
+	if rl.allow(peer, 1, 3, now) {
+		t.Fatalf("Expected a request over the burst to be refused")
+	}
+	if !rl.allow(NodeID{2}, 1, 3, now) {
+		t.Fatalf("Another peer was refused")
+	}
+	if !rl.allow(peer, 1, 3, now.Add(time.Second)) {
+		t.Errorf("Bucket did not refill")
+	}
+}
+
+// TestStoreFallsBackToNextNode tests that a replica refusing the value is replaced by the next closest node
+func TestStoreFallsBackToNextNode(t *testing.T) {
+	key := idWithPrefix(0x00, 1)
+	full := startHonestNode(t, idWithPrefix(0x01, 1))
+	r2 := startHonestNode(t, idWithPrefix(0x02, 1))
+	r3 := startHonestNode(t, idWithPrefix(0x03, 1))
+	spare := startHonestNode(t, idWithPrefix(0x04, 1))
+	writer := startHonestNode(t, idWithPrefix(0x80, 1))
+	connect(full, r2, r3, spare, writer)
+	full.Limits.MaxBytes = 1
+	full.Eviction = nil
+
+	value := []byte("needs a home")
+	if err := writer.Store(key, value); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	if hasKey(full, key, value) {
+		t.Errorf("Full node stored the value")
+	}
+	for _, n := range []*Node{r2, r3, spare} {
+		if !hasKey(n, key, value) {
+			t.Errorf("Node %x did not get the value", n.Self.ID[0])
+		}
+	}
+}
@@ -0,0 +1,270 @@
+package dht
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kutluhann/decentralized-file-sharing-system/constants"
+)
+
+// ---------------------------------------------------------
+// REPLICATION SCHEDULER
+// A single goroutine republishes every stored key once per
+// replication interval. Due keys are grouped by neighbourhood
+// (one lookup per group) and sent as batched STOREs per node.
+// The same goroutine triggers the anti-entropy rounds and the
+// re-announcement of provider records.
+// ---------------------------------------------------------
+
+// ReplicationScheduler tracks when each stored key is due for republishing
+type ReplicationScheduler struct {
+	node  *Node
+	due   map[NodeID]time.Time // Key -> next republish time
+	mutex sync.Mutex
+	stop  chan struct{}
+}
+
+func NewReplicationScheduler(node *Node) *ReplicationScheduler {
+	return &ReplicationScheduler{
+		node: node,
+		due:  make(map[NodeID]time.Time),
+	}
+}
+
+// Track schedules a key we just stored for republishing after a full interval.
+// It is also called when another replica STOREs the key to us: that replica has
+// just republished it, so we skip this round (the Kademlia optimization).
+func (rs *ReplicationScheduler) Track(key NodeID) {
+	rs.trackAt(key, time.Now())
+}
+
+func (rs *ReplicationScheduler) trackAt(key NodeID, now time.Time) {
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+	rs.due[key] = now.Add(rs.node.Config.ReplicationInterval)
+}
+
+// Forget stops republishing a key we no longer store
+func (rs *ReplicationScheduler) Forget(key NodeID) {
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+	delete(rs.due, key)
+}
+
+// Len returns the number of keys being republished
+func (rs *ReplicationScheduler) Len() int {
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+	return len(rs.due)
+}
+
+// Start runs the scheduler until Stop is called
+func (rs *ReplicationScheduler) Start() {
+	rs.mutex.Lock()
+	if rs.stop != nil {
+		rs.mutex.Unlock()
+		return
+	}
+	stop := make(chan struct{})
+	rs.stop = stop
+	rs.mutex.Unlock()
+
+	ticker := time.NewTicker(rs.node.Config.ReplicationCheckInterval)
+	go func() {
+		defer ticker.Stop()
+		lastSync := time.Now()
+		lastAnnounce := time.Now()
+		for {
+			select {
+			case now := <-ticker.C:
+				rs.republish(now)
+				rs.node.Providers.expireAt(now)
+
+				// Anti-entropy runs on its own, slower schedule
+				if now.Sub(lastSync) >= rs.node.Config.SyncInterval {
+					lastSync = now
+					go rs.node.SyncWithNeighbours()
+				}
+
+				// Provider records expire unless they are announced again
+				if now.Sub(lastAnnounce) >= rs.node.Config.ProviderInterval {
+					lastAnnounce = now
+					go rs.node.reannounce()
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	fmt.Printf("[REPLICATION] Scheduler started (republish every %s)\n", rs.node.Config.ReplicationInterval)
+}
+
+// Stop halts the scheduler goroutine
+func (rs *ReplicationScheduler) Stop() {
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+	if rs.stop != nil {
+		close(rs.stop)
+		rs.stop = nil
+	}
+}
+
+// takeDue returns the keys due at now and schedules their next round
+func (rs *ReplicationScheduler) takeDue(now time.Time) []NodeID {
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+
+	var keys []NodeID
+	for key, due := range rs.due {
+		if !now.Before(due) {
+			keys = append(keys, key)
+			rs.due[key] = now.Add(rs.node.Config.ReplicationInterval)
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].Less(keys[j])
+	})
+	return keys
+}
+
+// republish sends every due key to its k closest nodes
+func (rs *ReplicationScheduler) republish(now time.Time) {
+	n := rs.node
+	keys := rs.takeDue(now)
+	if len(keys) == 0 || n.Network == nil {
+		return
+	}
+
+	// 1. Collect the values and live tombstones, forgetting keys that are gone or are no longer replicas
+	values := make(map[NodeID][]byte, len(keys))
+	owners := make(map[NodeID][]byte, len(keys))
+	var tombstones []Tombstone
+	n.StorageMux.Lock()
+	for _, key := range keys {
+		if value, exists := n.Storage[key]; exists && n.isReplica(key) {
+			values[key] = value
+			owners[key] = n.Owners[key]
+		} else if n.tombstoned(key, nil) {
+			// Tombstones a peer sent for keys we did not hold are only kept until they expire
+			if _, charged := n.account.tombstonedBy[key]; !charged {
+				tombstones = append(tombstones, n.Tombstones[key])
+			}
+		} else {
+			rs.Forget(key)
+		}
+	}
+	n.StorageMux.Unlock()
+
+	rs.republishTombstones(tombstones)
+
+	// 2. Group keys whose k closest known nodes are the same, one lookup per group
+	groups := make(map[string][]NodeID)
+	var order []string
+	for _, key := range keys {
+		if _, exists := values[key]; !exists {
+			continue
+		}
+		sig := neighbourhood(n.RoutingTable.GetClosestNodes(key, n.Config.K))
+		if _, exists := groups[sig]; !exists {
+			order = append(order, sig)
+		}
+		groups[sig] = append(groups[sig], key)
+	}
+
+	// 3. Build one batch per destination node
+	batches := make(map[NodeID][]StoreRequest)
+	destinations := make(map[NodeID]Contact)
+	for _, sig := range order {
+		group := groups[sig]
+		closest, _ := n.NodeLookup(group[0])
+
+		for _, contact := range closest {
+			if contact.ID == n.Self.ID {
+				continue
+			}
+			destinations[contact.ID] = contact
+			for _, key := range group {
+				item := StoreRequest{Key: key, Value: values[key], Owner: owners[key]}
+				batches[contact.ID] = append(batches[contact.ID], item)
+			}
+		}
+	}
+
+	fmt.Printf("[REPLICATION] Republishing %d keys in %d neighbourhoods to %d nodes\n",
+		len(values), len(groups), len(destinations))
+
+	// 4. Send the batches, split to fit into UDP packets
+	for id, items := range batches {
+		contact := destinations[id]
+		for _, chunk := range chunkStoreItems(items, constants.ReplicationBatchBytes) {
+			if err := n.Network.SendStoreBatch(contact, chunk); err != nil {
+				fmt.Printf("[REPLICATION] ✗ Failed to send %d keys to %s: %v\n",
+					len(chunk), contact.ID.String()[:16], err)
+			}
+		}
+	}
+
+	// 5. Shards are not replicated, so the closest manifest holder checks them for losses
+	for _, key := range keys {
+		manifest, ok := ParseManifest(values[key])
+		if !ok || n.closestWithSelf(key)[0].ID != n.Self.ID {
+			continue
+		}
+		if _, err := n.RepairShards(manifest); err != nil {
+			fmt.Printf("[REPLICATION] ✗ Shard repair for %s failed: %v\n", key.String()[:16], err)
+		}
+	}
+}
+
+// republishTombstones sends each tombstone to the k closest nodes of its key
+func (rs *ReplicationScheduler) republishTombstones(tombstones []Tombstone) {
+	n := rs.node
+	for _, t := range tombstones {
+		closest, _ := n.NodeLookup(t.Key)
+		for _, contact := range closest {
+			if contact.ID == n.Self.ID {
+				continue
+			}
+			if err := n.Network.SendDelete(contact, t); err != nil {
+				fmt.Printf("[REPLICATION] ✗ Failed to send tombstone for %s to %s: %v\n",
+					t.Key.String()[:16], contact.ID.String()[:16], err)
+			}
+		}
+	}
+}
+
+// neighbourhood identifies a set of contacts independent of their order
+func neighbourhood(contacts []Contact) string {
+	ids := make([]string, len(contacts))
+	for i, c := range contacts {
+		ids[i] = c.ID.String()
+	}
+	sort.Strings(ids)
+	return strings.Join(ids, ",")
+}
+
+// chunkStoreItems splits items into batches whose values add up to at most maxBytes.
+// An item larger than maxBytes is sent in a batch of its own.
+func chunkStoreItems(items []StoreRequest, maxBytes int) [][]StoreRequest {
+	var chunks [][]StoreRequest
+	var current []StoreRequest
+	size := 0
+
+	for _, item := range items {
+		if len(current) > 0 && size+len(item.Value) > maxBytes {
+			chunks = append(chunks, current)
+			current, size = nil, 0
+		}
+		current = append(current, item)
+		size += len(item.Value)
+	}
+	if len(current) > 0 {
+		chunks = append(chunks, current)
+	}
+	return chunks
+}
@@ -0,0 +1,91 @@
+package dht
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kutluhann/decentralized-file-sharing-system/constants"
+)
+
+// TestReplicationSchedule tests that a tracked key becomes due after one interval
+func TestReplicationSchedule(t *testing.T) {
+	node := NewNode(Contact{ID: idWithPrefix(0x20, 1)}, nil)
+	testKey := NodeID{20, 19, 18, 17, 16, 15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1}
+	now := time.Unix(1_000_000, 0)
+	interval := constants.ReplicationInterval * time.Second
+
+	node.Replication.trackAt(testKey, now)
+
+	if keys := node.Replication.takeDue(now.Add(interval - time.Second)); len(keys) != 0 {
+		t.Fatalf("Key was due before the replication interval: %v", keys)
+	}
+	if keys := node.Replication.takeDue(now.Add(interval)); len(keys) != 1 || keys[0] != testKey {
+		t.Fatalf("Expected key to be due after the replication interval, got %v", keys)
+	}
+
+	// Taking a key reschedules it for the next round
+	if keys := node.Replication.takeDue(now.Add(interval)); len(keys) != 0 {
+		t.Fatalf("Key was due twice in the same round: %v", keys)
+	}
+}
+
+// TestReplicationSkipsRecentlyReceived tests that a STORE from another replica postpones republishing
+func TestReplicationSkipsRecentlyReceived(t *testing.T) {
+	node := NewNode(Contact{ID: idWithPrefix(0x20, 1)}, nil)
+	testKey := NodeID{1}
+	now := time.Now()
+
+	node.Replication.trackAt(testKey, now.Add(-constants.ReplicationInterval*time.Second))
+	node.HandleStore(Contact{ID: idWithPrefix(0x30, 1)}, StoreRequest{Key: testKey, Value: []byte("test value")})
+
+	if keys := node.Replication.takeDue(now); len(keys) != 0 {
+		t.Fatalf("Key received from another replica was republished anyway: %v", keys)
+	}
+}
+
+// TestReplicationBatchesByDestination tests that all due keys reach the k closest nodes in batches
+func TestReplicationBatchesByDestination(t *testing.T) {
+	// Only the scheduler may deliver the keys
+	node := startNodeWithoutHandoff(t, idWithPrefix(0x40, 1))
+	r1 := startHonestNode(t, idWithPrefix(0x01, 1))
+	r2 := startHonestNode(t, idWithPrefix(0x02, 1))
+	node.RoutingTable.Update(r1.Self)
+	node.RoutingTable.Update(r2.Self)
+
+	now := time.Now()
+	keys := []NodeID{idWithPrefix(0x00, 1), idWithPrefix(0x00, 2), idWithPrefix(0x00, 3)}
+	for _, key := range keys {
+		node.StorageMux.Lock()
+		node.Storage[key] = []byte("test value")
+		node.StorageMux.Unlock()
+		node.Replication.trackAt(key, now.Add(-constants.ReplicationInterval*time.Second))
+	}
+
+	node.Replication.republish(now)
+
+	for _, replica := range []*Node{r1, r2} {
+		for _, key := range keys {
+			if !hasKey(replica, key, []byte("test value")) {
+				t.Fatalf("Replica %s is missing key %s", replica.Self.ID.String()[:4], key.String()[:4])
+			}
+		}
+		if replica.Replication.Len() != len(keys) {
+			t.Errorf("Replica should track %d received keys, tracks %d", len(keys), replica.Replication.Len())
+		}
+	}
+}
+
+// TestChunkStoreItems tests that batches are split by value size
+func TestChunkStoreItems(t *testing.T) {
+	items := []StoreRequest{
+		{Value: make([]byte, 40)},
+		{Value: make([]byte, 40)},
+		{Value: make([]byte, 40)},
+		{Value: make([]byte, 200)},
+	}
+
+	chunks := chunkStoreItems(items, 100)
+	if len(chunks) != 3 || len(chunks[0]) != 2 || len(chunks[1]) != 1 || len(chunks[2]) != 1 {
+		t.Fatalf("Unexpected chunking: %d chunks", len(chunks))
+	}
+}
@@ -0,0 +1,211 @@
+package dht
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/kutluhann/decentralized-file-sharing-system/id_tools"
+)
+
+// ---------------------------------------------------------
+// KEY ROTATION
+// A node that replaces its identity key publishes a rotation
+// record at a key derived from its old PeerID. The old key signs
+// the move and the new key countersigns it, so nobody can claim
+// someone else's identity or redirect a peer to a key it does not
+// hold. A rotation record is final: replicas never replace it.
+// Grants and name lookups follow the chain to the current PeerID.
+// ---------------------------------------------------------
+
+const rotationFormat = "dfss-rotation-v1"
+
+// maxRotations bounds how many rotations a lookup follows
+const maxRotations = 16
+
+// RotationRecord is stored as JSON at the rotation key of the old PeerID
+type RotationRecord struct {
+	Format       string `json:"format"`  // Always rotationFormat, must stay the first field
+	OldKey       []byte `json:"old_key"` // PKIX public key being retired
+	NewKey       []byte `json:"new_key"` // PKIX public key replacing it
+	Timestamp    int64  `json:"timestamp"`
+	Signature    []byte `json:"signature"`     // Old key's signature over both keys and the timestamp
+	NewSignature []byte `json:"new_signature"` // New key's signature over the same message
+}
+
+// RotationKey returns the DHT key of the rotation record of peerID
+func RotationKey(peerID NodeID) NodeID {
+	return NodeID(sha256.Sum256(append([]byte("rotation:"), peerID[:]...)))
+}
+
+// ParseRotation returns the rotation record if value is one
+func ParseRotation(value []byte) (*RotationRecord, bool) {
+	if !bytes.HasPrefix(value, []byte(`{"format":"`+rotationFormat+`"`)) {
+		return nil, false
+	}
+
+	var record RotationRecord
+	if err := json.Unmarshal(value, &record); err != nil {
+		return nil, false
+	}
+	return &record, true
+}
+
+// rotationMessage returns the message both keys sign to rotate
+func rotationMessage(r *RotationRecord) string {
+	return fmt.Sprintf("ROTATE|%x|%x|%d", r.OldKey, r.NewKey, r.Timestamp)
+}
+
+// NewRotation returns the rotation record moving the identity of oldKey to newKey
+func NewRotation(oldKey, newKey *ecdsa.PrivateKey) (*RotationRecord, error) {
+	oldPub, err := x509.MarshalPKIXPublicKey(&oldKey.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	newPub, err := x509.MarshalPKIXPublicKey(&newKey.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Equal(oldPub, newPub) {
+		return nil, fmt.Errorf("old and new key are the same")
+	}
+
+	record := &RotationRecord{
+		Format:    rotationFormat,
+		OldKey:    oldPub,
+		NewKey:    newPub,
+		Timestamp: time.Now().Unix(),
+	}
+	record.Signature = id_tools.SignMessage(*oldKey, rotationMessage(record))
+	record.NewSignature = id_tools.SignMessage(*newKey, rotationMessage(record))
+	return record, nil
+}
+
+// OldPeerID returns the PeerID the record retires
+func (r *RotationRecord) OldPeerID() (NodeID, error) {
+	return peerIDOf(r.OldKey)
+}
+
+// NewPeerID returns the PeerID the record moves to
+func (r *RotationRecord) NewPeerID() (NodeID, error) {
+	return peerIDOf(r.NewKey)
+}
+
+// verifyRotation checks that a rotation record is signed by both keys and
+// stored at the rotation key of the old one
+func verifyRotation(key NodeID, r *RotationRecord) error {
+	oldPub, err := parseOwnerKey(r.OldKey)
+	if err != nil {
+		return err
+	}
+	newPub, err := parseOwnerKey(r.NewKey)
+	if err != nil {
+		return err
+	}
+
+	if RotationKey(NodeID(id_tools.GeneratePeerIDFromPublicKey(oldPub))) != key {
+		return fmt.Errorf("rotation does not belong to this key")
+	}
+	if !id_tools.VerifySignature(*oldPub, rotationMessage(r), r.Signature) {
+		return fmt.Errorf("invalid rotation signature of the old key")
+	}
+	if !id_tools.VerifySignature(*newPub, rotationMessage(r), r.NewSignature) {
+		return fmt.Errorf("invalid rotation signature of the new key")
+	}
+	return nil
+}
+
+// checkRotationUpdate refuses to replace a rotation record with anything else
+func checkRotationUpdate(current []byte, value []byte) error {
+	if _, rotated := ParseRotation(current); rotated && !bytes.Equal(current, value) {
+		return fmt.Errorf("a key rotation is final and cannot be overwritten")
+	}
+	return nil
+}
+
+// PublishRotation stores a rotation record at the rotation key of its old PeerID.
+// Returns: the number of other nodes that accepted the record, error
+func (n *Node) PublishRotation(record *RotationRecord) (int, error) {
+	oldPeerID, err := record.OldPeerID()
+	if err != nil {
+		return 0, err
+	}
+	newPeerID, err := record.NewPeerID()
+	if err != nil {
+		return 0, err
+	}
+	key := RotationKey(oldPeerID)
+	value, _ := json.Marshal(record)
+	if err := verifyRotation(key, record); err != nil {
+		return 0, err
+	}
+
+	fmt.Printf("[IDENTITY] Publishing rotation %s -> %s\n", oldPeerID.String()[:16], newPeerID.String()[:16])
+	return n.storeReplicas(key, value)
+}
+
+// findRotation returns the rotation record of peerID, or nil if it never rotated.
+// A rotation record proves itself, so one copy that verifies is enough, even if
+// the other paths of the lookup did not find it. Anything else stored at the
+// rotation key is ignored.
+func (n *Node) findRotation(peerID NodeID) *RotationRecord {
+	key := RotationKey(peerID)
+	verified := func(value []byte) *RotationRecord {
+		record, ok := ParseRotation(value)
+		if !ok || verifyRotation(key, record) != nil {
+			return nil
+		}
+		return record
+	}
+
+	if local, exists := n.getLocal(key); exists {
+		if record := verified(local); record != nil {
+			return record
+		}
+	}
+	for _, r := range n.disjointLookup("ROTATION", key, n.Network.SendFindValue) {
+		if record := verified(r.Value); record != nil {
+			return record
+		}
+	}
+	return nil
+}
+
+// FollowRotations returns the PeerID that peerID rotated to, following the
+// chain of rotations, or peerID itself when it never rotated
+func (n *Node) FollowRotations(peerID NodeID) (NodeID, error) {
+	chain, err := n.rotationChain(peerID)
+	if err != nil {
+		return NodeID{}, err
+	}
+	return chain[len(chain)-1], nil
+}
+
+// rotationChain returns peerID followed by every PeerID it rotated to, in order.
+// When the chain cannot be followed to its end, the PeerIDs found so far are
+// returned with the error.
+func (n *Node) rotationChain(peerID NodeID) ([]NodeID, error) {
+	chain := []NodeID{peerID}
+	seen := map[NodeID]bool{peerID: true}
+	for i := 0; i < maxRotations; i++ {
+		record := n.findRotation(chain[len(chain)-1])
+		if record == nil {
+			return chain, nil
+		}
+
+		next, err := record.NewPeerID()
+		if err != nil {
+			return chain, err
+		}
+		if seen[next] {
+			return chain, fmt.Errorf("rotations of %s form a cycle", peerID.String()[:16])
+		}
+		seen[next] = true
+		chain = append(chain, next)
+	}
+	return chain, fmt.Errorf("%s rotated more than %d times", peerID.String()[:16], maxRotations)
+}
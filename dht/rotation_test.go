@@ -0,0 +1,209 @@
+package dht
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/kutluhann/decentralized-file-sharing-system/encryption"
+	"github.com/kutluhann/decentralized-file-sharing-system/id_tools"
+)
+
+// TestGrantFollowsRotation tests that a grant to a retired PeerID reaches the key it
+// rotated to, and that revoking the retired PeerID revokes that key
+func TestGrantFollowsRotation(t *testing.T) {
+	owner := startOwnerNode(t, idWithPrefix(0x80, 1))
+	alice := startOwnerNode(t, idWithPrefix(0x40, 1))
+	r1 := startOwnerNode(t, idWithPrefix(0x01, 1))
+	r2 := startOwnerNode(t, idWithPrefix(0x02, 1))
+	connect(owner, alice, r1, r2)
+
+	oldPeerID := mustPeerID(t, alice)
+	newKey, _ := id_tools.GenerateNewPID()
+	record, err := NewRotation(alice.PrivKey, newKey)
+	if err != nil {
+		t.Fatalf("NewRotation failed: %v", err)
+	}
+	alice.PrivKey = newKey
+	if err := alice.PublishIdentity(); err != nil {
+		t.Fatalf("PublishIdentity failed: %v", err)
+	}
+	if replicas, err := alice.PublishRotation(record); err != nil || replicas == 0 {
+		t.Fatalf("PublishRotation returned %d replicas, %v", replicas, err)
+	}
+
+	if current, err := owner.FollowRotations(oldPeerID); err != nil || current != mustPeerID(t, alice) {
+		t.Fatalf("Expected the rotation to be followed, got %s, %v", current.String()[:16], err)
+	}
+
+	key := idWithPrefix(0x00, 1)
+	plaintext := []byte("still for alice")
+	if err := owner.StoreEncrypted(key, plaintext, nil, false); err != nil {
+		t.Fatalf("StoreEncrypted failed: %v", err)
+	}
+	if err := owner.Grant(key, oldPeerID); err != nil {
+		t.Fatalf("Grant failed: %v", err)
+	}
+	if value, _, err := alice.Retrieve(key); err != nil || !bytes.Equal(value, plaintext) {
+		t.Fatalf("Rotated peer could not read: %q, %v", value, err)
+	}
+
+	// Revoking the retired PeerID removes the grant of the current one
+	if err := owner.Revoke(key, oldPeerID); err != nil {
+		t.Fatalf("Revoke failed: %v", err)
+	}
+	if _, _, err := alice.Retrieve(key); !errors.Is(err, encryption.ErrNotRecipient) {
+		t.Errorf("Expected the rotated peer to lose access, got %v", err)
+	}
+}
+
+// TestRotationIsFinal tests that replicas refuse forged rotations and never replace a stored one
+func TestRotationIsFinal(t *testing.T) {
+	owner := startOwnerNode(t, idWithPrefix(0x80, 1))
+	attacker := startOwnerNode(t, idWithPrefix(0x40, 1))
+	replica := startHonestNode(t, idWithPrefix(0x01, 1))
+
+	key := RotationKey(mustPeerID(t, owner))
+
+	// Moving someone else's identity needs their old key
+	forged, _ := NewRotation(attacker.PrivKey, owner.PrivKey)
+	forged.OldKey = owner.ownerKey()
+	value, _ := json.Marshal(forged)
+	replica.HandleStore(attacker.Self, StoreRequest{Key: key, Value: value})
+	if hasKey(replica, key, value) {
+		t.Fatalf("Forged rotation was stored")
+	}
+
+	// Both keys have to sign, the old key alone cannot point to a key it does not hold
+	newKey, _ := id_tools.GenerateNewPID()
+	unsigned, _ := NewRotation(owner.PrivKey, newKey)
+	unsigned.NewSignature = unsigned.Signature
+	value, _ = json.Marshal(unsigned)
+	replica.HandleStore(owner.Self, StoreRequest{Key: key, Value: value})
+	if hasKey(replica, key, value) {
+		t.Fatalf("Rotation without the new key's signature was stored")
+	}
+
+	first, _ := NewRotation(owner.PrivKey, newKey)
+	firstValue, _ := json.Marshal(first)
+	replica.HandleStore(owner.Self, StoreRequest{Key: key, Value: firstValue})
+
+	otherKey, _ := id_tools.GenerateNewPID()
+	second, _ := NewRotation(owner.PrivKey, otherKey)
+	value, _ = json.Marshal(second)
+	replica.HandleStore(owner.Self, StoreRequest{Key: key, Value: value})
+
+	if !hasKey(replica, key, firstValue) {
+		t.Fatalf("Stored rotation was replaced")
+	}
+}
+
+// TestRotationIgnoresJunk tests that a value at a rotation key that is not a valid
+// rotation does not break grants, revocations or names, and that a rotation held by
+// a single replica is still followed
+func TestRotationIgnoresJunk(t *testing.T) {
+	owner := startOwnerNode(t, idWithPrefix(0x80, 1))
+	alice := startOwnerNode(t, idWithPrefix(0x40, 1))
+	attacker := startOwnerNode(t, idWithPrefix(0x20, 1))
+	r1 := startOwnerNode(t, idWithPrefix(0x01, 1))
+	r2 := startOwnerNode(t, idWithPrefix(0x02, 1))
+	connect(owner, alice, attacker, r1, r2)
+
+	alicePeerID := mustPeerID(t, alice)
+	if err := alice.PublishIdentity(); err != nil {
+		t.Fatalf("PublishIdentity failed: %v", err)
+	}
+	for _, replica := range []*Node{owner, alice, r1, r2} {
+		replica.HandleStore(attacker.Self, StoreRequest{Key: RotationKey(alicePeerID), Value: []byte("junk")})
+	}
+	if current, err := owner.FollowRotations(alicePeerID); err != nil || current != alicePeerID {
+		t.Fatalf("Expected junk to count as no rotation, got %s, %v", current.String()[:16], err)
+	}
+
+	key := idWithPrefix(0x00, 1)
+	plaintext := []byte("for alice")
+	if err := owner.StoreEncrypted(key, plaintext, nil, false); err != nil {
+		t.Fatalf("StoreEncrypted failed: %v", err)
+	}
+	if err := owner.Grant(key, alicePeerID); err != nil {
+		t.Fatalf("Grant failed: %v", err)
+	}
+	if err := owner.Revoke(key, alicePeerID); err != nil {
+		t.Fatalf("Revoke failed: %v", err)
+	}
+	if _, _, err := alice.Retrieve(key); !errors.Is(err, encryption.ErrNotRecipient) {
+		t.Errorf("Expected alice to lose access, got %v", err)
+	}
+
+	target := idWithPrefix(0x00, 2)
+	if _, err := alice.PublishName("site", target); err != nil {
+		t.Fatalf("PublishName failed: %v", err)
+	}
+	if record, _, err := owner.ResolveName(alicePeerID, "site"); err != nil || record.Target != target {
+		t.Fatalf("ResolveName returned %v, %v", record, err)
+	}
+
+	// A rotation on one replica only is followed, it proves itself
+	oldPeerID := mustPeerID(t, r2)
+	newKey, _ := id_tools.GenerateNewPID()
+	rotation, _ := NewRotation(r2.PrivKey, newKey)
+	value, _ := json.Marshal(rotation)
+	if err := r1.HandleStore(r2.Self, StoreRequest{Key: RotationKey(oldPeerID), Value: value}); err != nil {
+		t.Fatalf("Rotation was refused: %v", err)
+	}
+	newPeerID, _ := rotation.NewPeerID()
+	if current, err := owner.FollowRotations(oldPeerID); err != nil || current != newPeerID {
+		t.Errorf("Expected the rotation on one replica to be followed, got %s, %v", current.String()[:16], err)
+	}
+}
+
+// TestRevokeWholeRotationChain tests that revoking a PeerID removes the keys
+// wrapped for every PeerID it rotated through
+func TestRevokeWholeRotationChain(t *testing.T) {
+	owner := startOwnerNode(t, idWithPrefix(0x80, 1))
+	alice := startOwnerNode(t, idWithPrefix(0x40, 1))
+	r1 := startOwnerNode(t, idWithPrefix(0x01, 1))
+	r2 := startOwnerNode(t, idWithPrefix(0x02, 1))
+	connect(owner, alice, r1, r2)
+
+	rotate := func() {
+		t.Helper()
+		newKey, _ := id_tools.GenerateNewPID()
+		record, _ := NewRotation(alice.PrivKey, newKey)
+		alice.PrivKey = newKey
+		if err := alice.PublishIdentity(); err != nil {
+			t.Fatalf("PublishIdentity failed: %v", err)
+		}
+		if _, err := alice.PublishRotation(record); err != nil {
+			t.Fatalf("PublishRotation failed: %v", err)
+		}
+	}
+
+	key := idWithPrefix(0x00, 1)
+	if err := owner.StoreEncrypted(key, []byte("for alice"), nil, false); err != nil {
+		t.Fatalf("StoreEncrypted failed: %v", err)
+	}
+
+	// Alice is granted under each PeerID she rotates to: A -> B, then A -> B -> C
+	first := mustPeerID(t, alice)
+	rotate()
+	if err := owner.Grant(key, first); err != nil {
+		t.Fatalf("Grant failed: %v", err)
+	}
+	rotate()
+	if err := owner.Grant(key, first); err != nil {
+		t.Fatalf("Grant failed: %v", err)
+	}
+
+	if err := owner.Revoke(key, first); err != nil {
+		t.Fatalf("Revoke failed: %v", err)
+	}
+	manifest, err := owner.ownManifest(key)
+	if err != nil {
+		t.Fatalf("Manifest not found: %v", err)
+	}
+	if len(manifest.Keys) != 1 || manifest.Keys[0].PeerID != mustPeerID(t, owner) {
+		t.Errorf("Expected only the owner's key to remain, got %d keys", len(manifest.Keys))
+	}
+}
@@ -8,19 +8,19 @@ import (
 )
 
 type RoutingTable struct {
-	Self    Contact
-	Buckets [constants.KeySizeBytes * 8]*Bucket
-	mutex   sync.RWMutex
+	Self         Contact
+	Buckets      [constants.KeySizeBytes * 8]*Bucket
+	OnNewContact func(contact Contact) // Called in its own goroutine the first time a contact is added
+	mutex        sync.RWMutex
 }
 
-func NewRoutingTable(self Contact) *RoutingTable {
+// NewRoutingTable creates a routing table whose buckets hold k contacts each
+func NewRoutingTable(self Contact, k int) *RoutingTable {
 	rt := &RoutingTable{
 		Self: self,
 	}
 	for i := 0; i < len(rt.Buckets); i++ {
-		rt.Buckets[i] = &Bucket{
-			contacts: make([]Contact, 0, constants.K),
-		}
+		rt.Buckets[i] = NewBucket(k)
 	}
 	return rt
 }
@@ -34,10 +34,21 @@ func (rt *RoutingTable) GetBucketIndex(targetID NodeID) int {
 }
 
 func (rt *RoutingTable) Update(contact Contact) {
+	// A lookup can reach ourselves through the contacts of a peer
+	if contact.ID == rt.Self.ID {
+		return
+	}
 	bucketIndex := rt.GetBucketIndex(contact.ID)
 
 	bucket := rt.Buckets[bucketIndex]
-	bucket.Update(contact)
+	if bucket.Update(contact) && rt.OnNewContact != nil {
+		go rt.OnNewContact(contact)
+	}
+}
+
+// Remove drops a contact whose ID and address both match
+func (rt *RoutingTable) Remove(contact Contact) bool {
+	return rt.Buckets[rt.GetBucketIndex(contact.ID)].Remove(contact)
 }
 
 func (rt *RoutingTable) GetClosestNodes(targetID NodeID, count int) []Contact {
@@ -49,23 +60,15 @@ func (rt *RoutingTable) GetClosestNodes(targetID NodeID, count int) []Contact {
 	bucketIndex := rt.GetBucketIndex(targetID)
 	bucket := rt.Buckets[bucketIndex]
 
-	bucket.mutex.RLock()
 	nodes = append(nodes, bucket.GetContacts()...)
-	bucket.mutex.RUnlock()
 
 	for i := 1; len(nodes) < count && ((bucketIndex-i >= 0) || (bucketIndex+i < len(rt.Buckets))); i++ {
 		if bucketIndex-i >= 0 {
-			b := rt.Buckets[bucketIndex-i]
-			b.mutex.RLock()
-			nodes = append(nodes, b.GetContacts()...)
-			b.mutex.RUnlock()
+			nodes = append(nodes, rt.Buckets[bucketIndex-i].GetContacts()...)
 		}
 
 		if bucketIndex+i < len(rt.Buckets) {
-			b := rt.Buckets[bucketIndex+i]
-			b.mutex.RLock()
-			nodes = append(nodes, b.GetContacts()...)
-			b.mutex.RUnlock()
+			nodes = append(nodes, rt.Buckets[bucketIndex+i].GetContacts()...)
 		}
 	}
 
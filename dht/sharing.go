@@ -0,0 +1,389 @@
+package dht
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kutluhann/decentralized-file-sharing-system/encryption"
+	"github.com/kutluhann/decentralized-file-sharing-system/id_tools"
+)
+
+// ---------------------------------------------------------
+// SHARING
+// The owner of an encrypted value grants a peer read access by
+// wrapping the content key for it and publishing a new manifest
+// version. Revoking re-encrypts the value under a new content key
+// wrapped for the remaining recipients only, since the revoked
+// peer may have kept the old one. Grants are announced with a
+// signed notice in the recipient's inbox, a set that replicas
+// merge instead of overwrite. Notices expire, so revoked grants
+// and notices of strangers leave the inbox over time.
+// ---------------------------------------------------------
+
+const inboxFormat = "dfss-inbox-v1"
+
+// ShareNotice tells a recipient that the owner shared the value at File with it
+type ShareNotice struct {
+	File      NodeID `json:"file"`
+	Owner     []byte `json:"owner"`     // PKIX public key of the owner
+	Expires   int64  `json:"expires"`   // Unix time after which the notice is dropped
+	Signature []byte `json:"signature"` // Owner's signature over file, recipient and expiry
+}
+
+// Inbox is stored at the inbox key of a recipient and collects its share notices
+type Inbox struct {
+	Format    string        `json:"format"` // Always inboxFormat, must stay the first field
+	Recipient NodeID        `json:"recipient"`
+	Notices   []ShareNotice `json:"notices"`
+}
+
+// SharedFile is a value another peer shared with us
+type SharedFile struct {
+	Key     NodeID `json:"key"`
+	Owner   NodeID `json:"owner"` // PeerID of the owner
+	Size    int    `json:"size"`
+	Version uint64 `json:"version"`
+}
+
+// InboxKey returns the DHT key of the inbox of peerID
+func InboxKey(peerID NodeID) NodeID {
+	return NodeID(sha256.Sum256(append([]byte("inbox:"), peerID[:]...)))
+}
+
+// ParseInbox returns the inbox if value is one
+func ParseInbox(value []byte) (*Inbox, bool) {
+	if !bytes.HasPrefix(value, []byte(`{"format":"`+inboxFormat+`"`)) {
+		return nil, false
+	}
+
+	var inbox Inbox
+	if err := json.Unmarshal(value, &inbox); err != nil {
+		return nil, false
+	}
+	return &inbox, true
+}
+
+// noticeMessage returns the message an owner signs to notify recipient of a shared value
+func noticeMessage(recipient NodeID, notice ShareNotice) string {
+	return fmt.Sprintf("NOTICE|%s|%s|%d", recipient.String(), notice.File.String(), notice.Expires)
+}
+
+// verifyInbox checks that an inbox is stored at its recipient's inbox key, stays
+// within the notice limits, and that every notice is signed. Expired notices are
+// allowed, they are dropped on the next merge.
+func (n *Node) verifyInbox(key NodeID, inbox *Inbox) error {
+	if InboxKey(inbox.Recipient) != key {
+		return fmt.Errorf("inbox does not belong to this key")
+	}
+	if len(inbox.Notices) > n.Config.MaxInboxNotices {
+		return fmt.Errorf("inbox holds more than %d notices", n.Config.MaxInboxNotices)
+	}
+	latest := time.Now().Add(n.Config.InboxNoticeTTL + time.Minute).Unix()
+	perOwner := make(map[string]int)
+	for _, notice := range inbox.Notices {
+		if notice.Expires > latest {
+			return fmt.Errorf("notice expires too late")
+		}
+		if perOwner[string(notice.Owner)]++; perOwner[string(notice.Owner)] > n.Config.MaxOwnerNotices {
+			return fmt.Errorf("inbox holds more than %d notices of one owner", n.Config.MaxOwnerNotices)
+		}
+		ownerKey, err := parseOwnerKey(notice.Owner)
+		if err != nil {
+			return err
+		}
+		if !id_tools.VerifySignature(*ownerKey, noticeMessage(inbox.Recipient, notice), notice.Signature) {
+			return fmt.Errorf("invalid notice signature")
+		}
+	}
+	return nil
+}
+
+// mergeInbox adds the notices of an incoming inbox to the current one and drops
+// expired ones. Values that are not inboxes are returned unchanged. A full inbox
+// keeps the newest notices. Both inboxes must be verified for the same key.
+func (n *Node) mergeInbox(current []byte, value []byte) []byte {
+	incoming, ok := ParseInbox(value)
+	if !ok {
+		return value
+	}
+	existing, ok := ParseInbox(current)
+	if !ok {
+		existing = &Inbox{Format: inboxFormat, Recipient: incoming.Recipient}
+	}
+
+	existing.Notices = n.pruneNotices(append(existing.Notices, incoming.Notices...), time.Now())
+	mergedBytes, _ := json.Marshal(existing)
+	if bytes.Equal(mergedBytes, current) {
+		return current
+	}
+	return mergedBytes
+}
+
+// pruneNotices drops expired notices and older copies of the same notice, then
+// keeps the MaxOwnerNotices newest of each owner and the MaxInboxNotices newest overall
+func (n *Node) pruneNotices(notices []ShareNotice, now time.Time) []ShareNotice {
+	sort.SliceStable(notices, func(i, j int) bool { return notices[i].Expires > notices[j].Expires })
+
+	kept := make([]ShareNotice, 0, min(len(notices), n.Config.MaxInboxNotices))
+	perOwner := make(map[string]int)
+	for _, notice := range notices {
+		if len(kept) == n.Config.MaxInboxNotices {
+			break
+		}
+		if now.Unix() >= notice.Expires || containsNotice(kept, notice) ||
+			perOwner[string(notice.Owner)] == n.Config.MaxOwnerNotices {
+			continue
+		}
+		perOwner[string(notice.Owner)]++
+		kept = append(kept, notice)
+	}
+	sortNotices(kept)
+	return kept
+}
+
+func containsNotice(notices []ShareNotice, notice ShareNotice) bool {
+	for _, n := range notices {
+		if n.File == notice.File && bytes.Equal(n.Owner, notice.Owner) {
+			return true
+		}
+	}
+	return false
+}
+
+// sortNotices gives merged inboxes a canonical order, so replicas holding the same notices store the same bytes
+func sortNotices(notices []ShareNotice) {
+	sort.Slice(notices, func(i, j int) bool {
+		if notices[i].File != notices[j].File {
+			return notices[i].File.Less(notices[j].File)
+		}
+		return bytes.Compare(notices[i].Owner, notices[j].Owner) < 0
+	})
+}
+
+// --- Client side ---
+
+// ownManifest finds the manifest at key and checks that we own it
+func (n *Node) ownManifest(key NodeID) (*EncryptedManifest, error) {
+	if n.PrivKey == nil {
+		return nil, fmt.Errorf("node has no identity key")
+	}
+
+	value, _, err := n.FindValue(key)
+	if err != nil {
+		return nil, err
+	}
+	manifest, ok := ParseEncryptedManifest(value)
+	if !ok {
+		return nil, fmt.Errorf("value is not encrypted")
+	}
+	if !bytes.Equal(manifest.Owner, n.ownerKey()) {
+		return nil, fmt.Errorf("value belongs to another owner")
+	}
+	return manifest, nil
+}
+
+// Grant gives peerID read access to the encrypted value at key and drops a notice in its inbox.
+// A peer that rotated its key is granted access under its current PeerID. Granting
+// again renews the notice, which expires after Config.InboxNoticeTTL.
+func (n *Node) Grant(key NodeID, peerID NodeID) error {
+	manifest, err := n.ownManifest(key)
+	if err != nil {
+		return err
+	}
+	if peerID, err = n.FollowRotations(peerID); err != nil {
+		return err
+	}
+	for _, k := range manifest.Keys {
+		if k.PeerID == peerID {
+			// Already granted, renew the notice before it expires
+			return n.notify(peerID, key)
+		}
+	}
+
+	recipient, err := n.LookupPublicKey(peerID)
+	if err != nil {
+		return err
+	}
+	contentKey, err := n.contentKey(manifest)
+	if err != nil {
+		return err
+	}
+	keys, err := wrapFor(contentKey, []*ecdsa.PublicKey{recipient})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("[SHARE] Granting %s access to key %s\n", peerID.String()[:16], key.String()[:16])
+
+	manifest.Keys = append(manifest.Keys, keys...)
+	if err := n.publishManifest(key, manifest, manifest.Version+1); err != nil {
+		return err
+	}
+	return n.notify(peerID, key)
+}
+
+// Revoke removes the read access of peerID to the encrypted value at key. The value
+// is re-encrypted under a new random content key and the old ciphertext is deleted.
+// A convergent value stops being deduplicated, since its key is known to the
+// revoked peer, and its old ciphertext is kept for the other manifests using it.
+// A peer that rotated its key loses access under every PeerID it rotated through.
+func (n *Node) Revoke(key NodeID, peerID NodeID) error {
+	manifest, err := n.ownManifest(key)
+	if err != nil {
+		return err
+	}
+
+	// A broken chain of rotations must not keep us from revoking the PeerIDs we know
+	chain, err := n.rotationChain(peerID)
+	if err != nil {
+		fmt.Printf("[SHARE] ✗ Failed to follow the rotations of %s: %v\n", peerID.String()[:16], err)
+	}
+	revoked := make(map[NodeID]bool, len(chain))
+	for _, id := range chain {
+		revoked[id] = true
+	}
+
+	var remaining []*ecdsa.PublicKey
+	for _, k := range manifest.Keys {
+		if revoked[k.PeerID] {
+			continue
+		}
+		recipient, err := parseOwnerKey(k.Key.Recipient)
+		if err != nil {
+			return err
+		}
+		remaining = append(remaining, recipient)
+	}
+	if len(remaining) == len(manifest.Keys) {
+		return fmt.Errorf("%s has no access to this value", peerID.String()[:16])
+	}
+
+	value, _, err := n.openEncrypted(manifest)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("[SHARE] Revoking access of %s to key %s, re-keying\n", peerID.String()[:16], key.String()[:16])
+
+	contentKey, ciphertext, err := encryption.Seal(value)
+	if err != nil {
+		return err
+	}
+	keys, err := wrapFor(contentKey, remaining)
+	if err != nil {
+		return err
+	}
+	ciphertextKey, err := n.storeCiphertext(ciphertext)
+	if err != nil {
+		return err
+	}
+
+	old, shared := manifest.Ciphertext, manifest.Convergent
+	manifest.Ciphertext = ciphertextKey
+	manifest.Keys = keys
+	manifest.Convergent = false
+	if err := n.publishManifest(key, manifest, manifest.Version+1); err != nil {
+		return err
+	}
+
+	if shared {
+		return nil
+	}
+	if _, err := n.deleteKey(old); err != nil {
+		fmt.Printf("[SHARE] ✗ Failed to delete the old ciphertext: %v\n", err)
+	}
+	return nil
+}
+
+// notify drops a signed share notice for key in the inbox of peerID
+func (n *Node) notify(peerID NodeID, key NodeID) error {
+	notice := ShareNotice{
+		File:    key,
+		Owner:   n.ownerKey(),
+		Expires: time.Now().Add(n.Config.InboxNoticeTTL).Unix(),
+	}
+	notice.Signature = id_tools.SignMessage(*n.PrivKey, noticeMessage(peerID, notice))
+
+	value, _ := json.Marshal(Inbox{Format: inboxFormat, Recipient: peerID, Notices: []ShareNotice{notice}})
+	return n.Store(InboxKey(peerID), value)
+}
+
+// SharedWithMe lists the values other peers currently share with us. The notices of our
+// inbox are merged from its k closest nodes and checked against the current manifests.
+func (n *Node) SharedWithMe() ([]SharedFile, error) {
+	peerID, err := n.PeerID()
+	if err != nil {
+		return nil, err
+	}
+	key := InboxKey(peerID)
+
+	inbox, _ := n.getLocal(key)
+	closest, _ := n.NodeLookup(key)
+	values := make([][]byte, len(closest))
+	var wg sync.WaitGroup
+	for i, c := range closest {
+		if c.ID == n.Self.ID {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, c Contact) {
+			defer wg.Done()
+			values[i], _, _ = n.Network.SendFindValue(c, key)
+		}(i, c)
+	}
+	wg.Wait()
+
+	for _, value := range values {
+		if received, ok := ParseInbox(value); !ok || n.verifyInbox(key, received) != nil {
+			continue
+		}
+		if inbox == nil {
+			inbox = value
+			continue
+		}
+		inbox = n.mergeInbox(inbox, value)
+	}
+
+	parsed, ok := ParseInbox(inbox)
+	if !ok {
+		return nil, nil
+	}
+
+	var shared []SharedFile
+	now := time.Now().Unix()
+	for _, notice := range parsed.Notices {
+		if now >= notice.Expires {
+			continue
+		}
+		value, _, err := n.FindValue(notice.File)
+		if err != nil {
+			continue
+		}
+		manifest, ok := ParseEncryptedManifest(value)
+		if !ok || !bytes.Equal(manifest.Owner, notice.Owner) {
+			continue
+		}
+		for _, k := range manifest.Keys {
+			if k.PeerID != peerID {
+				continue
+			}
+			owner, _ := peerIDOf(manifest.Owner)
+			shared = append(shared, SharedFile{
+				Key:     notice.File,
+				Owner:   owner,
+				Size:    manifest.Size,
+				Version: manifest.Version,
+			})
+			break
+		}
+	}
+
+	fmt.Printf("[SHARE] %d values shared with us\n", len(shared))
+	return shared, nil
+}
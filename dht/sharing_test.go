@@ -0,0 +1,217 @@
+package dht
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kutluhann/decentralized-file-sharing-system/constants"
+	"github.com/kutluhann/decentralized-file-sharing-system/encryption"
+	"github.com/kutluhann/decentralized-file-sharing-system/id_tools"
+)
+
+// mustPeerID returns the PeerID of a node with an identity key
+func mustPeerID(t *testing.T, n *Node) NodeID {
+	t.Helper()
+	peerID, err := n.PeerID()
+	if err != nil {
+		t.Fatalf("PeerID failed: %v", err)
+	}
+	return peerID
+}
+
+// TestGrantAndRevoke tests that granted peers can read and list a value and revoked ones no longer can
+func TestGrantAndRevoke(t *testing.T) {
+	owner := startOwnerNode(t, idWithPrefix(0x80, 1))
+	alice := startOwnerNode(t, idWithPrefix(0x40, 1))
+	bob := startOwnerNode(t, idWithPrefix(0xC0, 1))
+	r1 := startOwnerNode(t, idWithPrefix(0x01, 1))
+	r2 := startOwnerNode(t, idWithPrefix(0x02, 1))
+	connect(owner, alice, bob, r1, r2)
+
+	for _, n := range []*Node{alice, bob} {
+		if err := n.PublishIdentity(); err != nil {
+			t.Fatalf("PublishIdentity failed: %v", err)
+		}
+	}
+
+	key := idWithPrefix(0x00, 1)
+	plaintext := []byte("team notes")
+	if err := owner.StoreEncrypted(key, plaintext, nil, false); err != nil {
+		t.Fatalf("StoreEncrypted failed: %v", err)
+	}
+	if _, _, err := alice.Retrieve(key); !errors.Is(err, encryption.ErrNotRecipient) {
+		t.Fatalf("Expected no access before the grant, got %v", err)
+	}
+
+	for _, n := range []*Node{alice, bob} {
+		if err := owner.Grant(key, mustPeerID(t, n)); err != nil {
+			t.Fatalf("Grant failed: %v", err)
+		}
+	}
+	for _, n := range []*Node{alice, bob} {
+		if value, _, err := n.Retrieve(key); err != nil || !bytes.Equal(value, plaintext) {
+			t.Fatalf("Granted peer could not read: %q, %v", value, err)
+		}
+	}
+
+	shared, err := bob.SharedWithMe()
+	if err != nil || len(shared) != 1 || shared[0].Key != key || shared[0].Owner != mustPeerID(t, owner) {
+		t.Fatalf("Expected the value in bob's shared list, got %+v, %v", shared, err)
+	}
+
+	value, _, _ := owner.FindValue(key)
+	before, _ := ParseEncryptedManifest(value)
+
+	if err := owner.Revoke(key, mustPeerID(t, bob)); err != nil {
+		t.Fatalf("Revoke failed: %v", err)
+	}
+
+	if _, _, err := bob.Retrieve(key); !errors.Is(err, encryption.ErrNotRecipient) {
+		t.Errorf("Expected bob to lose access, got %v", err)
+	}
+	if value, _, err := alice.Retrieve(key); err != nil || !bytes.Equal(value, plaintext) {
+		t.Errorf("Alice lost access: %q, %v", value, err)
+	}
+	if _, _, err := bob.FindValue(before.Ciphertext); err == nil {
+		t.Errorf("Old ciphertext is still available")
+	}
+	if shared, _ := bob.SharedWithMe(); len(shared) != 0 {
+		t.Errorf("Expected bob's shared list to be empty, got %+v", shared)
+	}
+}
+
+// TestManifestUpdatesAreChecked tests that replicas only accept newer manifests from the same owner
+func TestManifestUpdatesAreChecked(t *testing.T) {
+	owner := startOwnerNode(t, idWithPrefix(0x80, 1))
+	attacker := startOwnerNode(t, idWithPrefix(0x40, 1))
+	replica := startHonestNode(t, idWithPrefix(0x01, 1))
+
+	key := idWithPrefix(0x00, 1)
+	signed := func(n *Node, version uint64) []byte {
+		m := &EncryptedManifest{Format: encryptedFormat, Ciphertext: NodeID{byte(version)}, Owner: n.ownerKey(), Version: version}
+		m.Signature = id_tools.SignMessage(*n.PrivKey, manifestMessage(key, m))
+		value, _ := json.Marshal(m)
+		return value
+	}
+
+	v2 := signed(owner, 2)
+	if err := replica.HandleStore(owner.Self, StoreRequest{Key: key, Value: v2}); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	if err := replica.HandleStore(attacker.Self, StoreRequest{Key: key, Value: signed(attacker, 3)}); err == nil {
+		t.Errorf("Manifest of another owner was accepted")
+	}
+	if err := replica.HandleStore(owner.Self, StoreRequest{Key: key, Value: signed(owner, 1)}); err == nil {
+		t.Errorf("Older manifest was accepted")
+	}
+
+	forged := signed(owner, 3)
+	forged = bytes.Replace(forged, []byte(`"version":3`), []byte(`"version":4`), 1)
+	if err := replica.HandleStore(owner.Self, StoreRequest{Key: key, Value: forged}); err == nil {
+		t.Errorf("Manifest with a broken signature was accepted")
+	}
+	if !hasKey(replica, key, v2) {
+		t.Errorf("Version 2 manifest was replaced")
+	}
+}
+
+// signedNotice returns a notice of the owner key for recipient
+func signedNotice(key *ecdsa.PrivateKey, recipient NodeID, file NodeID, expires time.Time) ShareNotice {
+	owner, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	notice := ShareNotice{File: file, Owner: owner, Expires: expires.Unix()}
+	notice.Signature = id_tools.SignMessage(*key, noticeMessage(recipient, notice))
+	return notice
+}
+
+// TestInboxMerge tests that replicas merge share notices instead of overwriting them
+func TestInboxMerge(t *testing.T) {
+	a := startOwnerNode(t, idWithPrefix(0x80, 1))
+	b := startOwnerNode(t, idWithPrefix(0x40, 1))
+	replica := startHonestNode(t, idWithPrefix(0x01, 1))
+	recipient := NodeID{7}
+	key := InboxKey(recipient)
+
+	inboxFrom := func(n *Node, file NodeID) []byte {
+		notice := signedNotice(n.PrivKey, recipient, file, time.Now().Add(time.Hour))
+		value, _ := json.Marshal(Inbox{Format: inboxFormat, Recipient: recipient, Notices: []ShareNotice{notice}})
+		return value
+	}
+
+	for _, value := range [][]byte{inboxFrom(a, NodeID{1}), inboxFrom(b, NodeID{2}), inboxFrom(a, NodeID{1})} {
+		if err := replica.HandleStore(a.Self, StoreRequest{Key: key, Value: value}); err != nil {
+			t.Fatalf("Store failed: %v", err)
+		}
+	}
+
+	value, _ := replica.getLocal(key)
+	inbox, ok := ParseInbox(value)
+	if !ok || len(inbox.Notices) != 2 {
+		t.Fatalf("Expected 2 merged notices, got %s", value)
+	}
+
+	if err := replica.HandleStore(a.Self, StoreRequest{Key: InboxKey(NodeID{8}), Value: inboxFrom(a, NodeID{1})}); err == nil {
+		t.Errorf("Inbox stored under another recipient's key was accepted")
+	}
+}
+
+// TestInboxLimits tests that an inbox fits in a datagram, that one owner cannot
+// fill it and that expired notices are dropped
+func TestInboxLimits(t *testing.T) {
+	replica := startHonestNode(t, idWithPrefix(0x01, 1))
+	recipient := NodeID{7}
+	key := InboxKey(recipient)
+	store := func(notices ...ShareNotice) error {
+		value, _ := json.Marshal(Inbox{Format: inboxFormat, Recipient: recipient, Notices: notices})
+		return replica.HandleStore(replica.Self, StoreRequest{Key: key, Value: value})
+	}
+	stored := func() []ShareNotice {
+		value, _ := replica.getLocal(key)
+		inbox, _ := ParseInbox(value)
+		return inbox.Notices
+	}
+
+	friend, _ := id_tools.GenerateNewPID()
+	spammer, _ := id_tools.GenerateNewPID()
+	if err := store(signedNotice(friend, recipient, NodeID{1}, time.Now().Add(time.Hour))); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	for i := range 2 * constants.MaxOwnerNotices {
+		expires := time.Now().Add(time.Duration(i+2) * time.Hour)
+		if err := store(signedNotice(spammer, recipient, NodeID{2, byte(i)}, expires)); err != nil {
+			t.Fatalf("Store failed: %v", err)
+		}
+	}
+	friendOwner, _ := x509.MarshalPKIXPublicKey(&friend.PublicKey)
+	if notices := stored(); len(notices) != constants.MaxOwnerNotices+1 || !containsNotice(notices, ShareNotice{File: NodeID{1}, Owner: friendOwner}) {
+		t.Fatalf("Expected the friend's notice and %d of the spammer's, got %d notices", constants.MaxOwnerNotices, len(notices))
+	}
+
+	if err := store(signedNotice(friend, recipient, NodeID{3}, time.Now().Add(-time.Second))); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	if len(stored()) != constants.MaxOwnerNotices+1 {
+		t.Errorf("Expired notice was kept")
+	}
+	tooLate := time.Now().Add(2 * constants.InboxNoticeTTL * time.Second)
+	if err := store(signedNotice(friend, recipient, NodeID{4}, tooLate)); err == nil {
+		t.Errorf("Notice expiring past the TTL was accepted")
+	}
+
+	// A full inbox still fits in one STORE
+	var full []ShareNotice
+	for i := range constants.MaxInboxNotices / constants.MaxOwnerNotices {
+		owner, _ := id_tools.GenerateNewPID()
+		for j := range constants.MaxOwnerNotices {
+			full = append(full, signedNotice(owner, recipient, NodeID{5, byte(i), byte(j)}, time.Now().Add(time.Hour)))
+		}
+	}
+	value, _ := json.Marshal(Inbox{Format: inboxFormat, Recipient: recipient, Notices: full})
+	if len(value) > constants.StreamChunkBytes {
+		t.Errorf("A full inbox takes %d bytes", len(value))
+	}
+}
@@ -0,0 +1,316 @@
+package dht
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"sync"
+
+	"github.com/kutluhann/decentralized-file-sharing-system/constants"
+)
+
+// ---------------------------------------------------------
+// STREAMING
+// A value read from a stream is cut into chunks as it arrives.
+// Each chunk is stored at its own SHA-256, so it verifies itself.
+// The chunk keys are packed into index chunks, and a manifest with
+// the index keys is stored at the value's key. Values that fit in
+// one chunk are stored as plain values. Uploads and downloads keep
+// at most Config.StreamWindow chunks in flight, so a slow DHT
+// slows down the reader of the upload and a slow reader of the
+// download stops further chunk lookups.
+// ---------------------------------------------------------
+
+const chunkFormat = "dfss-chunked-v1"
+
+// chunkKeysPerIndex is the number of chunk keys packed into one index chunk.
+// Unlike the size of data chunks it is fixed, so every node reads every manifest.
+const chunkKeysPerIndex = constants.StreamChunkBytes / constants.KeySizeBytes
+
+// ErrValueTooLarge is returned when a value that is stored whole does not fit in a chunk
+var ErrValueTooLarge = errors.New("value is too large")
+
+// ChunkManifest is stored at the key of a chunked value
+type ChunkManifest struct {
+	Format string   `json:"format"` // Always chunkFormat, must stay the first field
+	Size   int64    `json:"size"`   // Size of the original value
+	Chunks int      `json:"chunks"` // Number of data chunks
+	Index  []NodeID `json:"index"`  // Keys of the index chunks, each the concatenated keys of up to chunkKeysPerIndex chunks
+	Hash   [32]byte `json:"hash"`   // SHA256 of the original value
+}
+
+// ParseChunkManifest returns the manifest if value is one
+func ParseChunkManifest(value []byte) (*ChunkManifest, bool) {
+	if !bytes.HasPrefix(value, []byte(`{"format":"`+chunkFormat+`"`)) {
+		return nil, false
+	}
+
+	var manifest ChunkManifest
+	if err := json.Unmarshal(value, &manifest); err != nil {
+		return nil, false
+	}
+	if (manifest.Chunks+chunkKeysPerIndex-1)/chunkKeysPerIndex != len(manifest.Index) {
+		return nil, false
+	}
+	return &manifest, true
+}
+
+// readChunk reads the next chunk of size bytes of a stream. An empty chunk means the stream ended.
+func readChunk(r io.Reader, size int) ([]byte, error) {
+	chunk := make([]byte, size)
+	n, err := io.ReadFull(r, chunk)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return chunk[:n], nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return chunk, nil
+}
+
+// StoreStream reads a value from r until EOF and stores it at key, chunk by
+// chunk as it arrives. Read errors of r are returned unchanged.
+// Returns: size, SHA256 of the value, error
+func (n *Node) StoreStream(key NodeID, r io.Reader) (int64, [32]byte, error) {
+	chunkBytes := n.Config.StreamChunkBytes
+	chunk, err := readChunk(r, chunkBytes)
+	if err != nil {
+		return 0, [32]byte{}, err
+	}
+	if len(chunk) < chunkBytes {
+		return int64(len(chunk)), sha256.Sum256(chunk), n.Store(key, chunk)
+	}
+
+	fmt.Printf("[STREAM] Storing key %s in chunks of %d bytes\n", key.String()[:16], chunkBytes)
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		storeErr error
+	)
+	failed := func() error {
+		mu.Lock()
+		defer mu.Unlock()
+		return storeErr
+	}
+
+	window := make(chan struct{}, n.Config.StreamWindow)
+	valueHash := sha256.New()
+	var size int64
+	var keys []NodeID
+	for len(chunk) > 0 && failed() == nil {
+		valueHash.Write(chunk)
+		size += int64(len(chunk))
+		chunkKey := NodeID(sha256.Sum256(chunk))
+		keys = append(keys, chunkKey)
+
+		// Blocks, and so stops reading r, while the window is full
+		window <- struct{}{}
+		wg.Add(1)
+		go func(chunkKey NodeID, chunk []byte) {
+			defer wg.Done()
+			defer func() { <-window }()
+			if err := n.Store(chunkKey, chunk); err != nil {
+				mu.Lock()
+				if storeErr == nil {
+					storeErr = fmt.Errorf("failed to store chunk %s: %v", chunkKey.String()[:16], err)
+				}
+				mu.Unlock()
+			}
+		}(chunkKey, chunk)
+
+		if chunk, err = readChunk(r, chunkBytes); err != nil {
+			wg.Wait()
+			return size, [32]byte{}, err
+		}
+	}
+	wg.Wait()
+	if err := failed(); err != nil {
+		return size, [32]byte{}, err
+	}
+
+	manifest := ChunkManifest{
+		Format: chunkFormat,
+		Size:   size,
+		Chunks: len(keys),
+		Hash:   [32]byte(valueHash.Sum(nil)),
+	}
+	for start := 0; start < len(keys); start += chunkKeysPerIndex {
+		index := make([]byte, 0, chunkKeysPerIndex*constants.KeySizeBytes)
+		for _, chunkKey := range keys[start:min(start+chunkKeysPerIndex, len(keys))] {
+			index = append(index, chunkKey[:]...)
+		}
+		indexKey := NodeID(sha256.Sum256(index))
+		if err := n.Store(indexKey, index); err != nil {
+			return size, [32]byte{}, fmt.Errorf("failed to store chunk index: %v", err)
+		}
+		manifest.Index = append(manifest.Index, indexKey)
+	}
+
+	fmt.Printf("[STREAM] ✓ Stored %d bytes as %d chunks\n", size, len(keys))
+
+	manifestBytes, _ := json.Marshal(manifest)
+	return size, manifest.Hash, n.Store(key, manifestBytes)
+}
+
+// findChunk looks up a chunk and checks that it matches its key
+func (n *Node) findChunk(chunkKey NodeID) ([]byte, int, error) {
+	chunk, hopCount, err := n.FindValue(chunkKey)
+	if err != nil {
+		return nil, hopCount, err
+	}
+	if NodeID(sha256.Sum256(chunk)) != chunkKey {
+		return nil, hopCount, fmt.Errorf("chunk %s does not match its key", chunkKey.String()[:16])
+	}
+	return chunk, hopCount, nil
+}
+
+// chunkKeys fetches the index chunks of a manifest.
+// Returns: the keys of the data chunks in order, hopCount, error
+func (n *Node) chunkKeys(manifest *ChunkManifest) ([]NodeID, int, error) {
+	keys := make([]NodeID, 0, manifest.Chunks)
+	hopCount := 0
+	for _, indexKey := range manifest.Index {
+		index, hops, err := n.findChunk(indexKey)
+		hopCount += hops
+		if err != nil {
+			return nil, hopCount, fmt.Errorf("chunk index missing: %v", err)
+		}
+		if len(index)%constants.KeySizeBytes != 0 {
+			return nil, hopCount, fmt.Errorf("malformed chunk index")
+		}
+		for i := 0; i < len(index); i += constants.KeySizeBytes {
+			keys = append(keys, NodeID(index[i:i+constants.KeySizeBytes]))
+		}
+	}
+	if len(keys) != manifest.Chunks {
+		return nil, hopCount, fmt.Errorf("chunk index lists %d of %d chunks", len(keys), manifest.Chunks)
+	}
+	return keys, hopCount, nil
+}
+
+// chunkResult is a fetched chunk or the reason it could not be fetched
+type chunkResult struct {
+	chunk []byte
+	err   error
+}
+
+// ValueReader streams a value out of the DHT. Size and Hash are known before
+// the first Read. Chunked values are fetched ahead of the reader, at most
+// Config.StreamWindow chunks at a time. Close must be called when done.
+type ValueReader struct {
+	Size     int64
+	Hash     [32]byte // SHA256 of the whole value
+	HopCount int      // Hops to find the value, not counting the chunks
+
+	current []byte
+	pending chan chan chunkResult // In-flight chunks in value order, nil for values read in one piece
+	hash    hash.Hash
+	err     error
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// OpenStream finds the value at key and returns a reader for it. Values that
+// are not chunked are retrieved, rebuilt and decrypted as by Retrieve.
+func (n *Node) OpenStream(key NodeID) (*ValueReader, error) {
+	value, hopCount, err := n.FindValue(key)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, ok := ParseChunkManifest(value)
+	if !ok {
+		value, hopCount, err = n.decodeValue(key, value, hopCount)
+		if err != nil {
+			return nil, err
+		}
+		return &ValueReader{
+			Size:     int64(len(value)),
+			Hash:     sha256.Sum256(value),
+			HopCount: hopCount,
+			current:  value,
+			done:     make(chan struct{}),
+		}, nil
+	}
+	return n.openChunks(manifest, hopCount)
+}
+
+// openChunks starts fetching the chunks of a manifest for a reader
+func (n *Node) openChunks(manifest *ChunkManifest, hopCount int) (*ValueReader, error) {
+	keys, hops, err := n.chunkKeys(manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &ValueReader{
+		Size:     manifest.Size,
+		Hash:     manifest.Hash,
+		HopCount: hopCount + hops,
+		pending:  make(chan chan chunkResult, n.Config.StreamWindow),
+		hash:     sha256.New(),
+		done:     make(chan struct{}),
+	}
+
+	go func() {
+		defer close(v.pending)
+		for _, chunkKey := range keys {
+			result := make(chan chunkResult, 1)
+			// Blocks while the window is full, until the reader consumes a chunk
+			select {
+			case v.pending <- result:
+			case <-v.done:
+				return
+			}
+			go func(chunkKey NodeID) {
+				chunk, _, err := n.findChunk(chunkKey)
+				result <- chunkResult{chunk: chunk, err: err}
+			}(chunkKey)
+		}
+	}()
+	return v, nil
+}
+
+// Read reads the next bytes of the value
+func (v *ValueReader) Read(p []byte) (int, error) {
+	for len(v.current) == 0 {
+		if v.err != nil {
+			return 0, v.err
+		}
+		if v.pending == nil {
+			v.err = io.EOF
+			continue
+		}
+
+		result, ok := <-v.pending
+		if !ok {
+			v.err = io.EOF
+			if [32]byte(v.hash.Sum(nil)) != v.Hash {
+				v.err = fmt.Errorf("value does not match manifest hash")
+			}
+			continue
+		}
+		r := <-result
+		if r.err != nil {
+			v.err = fmt.Errorf("chunk missing: %v", r.err)
+			continue
+		}
+		v.hash.Write(r.chunk)
+		v.current = r.chunk
+	}
+
+	count := copy(p, v.current)
+	v.current = v.current[count:]
+	return count, nil
+}
+
+// Close stops fetching chunks ahead of the reader
+func (v *ValueReader) Close() error {
+	v.closeOnce.Do(func() { close(v.done) })
+	return nil
+}
@@ -0,0 +1,78 @@
+package dht
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"io"
+	"testing"
+
+	"github.com/kutluhann/decentralized-file-sharing-system/constants"
+)
+
+// TestStreamRoundTrip tests that a value larger than a chunk is stored in chunks and streamed back intact
+func TestStreamRoundTrip(t *testing.T) {
+	writer := startOwnerNode(t, idWithPrefix(0x80, 1))
+	reader := startOwnerNode(t, idWithPrefix(0x40, 1))
+	r1 := startHonestNode(t, idWithPrefix(0x01, 1))
+	r2 := startHonestNode(t, idWithPrefix(0x02, 1))
+	connect(writer, reader, r1, r2)
+
+	value := make([]byte, 3*constants.StreamChunkBytes+100)
+	rand.Read(value)
+	key := idWithPrefix(0x00, 1)
+
+	size, hash, err := writer.StoreStream(key, bytes.NewReader(value))
+	if err != nil || size != int64(len(value)) || hash != sha256.Sum256(value) {
+		t.Fatalf("StoreStream returned %d, %x, %v", size, hash[:4], err)
+	}
+
+	stored, _, _ := writer.FindValue(key)
+	if manifest, ok := ParseChunkManifest(stored); !ok || manifest.Chunks != 4 {
+		t.Fatalf("Expected a manifest of 4 chunks, got %.60q", stored)
+	}
+
+	stream, err := reader.OpenStream(key)
+	if err != nil {
+		t.Fatalf("OpenStream failed: %v", err)
+	}
+	defer stream.Close()
+	if stream.Size != int64(len(value)) || stream.Hash != hash {
+		t.Errorf("Stream announced %d bytes with hash %x", stream.Size, stream.Hash[:4])
+	}
+	streamed, err := io.ReadAll(stream)
+	if err != nil || !bytes.Equal(streamed, value) {
+		t.Fatalf("Streamed %d bytes, %v", len(streamed), err)
+	}
+
+	if retrieved, _, err := reader.Retrieve(key); err != nil || !bytes.Equal(retrieved, value) {
+		t.Errorf("Retrieve returned %d bytes, %v", len(retrieved), err)
+	}
+}
+
+// TestStreamSmallValue tests that a value within one chunk is stored as a plain value
+func TestStreamSmallValue(t *testing.T) {
+	writer := startOwnerNode(t, idWithPrefix(0x80, 1))
+	r1 := startHonestNode(t, idWithPrefix(0x01, 1))
+	connect(writer, r1)
+
+	key := idWithPrefix(0x00, 1)
+	value := []byte("small")
+	if _, _, err := writer.StoreStream(key, bytes.NewReader(value)); err != nil {
+		t.Fatalf("StoreStream failed: %v", err)
+	}
+	if !hasKey(r1, key, value) {
+		t.Errorf("Expected the plain value on the replica")
+	}
+
+	// An empty value is found on the replica, not mistaken for a missing one
+	empty := idWithPrefix(0x00, 2)
+	if _, _, err := writer.StoreStream(empty, bytes.NewReader(nil)); err != nil {
+		t.Fatalf("StoreStream of an empty value failed: %v", err)
+	}
+	reader := startOwnerNode(t, idWithPrefix(0x40, 1))
+	connect(writer, r1, reader)
+	if value, _, err := reader.FindValue(empty); err != nil || len(value) != 0 {
+		t.Errorf("Expected the empty value, got %q, %v", value, err)
+	}
+}
@@ -0,0 +1,215 @@
+package dht
+
+import (
+	"bytes"
+	"crypto/x509"
+	"fmt"
+	"time"
+
+	"github.com/kutluhann/decentralized-file-sharing-system/id_tools"
+)
+
+// ---------------------------------------------------------
+// DELETION
+// Every stored value remembers the public key of the node that
+// stored it. Only that owner can sign a tombstone for the key.
+// Replicas replace the value with the tombstone, stop serving
+// and republishing the value, and republish the tombstone instead
+// until it expires, so stale replicas that come back online
+// cannot resurrect the value. Cached copies expire on their own.
+// Nodes that never held the key keep the tombstone at the sender's
+// expense until it expires, without republishing it.
+// ---------------------------------------------------------
+
+// ownerKey returns our public key in the form stored with the values we own
+func (n *Node) ownerKey() []byte {
+	if n.PrivKey == nil {
+		return nil
+	}
+	owner, _ := x509.MarshalPKIXPublicKey(&n.PrivKey.PublicKey)
+	return owner
+}
+
+// tombstoneMessage returns the message an owner signs to delete a key
+func tombstoneMessage(t Tombstone) string {
+	return fmt.Sprintf("DELETE|%s|%d|%d", t.Key.String(), t.Deleted, t.Expires)
+}
+
+// newTombstone creates a tombstone for key signed with our private key
+func (n *Node) newTombstone(key NodeID) (Tombstone, error) {
+	if n.PrivKey == nil {
+		return Tombstone{}, fmt.Errorf("node has no private key to sign the tombstone")
+	}
+
+	now := time.Now()
+	t := Tombstone{
+		Key:     key,
+		Owner:   n.ownerKey(),
+		Deleted: now.Unix(),
+		Expires: now.Add(n.Config.TombstoneTTL).Unix(),
+	}
+	t.Signature = id_tools.SignMessage(*n.PrivKey, tombstoneMessage(t))
+	return t, nil
+}
+
+// verifyTombstone checks that a tombstone is unexpired and signed by the owner it names
+func verifyTombstone(t Tombstone) error {
+	if time.Now().Unix() >= t.Expires {
+		return fmt.Errorf("tombstone expired")
+	}
+
+	ecdsaPubKey, err := parseOwnerKey(t.Owner)
+	if err != nil {
+		return err
+	}
+
+	if !id_tools.VerifySignature(*ecdsaPubKey, tombstoneMessage(t), t.Signature) {
+		return fmt.Errorf("invalid tombstone signature")
+	}
+	return nil
+}
+
+// tombstoned reports whether a live tombstone blocks storing key. A tombstone
+// only blocks values of its own owner, or any value if owner is nil (cached copies).
+// Expired tombstones are dropped. The caller must hold the StorageMux write lock.
+func (n *Node) tombstoned(key NodeID, owner []byte) bool {
+	t, exists := n.Tombstones[key]
+	if !exists {
+		return false
+	}
+	if time.Now().Unix() >= t.Expires {
+		n.dropTombstoneLocked(key)
+		return false
+	}
+	return owner == nil || bytes.Equal(t.Owner, owner)
+}
+
+// HandleDelete verifies a tombstone and deletes our copy of the key
+func (n *Node) HandleDelete(sender Contact, tombstone Tombstone) error {
+	n.RoutingTable.Update(sender)
+
+	if err := n.applyTombstone(sender, tombstone); err != nil {
+		fmt.Printf("[SERVER] ✗ Rejected tombstone for key %s from %s: %v\n",
+			tombstone.Key.String()[:16], sender.ID.String()[:16], err)
+		return err
+	}
+
+	fmt.Printf("[SERVER] ✓ Deleted key %s (from %s)\n", tombstone.Key.String()[:16], sender.ID.String()[:16])
+	return nil
+}
+
+// applyTombstone replaces our copy of a key with its tombstone. A value we hold
+// can only be deleted by its owner. We also keep tombstones for keys we don't
+// hold, so late replicas are refused, but those are rate limited and charged
+// to the sender like a STORE, and only the owner republishes them.
+func (n *Node) applyTombstone(sender Contact, t Tombstone) error {
+	if err := verifyTombstone(t); err != nil {
+		return err
+	}
+
+	n.StorageMux.Lock()
+	_, exists := n.Storage[t.Key]
+	_, cached := n.CacheExpiry[t.Key]
+	held := exists && !cached
+	if held && !bytes.Equal(n.Owners[t.Key], t.Owner) {
+		n.StorageMux.Unlock()
+		return fmt.Errorf("tombstone is not signed by the owner of the value")
+	}
+	if current, exists := n.Tombstones[t.Key]; exists && current.Deleted >= t.Deleted {
+		// We already hold this tombstone or a newer one
+		n.StorageMux.Unlock()
+		return nil
+	}
+	if !held {
+		if err := n.allowStore(sender); err != nil {
+			n.StorageMux.Unlock()
+			return err
+		}
+		if err := n.chargeTombstone(sender.ID, t.Key); err != nil {
+			n.StorageMux.Unlock()
+			return err
+		}
+	}
+
+	n.deleteLocked(t.Key)
+	delete(n.CacheExpiry, t.Key)
+	delete(n.ShardKeys, t.Key)
+	delete(n.Owners, t.Key)
+	n.Tombstones[t.Key] = t
+	n.StorageMux.Unlock()
+
+	// The replication scheduler now republishes the tombstone instead of the
+	// value, and drops it once it expires
+	n.Replication.Track(t.Key)
+	return nil
+}
+
+// Delete removes a key we own from the DHT by sending a signed tombstone to its
+// k closest nodes. The shards of an erasure-coded value are deleted as well.
+// The chunks of a chunked value are stored at their content hash and may be
+// shared with other values, so they are left to eviction like a deduplicated
+// ciphertext.
+func (n *Node) Delete(key NodeID) error {
+	fmt.Printf("[DHT-DELETE] Deleting key %s...\n", key.String()[:16])
+
+	// Look up the value first, an erasure manifest points to shards to delete
+	// too and an encrypted one to its ciphertext, unless other manifests may share it.
+	// Chunks are content-addressed, so only the chunk manifest is deleted.
+	var shardKeys []NodeID
+	if value, _, err := n.FindValue(key); err == nil {
+		if manifest, ok := ParseManifest(value); ok {
+			shardKeys = manifest.ShardKeys
+		} else if encrypted, ok := ParseEncryptedManifest(value); ok && !encrypted.Convergent {
+			shardKeys = []NodeID{encrypted.Ciphertext}
+		}
+	}
+
+	deleted, err := n.deleteKey(key)
+	if err != nil {
+		return err
+	}
+
+	for _, shardKey := range shardKeys {
+		if _, err := n.deleteKey(shardKey); err != nil {
+			fmt.Printf("[DHT-DELETE] ✗ Failed to delete shard %s: %v\n", shardKey.String()[:16], err)
+		}
+	}
+
+	fmt.Printf("[DHT-DELETE] ✓ Complete: tombstone accepted by %d nodes\n", deleted)
+	return nil
+}
+
+// deleteKey sends a tombstone for key to its k closest nodes and applies it locally.
+// Returns the number of nodes that accepted it.
+func (n *Node) deleteKey(key NodeID) (int, error) {
+	tombstone, err := n.newTombstone(key)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := n.applyTombstone(n.Self, tombstone); err != nil {
+		return 0, err
+	}
+	accepted := 1
+
+	// Store may have placed the value on spares past the k closest nodes
+	closest, _ := n.NodeLookupN(key, 2*n.Config.K)
+	var lastErr error
+	for _, contact := range closest {
+		if contact.ID == n.Self.ID {
+			continue
+		}
+		if err := n.Network.SendDelete(contact, tombstone); err != nil {
+			fmt.Printf("[DHT-DELETE] ✗ %s did not delete key %s: %v\n",
+				contact.ID.String()[:16], key.String()[:16], err)
+			lastErr = err
+			continue
+		}
+		accepted++
+	}
+
+	if accepted == 1 && lastErr != nil {
+		return accepted, fmt.Errorf("no replica accepted the tombstone: %v", lastErr)
+	}
+	return accepted, nil
+}
@@ -0,0 +1,201 @@
+package dht
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/kutluhann/decentralized-file-sharing-system/constants"
+	"github.com/kutluhann/decentralized-file-sharing-system/id_tools"
+)
+
+// startOwnerNode starts a Node with its own key pair, so it can own and delete values
+func startOwnerNode(t *testing.T, id NodeID) *Node {
+	t.Helper()
+
+	node := startHonestNode(t, id)
+	node.PrivKey, _ = id_tools.GenerateNewPID()
+	return node
+}
+
+// connect adds every node to the routing table of every other node
+func connect(nodes ...*Node) {
+	for _, a := range nodes {
+		for _, b := range nodes {
+			if a != b {
+				a.RoutingTable.Update(b.Self)
+			}
+		}
+	}
+}
+
+// TestDeleteRemovesReplicas tests that the owner's tombstone removes the value
+// everywhere and that stale replicas of it are refused afterwards
+func TestDeleteRemovesReplicas(t *testing.T) {
+	owner := startOwnerNode(t, idWithPrefix(0x80, 1))
+	r1 := startHonestNode(t, idWithPrefix(0x01, 1))
+	r2 := startHonestNode(t, idWithPrefix(0x02, 1))
+	connect(owner, r1, r2)
+
+	key := idWithPrefix(0x00, 1)
+	value := []byte("to be deleted")
+	if err := owner.Store(key, value); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	if !hasKey(r1, key, value) || !hasKey(r2, key, value) {
+		t.Fatalf("Value was not replicated")
+	}
+
+	if err := owner.Delete(key); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	for _, n := range []*Node{owner, r1, r2} {
+		if _, exists := n.getLocal(key); exists {
+			t.Errorf("Node %s still holds the deleted key", n.Self.ID.String()[:4])
+		}
+	}
+	if _, _, err := r1.FindValue(key); err == nil {
+		t.Errorf("Deleted key was still found")
+	}
+
+	// A stale replica republishing the value is refused
+	r1.HandleStore(r2.Self, StoreRequest{Key: key, Value: value, Owner: owner.ownerKey()})
+	if hasKey(r1, key, value) {
+		t.Errorf("Stale replica resurrected the deleted key")
+	}
+}
+
+// TestDeleteRejectsNonOwner tests that only the owner of a value can delete it
+func TestDeleteRejectsNonOwner(t *testing.T) {
+	owner := startOwnerNode(t, idWithPrefix(0x80, 1))
+	attacker := startOwnerNode(t, idWithPrefix(0x40, 1))
+	replica := startHonestNode(t, idWithPrefix(0x01, 1))
+	connect(owner, attacker, replica)
+
+	key := idWithPrefix(0x00, 1)
+	value := []byte("not yours")
+	if err := owner.Store(key, value); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	tombstone, _ := attacker.newTombstone(key)
+	if err := replica.HandleDelete(attacker.Self, tombstone); err == nil {
+		t.Fatalf("Tombstone of a non-owner was accepted")
+	}
+	if !hasKey(replica, key, value) {
+		t.Fatalf("Value was deleted by a non-owner")
+	}
+
+	// A tampered tombstone fails signature verification
+	tombstone, _ = owner.newTombstone(key)
+	tombstone.Expires += 3600
+	if err := replica.HandleDelete(attacker.Self, tombstone); err == nil {
+		t.Fatalf("Tombstone with an invalid signature was accepted")
+	}
+}
+
+// TestTombstoneRepublished tests that a replica that missed the deletion gets the tombstone on republish
+func TestTombstoneRepublished(t *testing.T) {
+	owner := startOwnerNode(t, idWithPrefix(0x80, 1))
+	stale := startNodeWithoutHandoff(t, idWithPrefix(0x01, 1))
+
+	key := idWithPrefix(0x00, 1)
+	value := []byte("stale copy")
+	stale.HandleStore(owner.Self, StoreRequest{Key: key, Value: value, Owner: owner.ownerKey()})
+
+	// The owner deletes while the stale replica is unknown to it
+	if err := owner.Delete(key); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if !hasKey(stale, key, value) {
+		t.Fatalf("Stale replica should not have seen the deletion yet")
+	}
+
+	owner.RoutingTable.Update(stale.Self)
+	owner.Replication.republish(time.Now().Add(constants.ReplicationInterval * time.Second))
+
+	if hasKey(stale, key, value) {
+		t.Fatalf("Republished tombstone did not delete the stale replica")
+	}
+}
+
+// TestTombstoneForUnheldKeyIsCharged tests that a tombstone for a key we don't
+// hold is charged to its sender, not republished, and released once it expires
+func TestTombstoneForUnheldKeyIsCharged(t *testing.T) {
+	replica := startHonestNode(t, idWithPrefix(0x01, 1))
+	other := startHonestNode(t, idWithPrefix(0x02, 1))
+	stranger := startOwnerNode(t, idWithPrefix(0x40, 1))
+	connect(replica, other, stranger)
+	replica.Limits.PeerMaxRecords = 2
+
+	for i := byte(1); i <= 2; i++ {
+		tombstone, _ := stranger.newTombstone(idWithPrefix(0x00, i))
+		if err := replica.HandleDelete(stranger.Self, tombstone); err != nil {
+			t.Fatalf("Tombstone %d was refused: %v", i, err)
+		}
+	}
+	third, _ := stranger.newTombstone(idWithPrefix(0x00, 3))
+	if err := replica.HandleDelete(stranger.Self, third); err == nil {
+		t.Fatalf("Expected a tombstone over the peer key quota to be refused")
+	}
+
+	replica.Replication.republish(time.Now().Add(constants.ReplicationInterval * time.Second))
+	other.StorageMux.RLock()
+	republished := len(other.Tombstones)
+	other.StorageMux.RUnlock()
+	if republished != 0 {
+		t.Errorf("Tombstones of a stranger were republished to %d keys", republished)
+	}
+
+	// Expired tombstones are dropped by the scheduler and free the quota
+	replica.StorageMux.Lock()
+	for key, tombstone := range replica.Tombstones {
+		tombstone.Expires = time.Now().Unix()
+		replica.Tombstones[key] = tombstone
+	}
+	replica.StorageMux.Unlock()
+	replica.Replication.republish(time.Now().Add(2 * constants.ReplicationInterval * time.Second))
+	if err := replica.HandleDelete(stranger.Self, third); err != nil {
+		t.Errorf("Expired tombstones did not free the peer quota: %v", err)
+	}
+}
+
+// TestDeleteKeepsSharedChunks tests that deleting a chunked value leaves its
+// chunks to another value with the same content
+func TestDeleteKeepsSharedChunks(t *testing.T) {
+	a := startOwnerNode(t, idWithPrefix(0x80, 1))
+	b := startOwnerNode(t, idWithPrefix(0x40, 1))
+	r1 := startHonestNode(t, idWithPrefix(0x01, 1))
+	r2 := startHonestNode(t, idWithPrefix(0x02, 1))
+	connect(a, b, r1, r2)
+
+	value := make([]byte, 2*constants.StreamChunkBytes+100)
+	rand.Read(value)
+	keyA, keyB := idWithPrefix(0x00, 1), idWithPrefix(0x00, 2)
+	// a stores last, so it is the recorded owner of the shared chunks
+	if _, _, err := b.StoreStream(keyB, bytes.NewReader(value)); err != nil {
+		t.Fatalf("StoreStream failed: %v", err)
+	}
+	if _, _, err := a.StoreStream(keyA, bytes.NewReader(value)); err != nil {
+		t.Fatalf("StoreStream failed: %v", err)
+	}
+
+	if err := a.Delete(keyA); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, _, err := r1.FindValue(keyA); err == nil {
+		t.Errorf("Deleted key was still found")
+	}
+
+	stream, err := r1.OpenStream(keyB)
+	if err != nil {
+		t.Fatalf("OpenStream of the other key failed: %v", err)
+	}
+	defer stream.Close()
+	if streamed, err := io.ReadAll(stream); err != nil || !bytes.Equal(streamed, value) {
+		t.Fatalf("Other key streamed %d bytes, %v", len(streamed), err)
+	}
+}
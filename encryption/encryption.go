@@ -0,0 +1,210 @@
+package encryption
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"errors"
+	"fmt"
+
+	"github.com/kutluhann/decentralized-file-sharing-system/constants"
+)
+
+// A value is encrypted with AES-256-GCM under a random content key. The
+// content key is then wrapped for every recipient: an ephemeral P-256 key
+// agrees on a secret with the recipient's identity key (ECDH), and the
+// content key is sealed with a key derived from that secret (HKDF-SHA256).
+//
+// Convergent encryption derives the content key and nonce from the salted
+// hash of the plaintext instead, so identical plaintexts give identical
+// ciphertexts that the DHT stores once. The trade-off: anyone who can guess
+// a plaintext can compute its ciphertext and confirm that it is stored
+// (confirmation of a file), and learn the rest of a file that differs from
+// a known template only in a few guessable bytes. Only use it for content
+// that is not secret against someone who could already produce it.
+
+const KeySize = 32 // AES-256
+
+const (
+	wrapInfo       = "dfss-wrap-v1"
+	convergentInfo = "dfss-convergent-v1"
+)
+
+var (
+	ErrNotRecipient = errors.New("content key is not wrapped for this identity")
+	ErrCiphertext   = errors.New("ciphertext is corrupted or the key is wrong")
+)
+
+// WrappedKey is a content key sealed for one recipient
+type WrappedKey struct {
+	Recipient []byte `json:"recipient"` // PKIX public key of the recipient
+	Ephemeral []byte `json:"ephemeral"` // Ephemeral ECDH public key of the sender
+	Key       []byte `json:"key"`       // Nonce followed by the sealed content key
+}
+
+// NewContentKey returns a random content key
+func NewContentKey() []byte {
+	key := make([]byte, KeySize)
+	rand.Read(key)
+	return key
+}
+
+// Seal encrypts plaintext under a fresh random content key.
+// The ciphertext carries its nonce in front.
+func Seal(plaintext []byte) (contentKey []byte, ciphertext []byte, err error) {
+	contentKey = NewContentKey()
+	ciphertext, err = seal(contentKey, plaintext, nil)
+	return contentKey, ciphertext, err
+}
+
+// ConvergentKey derives the content key of a plaintext from its salted hash
+func ConvergentKey(plaintext []byte) []byte {
+	hash := sha256.Sum256(append([]byte(constants.Salt), plaintext...))
+	key, _ := hkdf.Key(sha256.New, hash[:], nil, convergentInfo, KeySize)
+	return key
+}
+
+// SealConvergent encrypts plaintext under its convergent key. The nonce is
+// derived from the key, which is safe since a key only ever seals one plaintext.
+func SealConvergent(plaintext []byte) (contentKey []byte, ciphertext []byte, err error) {
+	contentKey = ConvergentKey(plaintext)
+	gcm, err := newGCM(contentKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	nonceHash := sha256.Sum256(append([]byte(convergentInfo+"|nonce"), contentKey...))
+	nonce := nonceHash[:gcm.NonceSize()]
+	return contentKey, gcm.Seal(append([]byte{}, nonce...), nonce, plaintext, nil), nil
+}
+
+// Open decrypts a ciphertext produced by Seal or SealConvergent
+func Open(contentKey, ciphertext []byte) ([]byte, error) {
+	return open(contentKey, ciphertext, nil)
+}
+
+func seal(key, plaintext, additional []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	rand.Read(nonce)
+	return gcm.Seal(nonce, nonce, plaintext, additional), nil
+}
+
+func open(key, ciphertext, additional []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < gcm.NonceSize() {
+		return nil, ErrCiphertext
+	}
+	nonce, sealed := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, sealed, additional)
+	if err != nil {
+		return nil, ErrCiphertext
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("content key must be %d bytes, got %d", KeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// WrapKey seals a content key for the holder of recipient's private key
+func WrapKey(contentKey []byte, recipient *ecdsa.PublicKey) (WrappedKey, error) {
+	recipientECDH, err := recipient.ECDH()
+	if err != nil {
+		return WrappedKey{}, fmt.Errorf("recipient key cannot be used for ECDH: %v", err)
+	}
+	recipientDER, err := x509.MarshalPKIXPublicKey(recipient)
+	if err != nil {
+		return WrappedKey{}, err
+	}
+
+	ephemeral, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return WrappedKey{}, err
+	}
+	shared, err := ephemeral.ECDH(recipientECDH)
+	if err != nil {
+		return WrappedKey{}, err
+	}
+
+	wrapped := WrappedKey{
+		Recipient: recipientDER,
+		Ephemeral: ephemeral.PublicKey().Bytes(),
+	}
+	kek, err := wrappingKey(shared, wrapped)
+	if err != nil {
+		return WrappedKey{}, err
+	}
+	wrapped.Key, err = seal(kek, contentKey, wrapped.Recipient)
+	return wrapped, err
+}
+
+// UnwrapKey recovers a content key with the recipient's private key
+func UnwrapKey(wrapped WrappedKey, privateKey *ecdsa.PrivateKey) ([]byte, error) {
+	own, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(own, wrapped.Recipient) {
+		return nil, ErrNotRecipient
+	}
+
+	privateECDH, err := privateKey.ECDH()
+	if err != nil {
+		return nil, err
+	}
+	ephemeral, err := ecdh.P256().NewPublicKey(wrapped.Ephemeral)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ephemeral key: %v", err)
+	}
+	shared, err := privateECDH.ECDH(ephemeral)
+	if err != nil {
+		return nil, err
+	}
+
+	kek, err := wrappingKey(shared, wrapped)
+	if err != nil {
+		return nil, err
+	}
+	return open(kek, wrapped.Key, wrapped.Recipient)
+}
+
+// FindKey unwraps the content key wrapped for privateKey among keys
+func FindKey(keys []WrappedKey, privateKey *ecdsa.PrivateKey) ([]byte, error) {
+	own, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	for _, wrapped := range keys {
+		if bytes.Equal(wrapped.Recipient, own) {
+			return UnwrapKey(wrapped, privateKey)
+		}
+	}
+	return nil, ErrNotRecipient
+}
+
+// wrappingKey derives the key sealing a content key from the ECDH secret,
+// bound to both public keys
+func wrappingKey(shared []byte, wrapped WrappedKey) ([]byte, error) {
+	info := append([]byte(wrapInfo), wrapped.Ephemeral...)
+	info = append(info, wrapped.Recipient...)
+	return hkdf.Key(sha256.New, shared, nil, string(info), KeySize)
+}
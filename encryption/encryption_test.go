@@ -0,0 +1,99 @@
+package encryption
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"testing"
+)
+
+func newIdentity(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	return key
+}
+
+// TestSealOpen tests that a ciphertext opens with its key only and detects tampering
+func TestSealOpen(t *testing.T) {
+	plaintext := []byte("secret file contents")
+	key, ciphertext, err := Seal(plaintext)
+	if err != nil {
+		t.Fatalf("Seal failed: %v", err)
+	}
+	if bytes.Contains(ciphertext, plaintext) {
+		t.Fatalf("Ciphertext contains the plaintext")
+	}
+
+	opened, err := Open(key, ciphertext)
+	if err != nil || !bytes.Equal(opened, plaintext) {
+		t.Fatalf("Open returned %q, %v", opened, err)
+	}
+
+	if _, err := Open(NewContentKey(), ciphertext); !errors.Is(err, ErrCiphertext) {
+		t.Errorf("Expected a wrong key to fail, got %v", err)
+	}
+	ciphertext[len(ciphertext)-1] ^= 1
+	if _, err := Open(key, ciphertext); !errors.Is(err, ErrCiphertext) {
+		t.Errorf("Expected a tampered ciphertext to fail, got %v", err)
+	}
+}
+
+// TestWrapUnwrap tests that only the recipients of a content key can unwrap it
+func TestWrapUnwrap(t *testing.T) {
+	alice, bob, eve := newIdentity(t), newIdentity(t), newIdentity(t)
+	contentKey := NewContentKey()
+
+	var keys []WrappedKey
+	for _, recipient := range []*ecdsa.PrivateKey{alice, bob} {
+		wrapped, err := WrapKey(contentKey, &recipient.PublicKey)
+		if err != nil {
+			t.Fatalf("WrapKey failed: %v", err)
+		}
+		keys = append(keys, wrapped)
+	}
+
+	for _, recipient := range []*ecdsa.PrivateKey{alice, bob} {
+		key, err := FindKey(keys, recipient)
+		if err != nil || !bytes.Equal(key, contentKey) {
+			t.Fatalf("Recipient could not unwrap the content key: %v", err)
+		}
+	}
+
+	if _, err := FindKey(keys, eve); !errors.Is(err, ErrNotRecipient) {
+		t.Errorf("Expected ErrNotRecipient, got %v", err)
+	}
+
+	// Relabelling a wrapped key for another recipient does not help
+	forged := keys[0]
+	forged.Recipient = keys[1].Recipient
+	if _, err := UnwrapKey(forged, bob); err == nil {
+		t.Errorf("Expected a relabelled key to fail")
+	}
+}
+
+// TestSealConvergent tests that identical plaintexts give identical ciphertexts that still open
+func TestSealConvergent(t *testing.T) {
+	plaintext := []byte("the same file")
+	key1, ciphertext1, err := SealConvergent(plaintext)
+	if err != nil {
+		t.Fatalf("SealConvergent failed: %v", err)
+	}
+	key2, ciphertext2, _ := SealConvergent(append([]byte{}, plaintext...))
+	if !bytes.Equal(key1, key2) || !bytes.Equal(ciphertext1, ciphertext2) {
+		t.Fatalf("Identical plaintexts gave different ciphertexts")
+	}
+
+	opened, err := Open(key1, ciphertext1)
+	if err != nil || !bytes.Equal(opened, plaintext) {
+		t.Fatalf("Open returned %q, %v", opened, err)
+	}
+
+	if _, other, _ := SealConvergent([]byte("another file")); bytes.Equal(other, ciphertext1) {
+		t.Errorf("Different plaintexts gave the same ciphertext")
+	}
+}
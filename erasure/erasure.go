@@ -0,0 +1,192 @@
+package erasure
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Coder is a systematic Reed-Solomon coder over GF(2^8).
+// A value is split into DataShards shards and extended with parity shards
+// up to TotalShards; any DataShards of them reconstruct the value.
+type Coder struct {
+	DataShards  int
+	TotalShards int
+	matrix      [][]byte // TotalShards x DataShards encoding matrix, identity on top
+}
+
+var ErrTooFewShards = errors.New("too few shards to reconstruct")
+
+// New creates a coder producing total shards of which any data reconstruct the value
+func New(data, total int) (*Coder, error) {
+	if data < 1 || total < data || total > 256 {
+		return nil, fmt.Errorf("invalid shard counts: %d data, %d total", data, total)
+	}
+
+	// Identity rows keep the data shards verbatim. The parity rows form a
+	// Cauchy matrix 1/(x_i + y_j) with x_i = data+i and y_j = j, so every
+	// square sub-matrix of the whole encoding matrix is invertible.
+	matrix := make([][]byte, total)
+	for i := 0; i < total; i++ {
+		matrix[i] = make([]byte, data)
+		if i < data {
+			matrix[i][i] = 1
+			continue
+		}
+		for j := 0; j < data; j++ {
+			matrix[i][j] = gfInv(byte(i) ^ byte(j))
+		}
+	}
+
+	return &Coder{
+		DataShards:  data,
+		TotalShards: total,
+		matrix:      matrix,
+	}, nil
+}
+
+// ShardSize returns the size of each shard for a value of the given size
+func (c *Coder) ShardSize(size int) int {
+	if size == 0 {
+		return 1
+	}
+	return (size + c.DataShards - 1) / c.DataShards
+}
+
+// Encode splits a value into TotalShards equally sized shards.
+// The last data shard is zero padded.
+func (c *Coder) Encode(value []byte) [][]byte {
+	shardSize := c.ShardSize(len(value))
+
+	padded := make([]byte, shardSize*c.DataShards)
+	copy(padded, value)
+
+	shards := make([][]byte, c.TotalShards)
+	for i := 0; i < c.DataShards; i++ {
+		shards[i] = padded[i*shardSize : (i+1)*shardSize]
+	}
+	for i := c.DataShards; i < c.TotalShards; i++ {
+		shards[i] = make([]byte, shardSize)
+		for j := 0; j < c.DataShards; j++ {
+			gfMulAdd(shards[i], shards[j], c.matrix[i][j])
+		}
+	}
+	return shards
+}
+
+// Decode rebuilds the original value of the given size. shards must have
+// TotalShards entries with nil for the missing ones; at least DataShards
+// must be present.
+func (c *Coder) Decode(shards [][]byte, size int) ([]byte, error) {
+	if len(shards) != c.TotalShards {
+		return nil, fmt.Errorf("expected %d shards, got %d", c.TotalShards, len(shards))
+	}
+
+	// Pick the first DataShards shards we have
+	var rows []int
+	shardSize := -1
+	for i, shard := range shards {
+		if shard == nil {
+			continue
+		}
+		if shardSize == -1 {
+			shardSize = len(shard)
+		} else if len(shard) != shardSize {
+			return nil, fmt.Errorf("shard %d has size %d, expected %d", i, len(shard), shardSize)
+		}
+		rows = append(rows, i)
+		if len(rows) == c.DataShards {
+			break
+		}
+	}
+	if len(rows) < c.DataShards {
+		return nil, ErrTooFewShards
+	}
+	if size > shardSize*c.DataShards {
+		return nil, fmt.Errorf("size %d exceeds the %d bytes held by the shards", size, shardSize*c.DataShards)
+	}
+
+	// Invert the rows of the encoding matrix that produced the shards we have
+	sub := make([][]byte, c.DataShards)
+	for i, row := range rows {
+		sub[i] = append([]byte(nil), c.matrix[row]...)
+	}
+	inverse, err := invert(sub)
+	if err != nil {
+		return nil, err
+	}
+
+	value := make([]byte, shardSize*c.DataShards)
+	for i := 0; i < c.DataShards; i++ {
+		out := value[i*shardSize : (i+1)*shardSize]
+		for j, row := range rows {
+			gfMulAdd(out, shards[row], inverse[i][j])
+		}
+	}
+	return value[:size], nil
+}
+
+// Reconstruct fills in the missing (nil) shards in place
+func (c *Coder) Reconstruct(shards [][]byte) error {
+	shardSize := 0
+	for _, shard := range shards {
+		if shard != nil {
+			shardSize = len(shard)
+			break
+		}
+	}
+
+	value, err := c.Decode(shards, shardSize*c.DataShards)
+	if err != nil {
+		return err
+	}
+
+	for i, shard := range c.Encode(value) {
+		if shards[i] == nil {
+			shards[i] = shard
+		}
+	}
+	return nil
+}
+
+// invert returns the inverse of a square matrix using Gauss-Jordan elimination
+func invert(m [][]byte) ([][]byte, error) {
+	size := len(m)
+	inverse := make([][]byte, size)
+	for i := range inverse {
+		inverse[i] = make([]byte, size)
+		inverse[i][i] = 1
+	}
+
+	for col := 0; col < size; col++ {
+		pivot := -1
+		for row := col; row < size; row++ {
+			if m[row][col] != 0 {
+				pivot = row
+				break
+			}
+		}
+		if pivot == -1 {
+			return nil, errors.New("singular matrix")
+		}
+		m[col], m[pivot] = m[pivot], m[col]
+		inverse[col], inverse[pivot] = inverse[pivot], inverse[col]
+
+		scale := gfInv(m[col][col])
+		for j := 0; j < size; j++ {
+			m[col][j] = gfMul(m[col][j], scale)
+			inverse[col][j] = gfMul(inverse[col][j], scale)
+		}
+
+		for row := 0; row < size; row++ {
+			if row == col || m[row][col] == 0 {
+				continue
+			}
+			factor := m[row][col]
+			for j := 0; j < size; j++ {
+				m[row][j] ^= gfMul(factor, m[col][j])
+				inverse[row][j] ^= gfMul(factor, inverse[col][j])
+			}
+		}
+	}
+	return inverse, nil
+}
@@ -0,0 +1,79 @@
+package erasure
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+)
+
+// TestEncodeDecodeAllCombinations tests that every choice of DataShards shards rebuilds the value
+func TestEncodeDecodeAllCombinations(t *testing.T) {
+	coder, err := New(4, 6)
+	if err != nil {
+		t.Fatalf("Failed to create coder: %v", err)
+	}
+
+	value := make([]byte, 1001)
+	rand.Read(value)
+	shards := coder.Encode(value)
+
+	if len(shards) != 6 {
+		t.Fatalf("Expected 6 shards, got %d", len(shards))
+	}
+
+	// Drop every pair of shards
+	for a := 0; a < 6; a++ {
+		for b := a + 1; b < 6; b++ {
+			partial := make([][]byte, 6)
+			copy(partial, shards)
+			partial[a], partial[b] = nil, nil
+
+			decoded, err := coder.Decode(partial, len(value))
+			if err != nil {
+				t.Fatalf("Decode without shards %d,%d failed: %v", a, b, err)
+			}
+			if !bytes.Equal(decoded, value) {
+				t.Fatalf("Decode without shards %d,%d returned wrong data", a, b)
+			}
+		}
+	}
+}
+
+// TestDecodeTooFewShards tests that decoding fails below DataShards shards
+func TestDecodeTooFewShards(t *testing.T) {
+	coder, _ := New(3, 5)
+	shards := coder.Encode([]byte("not enough shards"))
+	shards[0], shards[2], shards[4] = nil, nil, nil
+
+	if _, err := coder.Decode(shards, 17); err != ErrTooFewShards {
+		t.Fatalf("Expected ErrTooFewShards, got %v", err)
+	}
+}
+
+// TestReconstruct tests that lost shards are regenerated identically
+func TestReconstruct(t *testing.T) {
+	coder, _ := New(4, 7)
+	shards := coder.Encode([]byte("shards lost and repaired"))
+
+	damaged := make([][]byte, len(shards))
+	copy(damaged, shards)
+	damaged[1], damaged[5], damaged[6] = nil, nil, nil
+
+	if err := coder.Reconstruct(damaged); err != nil {
+		t.Fatalf("Reconstruct failed: %v", err)
+	}
+	for i := range shards {
+		if !bytes.Equal(damaged[i], shards[i]) {
+			t.Errorf("Shard %d was not repaired correctly", i)
+		}
+	}
+}
+
+// TestInvalidParameters tests the shard count validation
+func TestInvalidParameters(t *testing.T) {
+	for _, p := range [][2]int{{0, 3}, {4, 3}, {10, 300}} {
+		if _, err := New(p[0], p[1]); err == nil {
+			t.Errorf("Expected error for %d data / %d total shards", p[0], p[1])
+		}
+	}
+}
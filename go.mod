@@ -2,4 +2,9 @@ module github.com/kutluhann/decentralized-file-sharing-system
 
 go 1.25.5
 
-require github.com/joho/godotenv v1.5.1
+require (
+	connectrpc.com/connect v1.21.0
+	github.com/joho/godotenv v1.5.1
+	google.golang.org/protobuf v1.36.12
+	gopkg.in/yaml.v3 v3.0.1
+)
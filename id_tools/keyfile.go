@@ -0,0 +1,330 @@
+package id_tools
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// ---------------------------------------------------------
+// KEY FILES
+// The identity key is stored as a PKCS#8 PEM file that only its
+// owner can read. With a passphrase it is encrypted with PBES2
+// (PBKDF2-SHA256 and AES-256-CBC), the format openssl writes
+// for `openssl pkcs8 -topk8 -v2 aes-256-cbc`.
+// ---------------------------------------------------------
+
+// PrivateKeyFilePath is the default path of the key file
+const PrivateKeyFilePath = "private_key.pem"
+
+// pbkdf2Iterations slows down guessing the passphrase of a stolen key file
+const pbkdf2Iterations = 600000
+
+var (
+	// ErrPassphraseRequired is returned when loading an encrypted key file without a passphrase
+	ErrPassphraseRequired = errors.New("private key file is encrypted, a passphrase is required")
+	// ErrWrongPassphrase is returned when the passphrase does not decrypt the key file
+	ErrWrongPassphrase = errors.New("wrong passphrase for private key file")
+)
+
+var (
+	oidPBES2          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2         = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	oidHMACWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidAES256CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+)
+
+// encryptedPrivateKeyInfo is the ASN.1 structure of an encrypted PKCS#8 key (RFC 5208)
+type encryptedPrivateKeyInfo struct {
+	Algorithm     pkix.AlgorithmIdentifier
+	EncryptedData []byte
+}
+
+// pbes2Params are the parameters of PBES2 (RFC 8018)
+type pbes2Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+// pbkdf2Params are the parameters of PBKDF2 (RFC 8018)
+type pbkdf2Params struct {
+	Salt           []byte
+	IterationCount int
+	KeyLength      int                      `asn1:"optional"`
+	PRF            pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+// SavePrivateKey writes key to path as a PKCS#8 PEM file with 0600 permissions,
+// encrypted when passphrase is not empty. The file is replaced atomically.
+func SavePrivateKey(path string, key *ecdsa.PrivateKey, passphrase []byte) error {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return fmt.Errorf("failed to encode private key: %w", err)
+	}
+
+	block := &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+	if len(passphrase) > 0 {
+		encrypted, err := encryptPKCS8(der, passphrase)
+		if err != nil {
+			return err
+		}
+		block = &pem.Block{Type: "ENCRYPTED PRIVATE KEY", Bytes: encrypted}
+	}
+	return writeFileAtomic(path, pem.EncodeToMemory(block))
+}
+
+// LoadPrivateKey reads the key file at path. A key file in the raw format of
+// earlier versions is converted to PKCS#8 PEM in place.
+func LoadPrivateKey(path string, passphrase []byte) (*ecdsa.PrivateKey, PeerID, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, PeerID{}, fmt.Errorf("failed to read private key file: %w", err)
+	}
+	if info, err := os.Stat(path); err == nil && info.Mode().Perm()&0077 != 0 && runtime.GOOS != "windows" {
+		fmt.Printf("[IDENTITY] Warning: %s is readable by other users (%s), run chmod 600\n", path, info.Mode().Perm())
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		key, err := ecdsa.ParseRawPrivateKey(ellipticCurve, data)
+		if err != nil {
+			return nil, PeerID{}, fmt.Errorf("private key file is neither PEM nor a raw key")
+		}
+		if err := SavePrivateKey(path, key, passphrase); err != nil {
+			return nil, PeerID{}, fmt.Errorf("failed to convert legacy key file: %w", err)
+		}
+		fmt.Printf("[IDENTITY] Converted legacy key file %s to PKCS#8 PEM\n", path)
+		return key, GeneratePeerIDFromPublicKey(&key.PublicKey), nil
+	}
+
+	der := block.Bytes
+	switch block.Type {
+	case "PRIVATE KEY":
+	case "ENCRYPTED PRIVATE KEY":
+		if len(passphrase) == 0 {
+			return nil, PeerID{}, ErrPassphraseRequired
+		}
+		if der, err = decryptPKCS8(der, passphrase); err != nil {
+			return nil, PeerID{}, err
+		}
+	default:
+		return nil, PeerID{}, fmt.Errorf("unexpected PEM block %q in private key file", block.Type)
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		if block.Type == "ENCRYPTED PRIVATE KEY" {
+			return nil, PeerID{}, ErrWrongPassphrase
+		}
+		return nil, PeerID{}, fmt.Errorf("failed to parse private key: %w", err)
+	}
+	key, ok := parsed.(*ecdsa.PrivateKey)
+	if !ok || key.Curve != ellipticCurve {
+		return nil, PeerID{}, fmt.Errorf("private key is not an ECDSA P-256 key")
+	}
+	return key, GeneratePeerIDFromPublicKey(&key.PublicKey), nil
+}
+
+// EncodePublicKey returns the public key as a PKIX PEM block, the format of `openssl pkey -pubout`
+func EncodePublicKey(publicKey *ecdsa.PublicKey) ([]byte, error) {
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode public key: %w", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+}
+
+// ParsePublicKey reads a public key written by EncodePublicKey
+func ParsePublicKey(data []byte) (*ecdsa.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, fmt.Errorf("not a PEM public key")
+	}
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key: %w", err)
+	}
+	key, ok := parsed.(*ecdsa.PublicKey)
+	if !ok || key.Curve != ellipticCurve {
+		return nil, fmt.Errorf("public key is not an ECDSA P-256 key")
+	}
+	return key, nil
+}
+
+// RotatePrivateKey replaces the key file at path with a new key encrypted with
+// the same passphrase. The old key file is kept next to it, named after the old
+// PeerID, so values signed with the old key can still be deleted.
+// Returns the old and the new key.
+func RotatePrivateKey(path string, passphrase []byte) (*ecdsa.PrivateKey, *ecdsa.PrivateKey, error) {
+	oldKey, oldPeerID, err := LoadPrivateKey(path, passphrase)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	archive := fmt.Sprintf("%s.%x.old", path, oldPeerID[:8])
+	if err := SavePrivateKey(archive, oldKey, passphrase); err != nil {
+		return nil, nil, fmt.Errorf("failed to keep the old key: %w", err)
+	}
+
+	newKey, _ := GenerateNewPID()
+	if err := SavePrivateKey(path, newKey, passphrase); err != nil {
+		return nil, nil, err
+	}
+	return oldKey, newKey, nil
+}
+
+// writeFileAtomic writes data to a temporary file only the owner can read and
+// renames it to path, so a crash never leaves a half written key behind
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create key directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, ".key-*")
+	if err != nil {
+		return fmt.Errorf("failed to create private key file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0600); err != nil && runtime.GOOS != "windows" {
+		tmp.Close()
+		return fmt.Errorf("failed to restrict private key file: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write private key file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write private key file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write private key file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace private key file: %w", err)
+	}
+	return nil
+}
+
+// encryptPKCS8 encrypts a PKCS#8 key with PBES2 and returns an EncryptedPrivateKeyInfo
+func encryptPKCS8(der []byte, passphrase []byte) ([]byte, error) {
+	salt := make([]byte, 16)
+	iv := make([]byte, aes.BlockSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(iv); err != nil {
+		return nil, err
+	}
+
+	key, err := pbkdf2.Key(sha256.New, string(passphrase), salt, pbkdf2Iterations, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	padding := aes.BlockSize - len(der)%aes.BlockSize
+	plaintext := append(append([]byte(nil), der...), bytes.Repeat([]byte{byte(padding)}, padding)...)
+	ciphertext := make([]byte, len(plaintext))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, plaintext)
+
+	kdfParams, err := asn1.Marshal(pbkdf2Params{
+		Salt:           salt,
+		IterationCount: pbkdf2Iterations,
+		PRF:            pkix.AlgorithmIdentifier{Algorithm: oidHMACWithSHA256, Parameters: asn1.NullRawValue},
+	})
+	if err != nil {
+		return nil, err
+	}
+	ivParam, err := asn1.Marshal(iv)
+	if err != nil {
+		return nil, err
+	}
+	params, err := asn1.Marshal(pbes2Params{
+		KeyDerivationFunc: pkix.AlgorithmIdentifier{Algorithm: oidPBKDF2, Parameters: asn1.RawValue{FullBytes: kdfParams}},
+		EncryptionScheme:  pkix.AlgorithmIdentifier{Algorithm: oidAES256CBC, Parameters: asn1.RawValue{FullBytes: ivParam}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(encryptedPrivateKeyInfo{
+		Algorithm:     pkix.AlgorithmIdentifier{Algorithm: oidPBES2, Parameters: asn1.RawValue{FullBytes: params}},
+		EncryptedData: ciphertext,
+	})
+}
+
+// decryptPKCS8 decrypts an EncryptedPrivateKeyInfo written by encryptPKCS8 or openssl
+func decryptPKCS8(data []byte, passphrase []byte) ([]byte, error) {
+	var info encryptedPrivateKeyInfo
+	if _, err := asn1.Unmarshal(data, &info); err != nil {
+		return nil, fmt.Errorf("invalid encrypted private key: %w", err)
+	}
+	if !info.Algorithm.Algorithm.Equal(oidPBES2) {
+		return nil, fmt.Errorf("unsupported key encryption %s, only PBES2 is supported", info.Algorithm.Algorithm)
+	}
+
+	var params pbes2Params
+	if _, err := asn1.Unmarshal(info.Algorithm.Parameters.FullBytes, &params); err != nil {
+		return nil, fmt.Errorf("invalid PBES2 parameters: %w", err)
+	}
+	if !params.KeyDerivationFunc.Algorithm.Equal(oidPBKDF2) || !params.EncryptionScheme.Algorithm.Equal(oidAES256CBC) {
+		return nil, fmt.Errorf("unsupported key encryption, only PBKDF2 with AES-256-CBC is supported")
+	}
+
+	var kdf pbkdf2Params
+	if _, err := asn1.Unmarshal(params.KeyDerivationFunc.Parameters.FullBytes, &kdf); err != nil {
+		return nil, fmt.Errorf("invalid PBKDF2 parameters: %w", err)
+	}
+	if !kdf.PRF.Algorithm.Equal(oidHMACWithSHA256) {
+		return nil, fmt.Errorf("unsupported PBKDF2 hash, only HMAC-SHA256 is supported")
+	}
+	var iv []byte
+	if _, err := asn1.Unmarshal(params.EncryptionScheme.Parameters.FullBytes, &iv); err != nil || len(iv) != aes.BlockSize {
+		return nil, fmt.Errorf("invalid AES-256-CBC parameters")
+	}
+	if len(info.EncryptedData) == 0 || len(info.EncryptedData)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("invalid encrypted private key length")
+	}
+
+	key, err := pbkdf2.Key(sha256.New, string(passphrase), kdf.Salt, kdf.IterationCount, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	plaintext := make([]byte, len(info.EncryptedData))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plaintext, info.EncryptedData)
+
+	// A wrong passphrase almost always shows up as broken padding
+	padding := int(plaintext[len(plaintext)-1])
+	if padding == 0 || padding > aes.BlockSize {
+		return nil, ErrWrongPassphrase
+	}
+	for _, b := range plaintext[len(plaintext)-padding:] {
+		if int(b) != padding {
+			return nil, ErrWrongPassphrase
+		}
+	}
+	return plaintext[:len(plaintext)-padding], nil
+}
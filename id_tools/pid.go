@@ -5,73 +5,48 @@ import (
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
 	"log"
-	"os"
 
 	"github.com/kutluhann/decentralized-file-sharing-system/constants"
 )
 
-// constant for file storage path
-const PrivateKeyFilePath = "private_key.pem"
-
 var ellipticCurve = elliptic.P256()
 
 // typedef peerID as SHA256 type, it is not a string
 type PeerID [32]byte
 
-func GenerateNewPID() (*ecdsa.PrivateKey, PeerID) {
-
-	privateKey, err := ecdsa.GenerateKey(ellipticCurve, rand.Reader)
-
-	if err != nil {
-		log.Fatal("Error generating ECDSA private key:", err)
-	}
-
-	peerID := GeneratePeerIDFromPublicKey(&privateKey.PublicKey)
-
-	return privateKey, peerID
+func (p PeerID) String() string {
+	return hex.EncodeToString(p[:])
 }
 
-func SavePrivateKey(key *ecdsa.PrivateKey) {
-
-	file, err := os.Create(PrivateKeyFilePath)
+// ParsePeerID parses the hex form returned by String
+func ParsePeerID(s string) (PeerID, error) {
+	var id PeerID
+	b, err := hex.DecodeString(s)
 	if err != nil {
-		log.Fatal("Error creating private key file:", err)
+		return id, fmt.Errorf("invalid PeerID: %w", err)
 	}
-	defer file.Close()
-
-	keyBytes, _ := key.Bytes()
-	_, err = file.Write(keyBytes)
-	if err != nil {
-		log.Fatal("Error writing private key to file:", err)
+	if len(b) != len(id) {
+		return id, fmt.Errorf("invalid PeerID: expected %d bytes, got %d", len(id), len(b))
 	}
-
+	copy(id[:], b)
+	return id, nil
 }
 
-func LoadPrivateKey() (*ecdsa.PrivateKey, PeerID) {
-	file, err := os.Open(PrivateKeyFilePath)
-	if err != nil {
-		log.Fatal("Error opening private key file:", err)
-	}
-	defer file.Close()
-
-	fileInfo, _ := file.Stat()
+func GenerateNewPID() (*ecdsa.PrivateKey, PeerID) {
 
-	keyBytes := make([]byte, fileInfo.Size())
-	_, err = file.Read(keyBytes)
-	if err != nil {
-		log.Fatal("Error reading private key from file:", err)
-	}
+	privateKey, err := ecdsa.GenerateKey(ellipticCurve, rand.Reader)
 
-	privateKey, err := ecdsa.ParseRawPrivateKey(ellipticCurve, keyBytes)
 	if err != nil {
-		log.Fatal("Error parsing private key:", err)
+		log.Fatal("Error generating ECDSA private key:", err)
 	}
 
 	peerID := GeneratePeerIDFromPublicKey(&privateKey.PublicKey)
 
 	return privateKey, peerID
-
 }
 
 func GeneratePeerIDFromPublicKey(pubKey *ecdsa.PublicKey) PeerID {
@@ -109,6 +84,25 @@ func VerifySignature(publicKey ecdsa.PublicKey, message string, signature []byte
 	return valid
 }
 
+// SignFile signs the SHA-256 digest of everything read from r. The ASN.1
+// signature is the one `openssl dgst -sha256 -sign` writes.
+func SignFile(privateKey *ecdsa.PrivateKey, r io.Reader) ([]byte, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return nil, err
+	}
+	return ecdsa.SignASN1(rand.Reader, privateKey, h.Sum(nil))
+}
+
+// VerifyFile checks a signature made by SignFile over everything read from r
+func VerifyFile(publicKey *ecdsa.PublicKey, r io.Reader, signature []byte) (bool, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return false, err
+	}
+	return ecdsa.VerifyASN1(publicKey, h.Sum(nil), signature), nil
+}
+
 func VerifyIdentity(privateKey *ecdsa.PrivateKey, peerID PeerID) bool {
 	if !CheckPublicKeyMatchesPeerID(&privateKey.PublicKey, peerID) {
 		log.Println("Error: Public Key does not match Peer ID")
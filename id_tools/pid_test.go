@@ -0,0 +1,39 @@
+package id_tools
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestParsePeerID tests that a PeerID parses back from its hex form and malformed ones are refused
+func TestParsePeerID(t *testing.T) {
+	_, peerID := GenerateNewPID()
+	if parsed, err := ParsePeerID(peerID.String()); err != nil || parsed != peerID {
+		t.Fatalf("PeerID did not round trip: %v", err)
+	}
+	for _, s := range []string{"", "zz", peerID.String()[:62]} {
+		if _, err := ParsePeerID(s); err == nil {
+			t.Errorf("Expected %q to be refused", s)
+		}
+	}
+}
+
+// TestSignFile tests that file signatures match SignMessage and detect changed content
+func TestSignFile(t *testing.T) {
+	key, _ := GenerateNewPID()
+	content := "release notes"
+
+	signature, err := SignFile(key, strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("SignFile failed: %v", err)
+	}
+	if !VerifySignature(key.PublicKey, content, signature) {
+		t.Errorf("Expected a file signature to verify as a message signature")
+	}
+	if valid, err := VerifyFile(&key.PublicKey, strings.NewReader(content), signature); err != nil || !valid {
+		t.Errorf("Valid signature was refused: %v", err)
+	}
+	if valid, _ := VerifyFile(&key.PublicKey, strings.NewReader(content+"!"), signature); valid {
+		t.Errorf("Signature verified changed content")
+	}
+}
@@ -1,40 +1,37 @@
 package main
 
 import (
+	"context"
 	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
-	"net"
 	"os"
-	"time"
+	"os/signal"
+	"syscall"
 
-	"github.com/kutluhann/decentralized-file-sharing-system/api"
+	"github.com/kutluhann/decentralized-file-sharing-system/config"
 	"github.com/kutluhann/decentralized-file-sharing-system/dht"
 	"github.com/kutluhann/decentralized-file-sharing-system/id_tools"
+	"github.com/kutluhann/decentralized-file-sharing-system/node"
 )
 
 func main() {
-	isGenesis := flag.Bool("genesis", false, "Start as a Genesis Node (no bootstrap)")
-	port := flag.Int("port", 8080, "UDP port to listen on")
-	httpPort := flag.Int("http", 8000, "HTTP API port for client requests")
-	bootstrapIP := flag.String("bootstrap", "", "Bootstrap Node IP:Port (e.g. 127.0.0.1:8080)")
-	flag.Parse()
-
-	fmt.Printf("Starting DHT Node on port %d...\n", *port)
-
-	var privateKey *ecdsa.PrivateKey
-	var peerID id_tools.PeerID
+	cfg, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		log.Fatalf("FATAL: %v", err)
+	}
 
-	keyFile := "private_key.pem"
+	fmt.Printf("Starting DHT Node on port %d...\n", cfg.Network.Port)
 
-	if _, err := os.Stat(keyFile); err == nil {
-		fmt.Println("Loading existing private key from", keyFile)
-		privateKey, peerID = id_tools.LoadPrivateKey()
-	} else {
-		fmt.Println("Generating new identity...")
-		privateKey, peerID = id_tools.GenerateNewPID()
-		id_tools.SavePrivateKey(privateKey)
+	privateKey, peerID, err := loadIdentity(cfg.Identity)
+	if err != nil {
+		log.Fatalf("FATAL: %v", err)
 	}
 
 	fmt.Println("Verifying identity integrity...")
@@ -43,86 +40,100 @@ func main() {
 	}
 	fmt.Println("Identity verified successfully.")
 
-	contact := dht.Contact{
-		ID:       dht.NodeID(peerID),
-		IP:       "127.0.0.1",
-		Port:     *port,
-		LastSeen: time.Now(),
-	}
+	nodeConfig := cfg.Node()
+	nodeConfig.PrivateKey = privateKey
 
-	network, err := dht.NewNetwork(fmt.Sprintf(":%d", *port), dht.NodeID(peerID))
+	n, err := node.New(nodeConfig)
 	if err != nil {
-		log.Fatalf("Failed to start network: %v", err)
+		log.Fatalf("FATAL: %v", err)
 	}
-
-	node := dht.NewNode(contact, privateKey)
-	node.Network = network
-	network.SetHandler(node)
-
-	fmt.Printf("Node initialized with ID: %s\n", node.Self.ID.String())
-
-	// Initialize Proof of Space plot for Sybil resistance
-	fmt.Println("Initializing Proof of Space...")
-	if err := node.InitializePosPlot(); err != nil {
-		log.Fatalf("Failed to initialize PoS plot: %v", err)
+	if err := n.Start(context.Background()); err != nil {
+		log.Fatalf("FATAL: %v", err)
+	}
+	go publishRotation(n, cfg.Identity.KeyFile+".rotation")
+
+	// On SIGINT or SIGTERM hand our keys off before exiting, a second signal exits at once
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	<-ctx.Done()
+	stop()
+
+	fmt.Println("Shutting down, press Ctrl+C again to exit immediately...")
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeouts.Leave)
+	defer cancel()
+	if err := n.Shutdown(ctx); err != nil {
+		log.Printf("Shutdown: %v", err)
 	}
-	fmt.Println("✓ Proof of Space ready")
+}
 
-	// Start UDP network listener for DHT protocol
-	go network.Listen()
+// loadIdentity loads the key file, generating it on the first start and
+// replacing the key first when asked to rotate it
+func loadIdentity(c config.IdentityConfig) (*ecdsa.PrivateKey, id_tools.PeerID, error) {
+	passphrase, err := c.Passphrase()
+	if err != nil {
+		return nil, id_tools.PeerID{}, err
+	}
 
-	// Start HTTP API server for client requests
-	httpServer := api.NewHTTPServer(node, *httpPort)
-	go func() {
-		err := httpServer.Start()
-		if err != nil {
-			log.Fatalf("HTTP server failed: %v", err)
+	if _, err := os.Stat(c.KeyFile); errors.Is(err, os.ErrNotExist) {
+		if c.Rotate {
+			return nil, id_tools.PeerID{}, fmt.Errorf("no key file at %s to rotate", c.KeyFile)
 		}
-	}()
-
-	fmt.Printf("HTTP API listening on port %d\n", *httpPort)
-
-	if *isGenesis {
-		fmt.Println("--> Running as GENESIS Node. Waiting for connections...")
-	} else {
-		if *bootstrapIP == "" {
-			log.Fatal("FATAL: Bootstrap address required for non-genesis nodes. Use -bootstrap flag (e.g., -bootstrap 127.0.0.1:8080)")
+		fmt.Println("Generating new identity...")
+		privateKey, peerID := id_tools.GenerateNewPID()
+		if err := id_tools.SavePrivateKey(c.KeyFile, privateKey, passphrase); err != nil {
+			return nil, id_tools.PeerID{}, err
 		}
+		return privateKey, peerID, nil
+	}
 
-		_, err := net.ResolveUDPAddr("udp", *bootstrapIP)
+	if c.Rotate {
+		oldKey, newKey, err := id_tools.RotatePrivateKey(c.KeyFile, passphrase)
 		if err != nil {
-			log.Fatalf("FATAL: Invalid bootstrap address format '%s': %v\n", *bootstrapIP, err)
+			return nil, id_tools.PeerID{}, passphraseHint(err)
 		}
-
-		fmt.Printf("--> Bootstrapping... Connecting to %s\n", *bootstrapIP)
-
-		// Step 1: Secure Handshake (Authentication)
-		bootstrapContact, err := node.JoinNetwork(*bootstrapIP)
+		record, err := dht.NewRotation(oldKey, newKey)
 		if err != nil {
-			log.Fatalf("FATAL: Failed to join network: %v\n", err)
+			return nil, id_tools.PeerID{}, err
 		}
+		// Kept until the network stored it, so a failed publish is retried on the next start
+		value, _ := json.Marshal(record)
+		if err := os.WriteFile(c.KeyFile+".rotation", value, 0600); err != nil {
+			return nil, id_tools.PeerID{}, fmt.Errorf("failed to save rotation record: %v", err)
+		}
+		fmt.Println("Rotated identity key, the old key was kept next to", c.KeyFile)
+	}
 
-		fmt.Println("✓ Secure handshake complete!")
-		fmt.Printf("✓ Bootstrap node: %s at %s:%d\n",
-			bootstrapContact.ID.String()[:16], bootstrapContact.IP, bootstrapContact.Port)
-
-		fmt.Printf("[JOIN] Starting Kademlia bootstrap process\n")
-
-		// 1. Add the bootstrap node to our routing table
-		node.RoutingTable.Update(bootstrapContact)
-		fmt.Printf("[JOIN] Added bootstrap node %s to routing table\n",
-			bootstrapContact.ID.String()[:16])
-
-		// 2. Perform a Self-Lookup
-		// This is the core of Kademlia's bootstrap: by looking up our own ID,
-		// we populate the buckets closest to us, which are the most important.
-		fmt.Printf("[JOIN] Performing self-lookup to populate routing table\n")
-		closestNodes, lookupHops := node.NodeLookup(node.Self.ID)
-
-		fmt.Printf("[JOIN] ✓ Bootstrap complete. Found %d nodes close to self (hops: %d)\n", len(closestNodes), lookupHops)
+	fmt.Println("Loading existing private key from", c.KeyFile)
+	privateKey, peerID, err := id_tools.LoadPrivateKey(c.KeyFile, passphrase)
+	return privateKey, peerID, passphraseHint(err)
+}
 
-		fmt.Println("✓ Successfully joined the network!")
+// passphraseHint explains how to pass the passphrase of an encrypted key file
+func passphraseHint(err error) error {
+	if errors.Is(err, id_tools.ErrPassphraseRequired) {
+		return fmt.Errorf("%v: set %s or identity.passphrase_file", err, config.PassphraseEnv)
 	}
+	return err
+}
 
-	select {}
+// publishRotation stores a pending rotation record in the DHT and removes it
+// once another node holds it, a copy held only by us would be lost with this node
+func publishRotation(n *node.Node, path string) {
+	value, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	record, ok := dht.ParseRotation(value)
+	if !ok {
+		log.Printf("Ignoring invalid rotation record %s", path)
+		return
+	}
+	replicas, err := n.DHT.PublishRotation(record)
+	if err == nil && replicas == 0 {
+		err = fmt.Errorf("no other node accepted the record")
+	}
+	if err != nil {
+		log.Printf("Failed to publish key rotation, retrying on the next start: %v", err)
+		return
+	}
+	os.Remove(path)
 }
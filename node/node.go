@@ -0,0 +1,310 @@
+package node
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/kutluhann/decentralized-file-sharing-system/api"
+	"github.com/kutluhann/decentralized-file-sharing-system/constants"
+	"github.com/kutluhann/decentralized-file-sharing-system/dht"
+	"github.com/kutluhann/decentralized-file-sharing-system/id_tools"
+)
+
+// ---------------------------------------------------------
+// NODE
+// A complete DHT node: the UDP listener, the replication
+// scheduler and the HTTP API. New binds the sockets, Start joins
+// the network and Close stops everything again, so a program can
+// embed a node and a test can run many of them in one process.
+// ---------------------------------------------------------
+
+// Config describes a node
+type Config struct {
+	Host            string            // IP address peers reach this node at
+	Port            int               // UDP port of the DHT protocol, 0 picks a free port
+	HTTPPort        int               // Port of the HTTP API, 0 picks a free port, -1 disables the API
+	Genesis         bool              // Start a new network instead of joining one
+	Bootstrap       string            // UDP address (IP:Port) of a node to join through, unless Genesis
+	PrivateKey      *ecdsa.PrivateKey // Identity of the node, a new one when nil
+	DHT             dht.Config        // Protocol parameters and timeouts
+	Limits          dht.StoreLimits   // Storage quotas and STORE rate limits
+	MaxUploadBytes  int64             // Largest value the HTTP API accepts
+	ShutdownTimeout time.Duration     // How long Close waits for active HTTP requests
+}
+
+// DefaultConfig returns the configuration of a node on the default ports
+func DefaultConfig() Config {
+	return Config{
+		Host:            "127.0.0.1",
+		Port:            8080,
+		HTTPPort:        8000,
+		DHT:             dht.DefaultConfig(),
+		Limits:          dht.DefaultStoreLimits(),
+		MaxUploadBytes:  constants.MaxUploadBytes,
+		ShutdownTimeout: constants.ShutdownTimeout * time.Second,
+	}
+}
+
+// Node runs a DHT node and its HTTP API
+type Node struct {
+	DHT  *dht.Node
+	HTTP *api.HTTPServer // nil when the HTTP API is disabled
+
+	config       Config
+	network      *dht.Network
+	httpListener net.Listener
+	wg           sync.WaitGroup // Listener, HTTP server and background work started by Start
+
+	mutex     sync.Mutex
+	started   bool
+	stopAfter func() bool // Stops closing the node with the context of Start
+	closeOnce sync.Once
+	closeErr  error
+	done      chan struct{}
+}
+
+// New creates a node and binds its sockets. Nothing runs until Start.
+func New(config Config) (*Node, error) {
+	if err := config.DHT.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid DHT configuration: %v", err)
+	}
+	if !config.Genesis {
+		if config.Bootstrap == "" {
+			return nil, fmt.Errorf("a bootstrap address is required for non-genesis nodes")
+		}
+		if _, err := net.ResolveUDPAddr("udp", config.Bootstrap); err != nil {
+			return nil, fmt.Errorf("invalid bootstrap address '%s': %v", config.Bootstrap, err)
+		}
+	}
+
+	privateKey := config.PrivateKey
+	var peerID id_tools.PeerID
+	if privateKey == nil {
+		privateKey, peerID = id_tools.GenerateNewPID()
+	} else {
+		peerID = id_tools.GeneratePeerIDFromPublicKey(&privateKey.PublicKey)
+	}
+
+	network, err := dht.NewNetwork(fmt.Sprintf(":%d", config.Port), dht.NodeID(peerID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to start network: %v", err)
+	}
+
+	contact := dht.Contact{
+		ID:       dht.NodeID(peerID),
+		IP:       config.Host,
+		Port:     network.Conn.LocalAddr().(*net.UDPAddr).Port,
+		LastSeen: time.Now(),
+	}
+	network.Timeout = config.DHT.RPCTimeout
+	node := dht.NewNodeWithConfig(contact, privateKey, config.DHT)
+	node.Network = network
+	node.Limits = config.Limits
+	network.SetHandler(node)
+
+	n := &Node{
+		DHT:     node,
+		config:  config,
+		network: network,
+		done:    make(chan struct{}),
+	}
+
+	if config.HTTPPort >= 0 {
+		n.httpListener, err = net.Listen("tcp", fmt.Sprintf(":%d", config.HTTPPort))
+		if err != nil {
+			network.Close()
+			return nil, fmt.Errorf("failed to start HTTP API: %v", err)
+		}
+		node.HTTPPort = n.httpListener.Addr().(*net.TCPAddr).Port
+		n.HTTP = api.NewHTTPServer(node, node.HTTPPort)
+		n.HTTP.MaxUploadBytes = config.MaxUploadBytes
+	}
+
+	fmt.Printf("Node initialized with ID: %s\n", node.Self.ID.String())
+
+	// Initialize Proof of Space plot for Sybil resistance
+	if err := node.InitializePosPlot(); err != nil {
+		n.release()
+		return nil, fmt.Errorf("failed to initialize PoS plot: %v", err)
+	}
+	return n, nil
+}
+
+// HTTPURL returns the base URL of the HTTP API, empty when it is disabled
+func (n *Node) HTTPURL() string {
+	if n.HTTP == nil {
+		return ""
+	}
+	return fmt.Sprintf("http://%s", net.JoinHostPort(n.config.Host, fmt.Sprint(n.DHT.HTTPPort)))
+}
+
+// Start starts serving and joins the network through the bootstrap node. It
+// returns once the node joined, and the node runs until Close is called or
+// ctx is done.
+func (n *Node) Start(ctx context.Context) error {
+	n.mutex.Lock()
+	if n.started {
+		n.mutex.Unlock()
+		return fmt.Errorf("node already started")
+	}
+	n.started = true
+	n.mutex.Unlock()
+
+	select {
+	case <-n.done:
+		return fmt.Errorf("node is closed")
+	default:
+	}
+
+	// Start UDP network listener for DHT protocol
+	n.wg.Go(n.network.Listen)
+
+	// Start periodic re-replication of stored keys
+	n.DHT.Replication.Start()
+
+	// Start HTTP API server for client requests
+	if n.HTTP != nil {
+		n.wg.Go(func() {
+			if err := n.HTTP.Serve(n.httpListener); !errors.Is(err, http.ErrServerClosed) {
+				fmt.Printf("[NODE] ✗ HTTP server failed: %v\n", err)
+			}
+		})
+		fmt.Printf("HTTP API listening on port %d\n", n.DHT.HTTPPort)
+	}
+
+	if n.config.Genesis {
+		fmt.Println("--> Running as GENESIS Node. Waiting for connections...")
+	} else if err := n.join(); err != nil {
+		n.Close()
+		return err
+	}
+
+	// Publish our public key so peers can share encrypted values with us
+	n.wg.Go(func() {
+		if err := n.DHT.PublishIdentity(); err != nil {
+			fmt.Printf("[IDENTITY] ✗ Failed to publish identity: %v\n", err)
+		}
+	})
+
+	stopAfter := context.AfterFunc(ctx, func() { n.Close() })
+	n.mutex.Lock()
+	n.stopAfter = stopAfter
+	n.mutex.Unlock()
+	return nil
+}
+
+// join authenticates with the bootstrap node and fills the routing table
+func (n *Node) join() error {
+	fmt.Printf("--> Bootstrapping... Connecting to %s\n", n.config.Bootstrap)
+
+	// Step 1: Secure Handshake (Authentication)
+	bootstrapContact, err := n.DHT.JoinNetwork(n.config.Bootstrap)
+	if err != nil {
+		return fmt.Errorf("failed to join network: %v", err)
+	}
+
+	fmt.Println("✓ Secure handshake complete!")
+	fmt.Printf("✓ Bootstrap node: %s at %s:%d\n",
+		bootstrapContact.ID.String()[:16], bootstrapContact.IP, bootstrapContact.Port)
+
+	fmt.Printf("[JOIN] Starting Kademlia bootstrap process\n")
+
+	// 1. Add the bootstrap node to our routing table
+	n.DHT.RoutingTable.Update(bootstrapContact)
+	fmt.Printf("[JOIN] Added bootstrap node %s to routing table\n",
+		bootstrapContact.ID.String()[:16])
+
+	// 2. Perform a Self-Lookup
+	// This is the core of Kademlia's bootstrap: by looking up our own ID,
+	// we populate the buckets closest to us, which are the most important.
+	fmt.Printf("[JOIN] Performing self-lookup to populate routing table\n")
+	closestNodes, lookupHops := n.DHT.NodeLookup(n.DHT.Self.ID)
+
+	fmt.Printf("[JOIN] ✓ Bootstrap complete. Found %d nodes close to self (hops: %d)\n", len(closestNodes), lookupHops)
+
+	// 3. Pull the keys our new neighbours hold on our behalf
+	n.wg.Go(func() { n.DHT.SyncWithNeighbours() })
+
+	fmt.Println("✓ Successfully joined the network!")
+	return nil
+}
+
+// Shutdown leaves the network gracefully and closes the node: the HTTP API
+// stops taking requests, the DHT refuses new STOREs, every key is handed to
+// the node that takes our place and our contacts drop us from their routing
+// tables. When ctx is done first the node closes without finishing the handoff.
+func (n *Node) Shutdown(ctx context.Context) error {
+	n.mutex.Lock()
+	started := n.started
+	n.mutex.Unlock()
+	if !started {
+		return n.Close()
+	}
+
+	if n.HTTP != nil {
+		if err := n.HTTP.Shutdown(ctx); err != nil {
+			fmt.Printf("[NODE] ✗ HTTP server did not stop cleanly: %v\n", err)
+		}
+	}
+	n.DHT.Replication.Stop()
+
+	// Once the sockets are closed an unfinished handoff fails fast, Close waits for it
+	left := make(chan struct{})
+	n.wg.Go(func() {
+		defer close(left)
+		n.DHT.Leave()
+	})
+	select {
+	case <-left:
+	case <-ctx.Done():
+		fmt.Printf("[NODE] ✗ Handoff interrupted: %v\n", ctx.Err())
+	}
+	return n.Close()
+}
+
+// Close stops the replication scheduler, waits for active HTTP requests up to
+// the ShutdownTimeout of the configuration, closes the sockets and waits for the goroutines
+// Start began. Stored values only live in memory and are gone afterwards.
+// Close may be called more than once and before Start.
+func (n *Node) Close() error {
+	n.closeOnce.Do(func() {
+		n.mutex.Lock()
+		if n.stopAfter != nil {
+			n.stopAfter()
+		}
+		n.mutex.Unlock()
+
+		n.DHT.Replication.Stop()
+
+		if n.HTTP != nil {
+			ctx, cancel := context.WithTimeout(context.Background(), n.config.ShutdownTimeout)
+			n.closeErr = n.HTTP.Shutdown(ctx)
+			cancel()
+		}
+		n.release()
+		n.wg.Wait()
+
+		fmt.Printf("[NODE] ✓ Node %s stopped\n", n.DHT.Self.ID.String()[:16])
+		close(n.done)
+	})
+	return n.closeErr
+}
+
+// release closes the sockets
+func (n *Node) release() {
+	if n.httpListener != nil {
+		n.httpListener.Close()
+	}
+	n.network.Close()
+}
+
+// Done is closed once the node is closed
+func (n *Node) Done() <-chan struct{} {
+	return n.done
+}
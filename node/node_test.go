@@ -0,0 +1,133 @@
+package node
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/kutluhann/decentralized-file-sharing-system/dht"
+)
+
+// testConfig returns the configuration of a node on free ports
+func testConfig(t *testing.T) Config {
+	config := DefaultConfig()
+	config.Port = 0
+	config.HTTPPort = 0
+	config.DHT.PlotDir = t.TempDir()
+	config.DHT.PosPrefixBits = 8 // A small plot still answers almost every challenge
+	config.DHT.PosNumEntries = 4096
+	return config
+}
+
+// TestNodeLifecycle starts a genesis node and a node joining it, then stops both
+func TestNodeLifecycle(t *testing.T) {
+	config := testConfig(t)
+	config.Genesis = true
+	genesis, err := New(config)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer genesis.Close()
+	if err := genesis.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	config = testConfig(t)
+	config.Bootstrap = fmt.Sprintf("127.0.0.1:%d", genesis.DHT.Self.Port)
+	joiner, err := New(config)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := joiner.Start(ctx); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	key := dht.NodeID(sha256.Sum256([]byte("lifecycle")))
+	if err := joiner.DHT.Store(key, []byte("value")); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	if value, _, err := genesis.DHT.FindValue(key); err != nil || !bytes.Equal(value, []byte("value")) {
+		t.Fatalf("FindValue returned %q, %v", value, err)
+	}
+	resp, err := http.Get(joiner.HTTPURL() + "/health")
+	if err != nil {
+		t.Fatalf("HTTP API unreachable: %v", err)
+	}
+	resp.Body.Close()
+
+	// Cancelling the context of Start closes the node
+	cancel()
+	select {
+	case <-joiner.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Node did not stop after its context was cancelled")
+	}
+	if err := joiner.Close(); err != nil {
+		t.Errorf("Second Close failed: %v", err)
+	}
+
+	// Both ports are free again
+	udp, err := net.ListenUDP("udp", &net.UDPAddr{Port: joiner.DHT.Self.Port})
+	if err != nil {
+		t.Errorf("UDP port still in use: %v", err)
+	} else {
+		udp.Close()
+	}
+	if _, err := http.Get(joiner.HTTPURL() + "/health"); err == nil {
+		t.Errorf("HTTP API still answers after Close")
+	}
+}
+
+// TestNewRequiresBootstrap tests that a non-genesis node needs a bootstrap node
+func TestNewRequiresBootstrap(t *testing.T) {
+	if _, err := New(testConfig(t)); err == nil {
+		t.Errorf("Expected an error without a bootstrap address")
+	}
+}
+
+// TestNodeShutdown tests that a node shutting down is dropped by its peers
+func TestNodeShutdown(t *testing.T) {
+	config := testConfig(t)
+	config.Genesis = true
+	config.HTTPPort = -1
+	genesis, err := New(config)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer genesis.Close()
+	if err := genesis.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	config = testConfig(t)
+	config.Bootstrap = fmt.Sprintf("127.0.0.1:%d", genesis.DHT.Self.Port)
+	leaver, err := New(config)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if err := leaver.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := leaver.Shutdown(ctx); err != nil {
+		t.Errorf("Shutdown failed: %v", err)
+	}
+	select {
+	case <-leaver.Done():
+	default:
+		t.Errorf("Node is not closed after Shutdown")
+	}
+	for _, c := range genesis.DHT.RoutingTable.GetClosestNodes(leaver.DHT.Self.ID, 10) {
+		if c.ID == leaver.DHT.Self.ID {
+			t.Errorf("Genesis node still knows the node that left")
+		}
+	}
+}
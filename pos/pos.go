@@ -10,7 +10,6 @@ import (
 	"sort"
 	"strings"
 
-	"github.com/kutluhann/decentralized-file-sharing-system/constants"
 	"github.com/kutluhann/decentralized-file-sharing-system/id_tools"
 )
 
@@ -23,9 +22,10 @@ type PlotEntry struct {
 
 // Plot represents a Proof of Space plot using simple hash storage with BST index
 type Plot struct {
-	PeerID   id_tools.PeerID
-	FilePath string
-	Entries  []PlotEntry // BST-indexed entries sorted by hash prefix for quick lookup
+	PeerID     id_tools.PeerID
+	FilePath   string
+	NumEntries int         // Number of hash entries in the plot file
+	Entries    []PlotEntry // BST-indexed entries sorted by hash prefix for quick lookup
 }
 
 // Challenge represents a PoS challenge requiring a hash with specific prefix
@@ -43,7 +43,7 @@ type Proof struct {
 
 // GeneratePlot creates a proof of space plot using simple SHA256(PeerID||Index) approach
 // Uses external merge sort to avoid loading all entries into memory at once
-func GeneratePlot(peerID id_tools.PeerID, dataDir string) (*Plot, error) {
+func GeneratePlot(peerID id_tools.PeerID, dataDir string, numEntries int) (*Plot, error) {
 	// Create data directory if it doesn't exist
 	if err := os.MkdirAll(dataDir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create data directory: %w", err)
@@ -55,15 +55,15 @@ func GeneratePlot(peerID id_tools.PeerID, dataDir string) (*Plot, error) {
 	// Check if plot already exists and load it
 	if _, err := os.Stat(plotPath); err == nil {
 		fmt.Printf("Plot already exists at %s, loading...\n", plotPath)
-		return LoadPlot(peerID, dataDir)
+		return LoadPlot(peerID, dataDir, numEntries)
 	}
 
-	fmt.Printf("Generating Proof of Space plot (%d entries)...\n", constants.PosNumEntries)
+	fmt.Printf("Generating Proof of Space plot (%d entries)...\n", numEntries)
 	fmt.Println("Using external merge sort (memory-efficient)...")
 
 	// Use external merge sort with chunks to avoid loading all entries into memory
 	chunkSize := 50000 // Process 50k entries at a time (~2MB per chunk)
-	numChunks := (constants.PosNumEntries + chunkSize - 1) / chunkSize
+	numChunks := (numEntries + chunkSize - 1) / chunkSize
 
 	tempFiles := make([]string, 0, numChunks)
 
@@ -72,8 +72,8 @@ func GeneratePlot(peerID id_tools.PeerID, dataDir string) (*Plot, error) {
 	for chunkIdx := 0; chunkIdx < numChunks; chunkIdx++ {
 		startIdx := chunkIdx * chunkSize
 		endIdx := startIdx + chunkSize
-		if endIdx > constants.PosNumEntries {
-			endIdx = constants.PosNumEntries
+		if endIdx > numEntries {
+			endIdx = numEntries
 		}
 
 		// Generate entries for this chunk
@@ -104,13 +104,13 @@ func GeneratePlot(peerID id_tools.PeerID, dataDir string) (*Plot, error) {
 		}
 		tempFiles = append(tempFiles, tempFile)
 
-		progress := float64(endIdx) / float64(constants.PosNumEntries) * 50 // First 50% progress
+		progress := float64(endIdx) / float64(numEntries) * 50 // First 50% progress
 		fmt.Printf("Progress: %.0f%%\n", progress)
 	}
 
 	// Step 2: Merge sorted chunks into final file
 	fmt.Println("Step 2/2: Merging sorted chunks...")
-	if err := mergeSortedChunks(tempFiles, plotPath); err != nil {
+	if err := mergeSortedChunks(tempFiles, plotPath, numEntries); err != nil {
 		// Clean up temp files on error
 		for _, tf := range tempFiles {
 			os.Remove(tf)
@@ -124,17 +124,18 @@ func GeneratePlot(peerID id_tools.PeerID, dataDir string) (*Plot, error) {
 	}
 
 	fmt.Printf("✓ Plot generation complete: %s\n", plotPath)
-	fmt.Printf("✓ Generated %d entries with external merge sort\n", constants.PosNumEntries)
+	fmt.Printf("✓ Generated %d entries with external merge sort\n", numEntries)
 
 	return &Plot{
-		PeerID:   peerID,
-		FilePath: plotPath,
-		Entries:  nil, // Don't load entries into memory
+		PeerID:     peerID,
+		FilePath:   plotPath,
+		NumEntries: numEntries,
+		Entries:    nil, // Don't load entries into memory
 	}, nil
 }
 
 // LoadPlot loads an existing plot from disk without loading all entries into memory
-func LoadPlot(peerID id_tools.PeerID, dataDir string) (*Plot, error) {
+func LoadPlot(peerID id_tools.PeerID, dataDir string, numEntries int) (*Plot, error) {
 	plotPath := filepath.Join(dataDir, fmt.Sprintf("plot_%x.dat", peerID[:8]))
 
 	// Just verify the file exists and has correct size
@@ -143,17 +144,18 @@ func LoadPlot(peerID id_tools.PeerID, dataDir string) (*Plot, error) {
 		return nil, fmt.Errorf("failed to stat plot file: %w", err)
 	}
 
-	expectedSize := int64(constants.PosNumEntries) * int64(8+32) // 8 bytes index + 32 bytes hash
+	expectedSize := int64(numEntries) * int64(8+32) // 8 bytes index + 32 bytes hash
 	if info.Size() != expectedSize {
 		return nil, fmt.Errorf("plot file has incorrect size: expected %d, got %d", expectedSize, info.Size())
 	}
 
-	fmt.Printf("✓ Plot file verified: %s (%d entries)\n", plotPath, constants.PosNumEntries)
+	fmt.Printf("✓ Plot file verified: %s (%d entries)\n", plotPath, numEntries)
 
 	return &Plot{
-		PeerID:   peerID,
-		FilePath: plotPath,
-		Entries:  nil, // Don't load entries into memory
+		PeerID:     peerID,
+		FilePath:   plotPath,
+		NumEntries: numEntries,
+		Entries:    nil, // Don't load entries into memory
 	}, nil
 }
 
@@ -165,7 +167,7 @@ type chunkReader struct {
 }
 
 // mergeSortedChunks performs k-way merge of sorted chunk files
-func mergeSortedChunks(chunkFiles []string, outputPath string) error {
+func mergeSortedChunks(chunkFiles []string, outputPath string, numEntries int) error {
 	// Open all chunk files
 	readers := make([]*chunkReader, len(chunkFiles))
 	for i, chunkPath := range chunkFiles {
@@ -231,8 +233,8 @@ func mergeSortedChunks(chunkFiles []string, outputPath string) error {
 		}
 
 		written++
-		if written%(constants.PosNumEntries/20) == 0 {
-			progress := 50 + (float64(written)/float64(constants.PosNumEntries))*50 // Second 50% progress
+		if written%max(numEntries/20, 1) == 0 {
+			progress := 50 + (float64(written)/float64(numEntries))*50 // Second 50% progress
 			fmt.Printf("Progress: %.0f%%\n", progress)
 		}
 
@@ -280,9 +282,9 @@ func savePlot(plotPath string, entries []PlotEntry) error {
 }
 
 // GenerateChallenge creates a T-bit prefix challenge
-func GenerateChallenge() (*Challenge, error) {
-	// Generate random T bits (where T = constants.PosPrefixBits)
-	prefixBytes := (constants.PosPrefixBits + 7) / 8 // Round up to nearest byte
+func GenerateChallenge(prefixBits int) (*Challenge, error) {
+	// Generate random T bits (where T = prefixBits)
+	prefixBytes := (prefixBits + 7) / 8 // Round up to nearest byte
 	prefix := make([]byte, prefixBytes)
 
 	if _, err := rand.Read(prefix); err != nil {
@@ -290,13 +292,13 @@ func GenerateChallenge() (*Challenge, error) {
 	}
 
 	// Mask off extra bits if T is not a multiple of 8
-	extraBits := uint8(prefixBytes*8) - uint8(constants.PosPrefixBits)
+	extraBits := uint8(prefixBytes*8) - uint8(prefixBits)
 	if extraBits > 0 {
 		prefix[prefixBytes-1] &= ^((1 << extraBits) - 1)
 	}
 
 	return &Challenge{
-		PrefixBits: uint8(constants.PosPrefixBits),
+		PrefixBits: uint8(prefixBits),
 		Prefix:     prefix,
 	}, nil
 }
@@ -311,7 +313,7 @@ func (p *Plot) SearchMatchingHash(prefixBits uint8, prefix []byte) (*Proof, erro
 	defer file.Close()
 
 	entrySize := int64(8 + 32)
-	totalEntries := int64(constants.PosNumEntries)
+	totalEntries := int64(p.NumEntries)
 
 	left, right := int64(0), totalEntries
 
@@ -483,7 +485,7 @@ func compareHashes(a, b [32]byte) int {
 }
 
 // VerifyPlotExists checks if a plot file exists and has the correct size
-func VerifyPlotExists(peerID id_tools.PeerID, dataDir string) bool {
+func VerifyPlotExists(peerID id_tools.PeerID, dataDir string, numEntries int) bool {
 	plotPath := filepath.Join(dataDir, fmt.Sprintf("plot_%x.dat", peerID[:8]))
 
 	info, err := os.Stat(plotPath)
@@ -491,6 +493,6 @@ func VerifyPlotExists(peerID id_tools.PeerID, dataDir string) bool {
 		return false
 	}
 
-	expectedSize := int64(constants.PosNumEntries) * int64(8+32) // 8 bytes index + 32 bytes hash
+	expectedSize := int64(numEntries) * int64(8+32) // 8 bytes index + 32 bytes hash
 	return info.Size() == expectedSize
 }
@@ -17,7 +17,7 @@ func TestPlotGeneration(t *testing.T) {
 	testDir := "/tmp/pos_test"
 	defer os.RemoveAll(testDir)
 
-	plot, err := GeneratePlot(peerID, testDir)
+	plot, err := GeneratePlot(peerID, testDir, constants.PosNumEntries)
 	if err != nil {
 		t.Fatalf("Failed to generate plot: %v", err)
 	}
@@ -47,12 +47,12 @@ func TestChallengeAndProof(t *testing.T) {
 	testDir := "/tmp/pos_test_challenge"
 	defer os.RemoveAll(testDir)
 
-	plot, err := GeneratePlot(peerID, testDir)
+	plot, err := GeneratePlot(peerID, testDir, constants.PosNumEntries)
 	if err != nil {
 		t.Fatalf("Failed to generate plot: %v", err)
 	}
 
-	challenge, err := GenerateChallenge()
+	challenge, err := GenerateChallenge(constants.PosPrefixBits)
 	if err != nil {
 		t.Fatalf("Failed to generate challenge: %v", err)
 	}
@@ -85,12 +85,12 @@ func TestProofVerification(t *testing.T) {
 	testDir := "/tmp/pos_test_verify"
 	defer os.RemoveAll(testDir)
 
-	plot, err := GeneratePlot(peerID, testDir)
+	plot, err := GeneratePlot(peerID, testDir, constants.PosNumEntries)
 	if err != nil {
 		t.Fatalf("Failed to generate plot: %v", err)
 	}
 
-	challenge, err := GenerateChallenge()
+	challenge, err := GenerateChallenge(constants.PosPrefixBits)
 	if err != nil {
 		t.Fatalf("Failed to generate challenge: %v", err)
 	}
@@ -135,13 +135,13 @@ func TestPlotRegeneration(t *testing.T) {
 	defer os.RemoveAll(testDir)
 
 	// Generate plot first time
-	plot1, err := GeneratePlot(peerID, testDir)
+	plot1, err := GeneratePlot(peerID, testDir, constants.PosNumEntries)
 	if err != nil {
 		t.Fatalf("Failed to generate plot first time: %v", err)
 	}
 
 	// Generate plot second time (should load existing)
-	plot2, err := GeneratePlot(peerID, testDir)
+	plot2, err := GeneratePlot(peerID, testDir, constants.PosNumEntries)
 	if err != nil {
 		t.Fatalf("Failed to load existing plot: %v", err)
 	}
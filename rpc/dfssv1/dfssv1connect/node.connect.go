@@ -0,0 +1,321 @@
+// Code generated by protoc-gen-connect-go. DO NOT EDIT.
+//
+// Source: dfss/v1/node.proto
+
+package dfssv1connect
+
+import (
+	connect "connectrpc.com/connect"
+	context "context"
+	errors "errors"
+	dfssv1 "github.com/kutluhann/decentralized-file-sharing-system/rpc/dfssv1"
+	http "net/http"
+	strings "strings"
+)
+
+// This is a compile-time assertion to ensure that this generated file and the connect package are
+// compatible. If you get a compiler error that this constant is not defined, this code was
+// generated with a version of connect newer than the one compiled into your binary. You can fix the
+// problem by either regenerating this code with an older version of connect or updating the connect
+// version compiled into your binary.
+const _ = connect.IsAtLeastVersion1_13_0
+
+const (
+	// NodeServiceName is the fully-qualified name of the NodeService service.
+	NodeServiceName = "dfss.v1.NodeService"
+)
+
+// These constants are the fully-qualified names of the RPCs defined in this package. They're
+// exposed at runtime as Spec.Procedure and as the final two segments of the HTTP route.
+//
+// Note that these are different from the fully-qualified method names used by
+// google.golang.org/protobuf/reflect/protoreflect. To convert from these constants to
+// reflection-formatted method names, remove the leading slash and convert the remaining slash to a
+// period.
+const (
+	// NodeServicePutProcedure is the fully-qualified name of the NodeService's Put RPC.
+	NodeServicePutProcedure = "/dfss.v1.NodeService/Put"
+	// NodeServiceGetProcedure is the fully-qualified name of the NodeService's Get RPC.
+	NodeServiceGetProcedure = "/dfss.v1.NodeService/Get"
+	// NodeServiceDeleteProcedure is the fully-qualified name of the NodeService's Delete RPC.
+	NodeServiceDeleteProcedure = "/dfss.v1.NodeService/Delete"
+	// NodeServiceStatusProcedure is the fully-qualified name of the NodeService's Status RPC.
+	NodeServiceStatusProcedure = "/dfss.v1.NodeService/Status"
+	// NodeServiceRoutingTableProcedure is the fully-qualified name of the NodeService's RoutingTable
+	// RPC.
+	NodeServiceRoutingTableProcedure = "/dfss.v1.NodeService/RoutingTable"
+	// NodeServicePeersProcedure is the fully-qualified name of the NodeService's Peers RPC.
+	NodeServicePeersProcedure = "/dfss.v1.NodeService/Peers"
+	// NodeServiceUploadProcedure is the fully-qualified name of the NodeService's Upload RPC.
+	NodeServiceUploadProcedure = "/dfss.v1.NodeService/Upload"
+	// NodeServiceDownloadProcedure is the fully-qualified name of the NodeService's Download RPC.
+	NodeServiceDownloadProcedure = "/dfss.v1.NodeService/Download"
+)
+
+// NodeServiceClient is a client for the dfss.v1.NodeService service.
+type NodeServiceClient interface {
+	// Put stores a value
+	Put(context.Context, *connect.Request[dfssv1.PutRequest]) (*connect.Response[dfssv1.PutResponse], error)
+	// Get retrieves a value, rebuilding and decrypting it as needed
+	Get(context.Context, *connect.Request[dfssv1.GetRequest]) (*connect.Response[dfssv1.GetResponse], error)
+	// Delete removes a value this node stored
+	Delete(context.Context, *connect.Request[dfssv1.DeleteRequest]) (*connect.Response[dfssv1.DeleteResponse], error)
+	// Status describes the node
+	Status(context.Context, *connect.Request[dfssv1.StatusRequest]) (*connect.Response[dfssv1.StatusResponse], error)
+	// RoutingTable lists the non-empty buckets of the routing table
+	RoutingTable(context.Context, *connect.Request[dfssv1.RoutingTableRequest]) (*connect.Response[dfssv1.RoutingTableResponse], error)
+	// Peers lists every contact in the routing table
+	Peers(context.Context, *connect.Request[dfssv1.PeersRequest]) (*connect.Response[dfssv1.PeersResponse], error)
+	// Upload stores a value sent as a header followed by chunks, stored while they arrive
+	Upload(context.Context) *connect.ClientStreamForClient[dfssv1.UploadRequest, dfssv1.UploadResponse]
+	// Download streams a value as a header followed by chunks
+	Download(context.Context, *connect.Request[dfssv1.DownloadRequest]) (*connect.ServerStreamForClient[dfssv1.DownloadResponse], error)
+}
+
+// NewNodeServiceClient constructs a client for the dfss.v1.NodeService service. By default, it uses
+// the Connect protocol with the binary Protobuf Codec, asks for gzipped responses, and sends
+// uncompressed requests. To use the gRPC or gRPC-Web protocols, supply the connect.WithGRPC() or
+// connect.WithGRPCWeb() options.
+//
+// The URL supplied here should be the base URL for the Connect or gRPC server (for example,
+// http://api.acme.com or https://acme.com/grpc).
+func NewNodeServiceClient(httpClient connect.HTTPClient, baseURL string, opts ...connect.ClientOption) NodeServiceClient {
+	baseURL = strings.TrimRight(baseURL, "/")
+	nodeServiceMethods := dfssv1.File_dfss_v1_node_proto.Services().ByName("NodeService").Methods()
+	return &nodeServiceClient{
+		put: connect.NewClient[dfssv1.PutRequest, dfssv1.PutResponse](
+			httpClient,
+			baseURL+NodeServicePutProcedure,
+			connect.WithSchema(nodeServiceMethods.ByName("Put")),
+			connect.WithClientOptions(opts...),
+		),
+		get: connect.NewClient[dfssv1.GetRequest, dfssv1.GetResponse](
+			httpClient,
+			baseURL+NodeServiceGetProcedure,
+			connect.WithSchema(nodeServiceMethods.ByName("Get")),
+			connect.WithClientOptions(opts...),
+		),
+		delete: connect.NewClient[dfssv1.DeleteRequest, dfssv1.DeleteResponse](
+			httpClient,
+			baseURL+NodeServiceDeleteProcedure,
+			connect.WithSchema(nodeServiceMethods.ByName("Delete")),
+			connect.WithClientOptions(opts...),
+		),
+		status: connect.NewClient[dfssv1.StatusRequest, dfssv1.StatusResponse](
+			httpClient,
+			baseURL+NodeServiceStatusProcedure,
+			connect.WithSchema(nodeServiceMethods.ByName("Status")),
+			connect.WithClientOptions(opts...),
+		),
+		routingTable: connect.NewClient[dfssv1.RoutingTableRequest, dfssv1.RoutingTableResponse](
+			httpClient,
+			baseURL+NodeServiceRoutingTableProcedure,
+			connect.WithSchema(nodeServiceMethods.ByName("RoutingTable")),
+			connect.WithClientOptions(opts...),
+		),
+		peers: connect.NewClient[dfssv1.PeersRequest, dfssv1.PeersResponse](
+			httpClient,
+			baseURL+NodeServicePeersProcedure,
+			connect.WithSchema(nodeServiceMethods.ByName("Peers")),
+			connect.WithClientOptions(opts...),
+		),
+		upload: connect.NewClient[dfssv1.UploadRequest, dfssv1.UploadResponse](
+			httpClient,
+			baseURL+NodeServiceUploadProcedure,
+			connect.WithSchema(nodeServiceMethods.ByName("Upload")),
+			connect.WithClientOptions(opts...),
+		),
+		download: connect.NewClient[dfssv1.DownloadRequest, dfssv1.DownloadResponse](
+			httpClient,
+			baseURL+NodeServiceDownloadProcedure,
+			connect.WithSchema(nodeServiceMethods.ByName("Download")),
+			connect.WithClientOptions(opts...),
+		),
+	}
+}
+
+// nodeServiceClient implements NodeServiceClient.
+type nodeServiceClient struct {
+	put          *connect.Client[dfssv1.PutRequest, dfssv1.PutResponse]
+	get          *connect.Client[dfssv1.GetRequest, dfssv1.GetResponse]
+	delete       *connect.Client[dfssv1.DeleteRequest, dfssv1.DeleteResponse]
+	status       *connect.Client[dfssv1.StatusRequest, dfssv1.StatusResponse]
+	routingTable *connect.Client[dfssv1.RoutingTableRequest, dfssv1.RoutingTableResponse]
+	peers        *connect.Client[dfssv1.PeersRequest, dfssv1.PeersResponse]
+	upload       *connect.Client[dfssv1.UploadRequest, dfssv1.UploadResponse]
+	download     *connect.Client[dfssv1.DownloadRequest, dfssv1.DownloadResponse]
+}
+
+// Put calls dfss.v1.NodeService.Put.
+func (c *nodeServiceClient) Put(ctx context.Context, req *connect.Request[dfssv1.PutRequest]) (*connect.Response[dfssv1.PutResponse], error) {
+	return c.put.CallUnary(ctx, req)
+}
+
+// Get calls dfss.v1.NodeService.Get.
+func (c *nodeServiceClient) Get(ctx context.Context, req *connect.Request[dfssv1.GetRequest]) (*connect.Response[dfssv1.GetResponse], error) {
+	return c.get.CallUnary(ctx, req)
+}
+
+// Delete calls dfss.v1.NodeService.Delete.
+func (c *nodeServiceClient) Delete(ctx context.Context, req *connect.Request[dfssv1.DeleteRequest]) (*connect.Response[dfssv1.DeleteResponse], error) {
+	return c.delete.CallUnary(ctx, req)
+}
+
+// Status calls dfss.v1.NodeService.Status.
+func (c *nodeServiceClient) Status(ctx context.Context, req *connect.Request[dfssv1.StatusRequest]) (*connect.Response[dfssv1.StatusResponse], error) {
+	return c.status.CallUnary(ctx, req)
+}
+
+// RoutingTable calls dfss.v1.NodeService.RoutingTable.
+func (c *nodeServiceClient) RoutingTable(ctx context.Context, req *connect.Request[dfssv1.RoutingTableRequest]) (*connect.Response[dfssv1.RoutingTableResponse], error) {
+	return c.routingTable.CallUnary(ctx, req)
+}
+
+// Peers calls dfss.v1.NodeService.Peers.
+func (c *nodeServiceClient) Peers(ctx context.Context, req *connect.Request[dfssv1.PeersRequest]) (*connect.Response[dfssv1.PeersResponse], error) {
+	return c.peers.CallUnary(ctx, req)
+}
+
+// Upload calls dfss.v1.NodeService.Upload.
+func (c *nodeServiceClient) Upload(ctx context.Context) *connect.ClientStreamForClient[dfssv1.UploadRequest, dfssv1.UploadResponse] {
+	return c.upload.CallClientStream(ctx)
+}
+
+// Download calls dfss.v1.NodeService.Download.
+func (c *nodeServiceClient) Download(ctx context.Context, req *connect.Request[dfssv1.DownloadRequest]) (*connect.ServerStreamForClient[dfssv1.DownloadResponse], error) {
+	return c.download.CallServerStream(ctx, req)
+}
+
+// NodeServiceHandler is an implementation of the dfss.v1.NodeService service.
+type NodeServiceHandler interface {
+	// Put stores a value
+	Put(context.Context, *connect.Request[dfssv1.PutRequest]) (*connect.Response[dfssv1.PutResponse], error)
+	// Get retrieves a value, rebuilding and decrypting it as needed
+	Get(context.Context, *connect.Request[dfssv1.GetRequest]) (*connect.Response[dfssv1.GetResponse], error)
+	// Delete removes a value this node stored
+	Delete(context.Context, *connect.Request[dfssv1.DeleteRequest]) (*connect.Response[dfssv1.DeleteResponse], error)
+	// Status describes the node
+	Status(context.Context, *connect.Request[dfssv1.StatusRequest]) (*connect.Response[dfssv1.StatusResponse], error)
+	// RoutingTable lists the non-empty buckets of the routing table
+	RoutingTable(context.Context, *connect.Request[dfssv1.RoutingTableRequest]) (*connect.Response[dfssv1.RoutingTableResponse], error)
+	// Peers lists every contact in the routing table
+	Peers(context.Context, *connect.Request[dfssv1.PeersRequest]) (*connect.Response[dfssv1.PeersResponse], error)
+	// Upload stores a value sent as a header followed by chunks, stored while they arrive
+	Upload(context.Context, *connect.ClientStream[dfssv1.UploadRequest]) (*connect.Response[dfssv1.UploadResponse], error)
+	// Download streams a value as a header followed by chunks
+	Download(context.Context, *connect.Request[dfssv1.DownloadRequest], *connect.ServerStream[dfssv1.DownloadResponse]) error
+}
+
+// NewNodeServiceHandler builds an HTTP handler from the service implementation. It returns the path
+// on which to mount the handler and the handler itself.
+//
+// By default, handlers support the Connect, gRPC, and gRPC-Web protocols with the binary Protobuf
+// and JSON codecs. They also support gzip compression.
+func NewNodeServiceHandler(svc NodeServiceHandler, opts ...connect.HandlerOption) (string, http.Handler) {
+	nodeServiceMethods := dfssv1.File_dfss_v1_node_proto.Services().ByName("NodeService").Methods()
+	nodeServicePutHandler := connect.NewUnaryHandler(
+		NodeServicePutProcedure,
+		svc.Put,
+		connect.WithSchema(nodeServiceMethods.ByName("Put")),
+		connect.WithHandlerOptions(opts...),
+	)
+	nodeServiceGetHandler := connect.NewUnaryHandler(
+		NodeServiceGetProcedure,
+		svc.Get,
+		connect.WithSchema(nodeServiceMethods.ByName("Get")),
+		connect.WithHandlerOptions(opts...),
+	)
+	nodeServiceDeleteHandler := connect.NewUnaryHandler(
+		NodeServiceDeleteProcedure,
+		svc.Delete,
+		connect.WithSchema(nodeServiceMethods.ByName("Delete")),
+		connect.WithHandlerOptions(opts...),
+	)
+	nodeServiceStatusHandler := connect.NewUnaryHandler(
+		NodeServiceStatusProcedure,
+		svc.Status,
+		connect.WithSchema(nodeServiceMethods.ByName("Status")),
+		connect.WithHandlerOptions(opts...),
+	)
+	nodeServiceRoutingTableHandler := connect.NewUnaryHandler(
+		NodeServiceRoutingTableProcedure,
+		svc.RoutingTable,
+		connect.WithSchema(nodeServiceMethods.ByName("RoutingTable")),
+		connect.WithHandlerOptions(opts...),
+	)
+	nodeServicePeersHandler := connect.NewUnaryHandler(
+		NodeServicePeersProcedure,
+		svc.Peers,
+		connect.WithSchema(nodeServiceMethods.ByName("Peers")),
+		connect.WithHandlerOptions(opts...),
+	)
+	nodeServiceUploadHandler := connect.NewClientStreamHandler(
+		NodeServiceUploadProcedure,
+		svc.Upload,
+		connect.WithSchema(nodeServiceMethods.ByName("Upload")),
+		connect.WithHandlerOptions(opts...),
+	)
+	nodeServiceDownloadHandler := connect.NewServerStreamHandler(
+		NodeServiceDownloadProcedure,
+		svc.Download,
+		connect.WithSchema(nodeServiceMethods.ByName("Download")),
+		connect.WithHandlerOptions(opts...),
+	)
+	return "/dfss.v1.NodeService/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case NodeServicePutProcedure:
+			nodeServicePutHandler.ServeHTTP(w, r)
+		case NodeServiceGetProcedure:
+			nodeServiceGetHandler.ServeHTTP(w, r)
+		case NodeServiceDeleteProcedure:
+			nodeServiceDeleteHandler.ServeHTTP(w, r)
+		case NodeServiceStatusProcedure:
+			nodeServiceStatusHandler.ServeHTTP(w, r)
+		case NodeServiceRoutingTableProcedure:
+			nodeServiceRoutingTableHandler.ServeHTTP(w, r)
+		case NodeServicePeersProcedure:
+			nodeServicePeersHandler.ServeHTTP(w, r)
+		case NodeServiceUploadProcedure:
+			nodeServiceUploadHandler.ServeHTTP(w, r)
+		case NodeServiceDownloadProcedure:
+			nodeServiceDownloadHandler.ServeHTTP(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+// UnimplementedNodeServiceHandler returns CodeUnimplemented from all methods.
+type UnimplementedNodeServiceHandler struct{}
+
+func (UnimplementedNodeServiceHandler) Put(context.Context, *connect.Request[dfssv1.PutRequest]) (*connect.Response[dfssv1.PutResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("dfss.v1.NodeService.Put is not implemented"))
+}
+
+func (UnimplementedNodeServiceHandler) Get(context.Context, *connect.Request[dfssv1.GetRequest]) (*connect.Response[dfssv1.GetResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("dfss.v1.NodeService.Get is not implemented"))
+}
+
+func (UnimplementedNodeServiceHandler) Delete(context.Context, *connect.Request[dfssv1.DeleteRequest]) (*connect.Response[dfssv1.DeleteResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("dfss.v1.NodeService.Delete is not implemented"))
+}
+
+func (UnimplementedNodeServiceHandler) Status(context.Context, *connect.Request[dfssv1.StatusRequest]) (*connect.Response[dfssv1.StatusResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("dfss.v1.NodeService.Status is not implemented"))
+}
+
+func (UnimplementedNodeServiceHandler) RoutingTable(context.Context, *connect.Request[dfssv1.RoutingTableRequest]) (*connect.Response[dfssv1.RoutingTableResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("dfss.v1.NodeService.RoutingTable is not implemented"))
+}
+
+func (UnimplementedNodeServiceHandler) Peers(context.Context, *connect.Request[dfssv1.PeersRequest]) (*connect.Response[dfssv1.PeersResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("dfss.v1.NodeService.Peers is not implemented"))
+}
+
+func (UnimplementedNodeServiceHandler) Upload(context.Context, *connect.ClientStream[dfssv1.UploadRequest]) (*connect.Response[dfssv1.UploadResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("dfss.v1.NodeService.Upload is not implemented"))
+}
+
+func (UnimplementedNodeServiceHandler) Download(context.Context, *connect.Request[dfssv1.DownloadRequest], *connect.ServerStream[dfssv1.DownloadResponse]) error {
+	return connect.NewError(connect.CodeUnimplemented, errors.New("dfss.v1.NodeService.Download is not implemented"))
+}
@@ -0,0 +1,1604 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.12
+// 	protoc        (unknown)
+// source: dfss/v1/node.proto
+
+package dfssv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// StoreMode selects how a value is stored
+type StoreMode int32
+
+const (
+	StoreMode_STORE_MODE_UNSPECIFIED StoreMode = 0 // Fully replicated, chunked when larger than a chunk
+	StoreMode_STORE_MODE_ERASURE     StoreMode = 1 // Erasure-coded shards
+	StoreMode_STORE_MODE_ENCRYPTED   StoreMode = 2 // Encrypted for this node and the recipients
+	StoreMode_STORE_MODE_CONVERGENT  StoreMode = 3 // Encrypted with a key derived from the content, deduplicated
+)
+
+// Enum value maps for StoreMode.
+var (
+	StoreMode_name = map[int32]string{
+		0: "STORE_MODE_UNSPECIFIED",
+		1: "STORE_MODE_ERASURE",
+		2: "STORE_MODE_ENCRYPTED",
+		3: "STORE_MODE_CONVERGENT",
+	}
+	StoreMode_value = map[string]int32{
+		"STORE_MODE_UNSPECIFIED": 0,
+		"STORE_MODE_ERASURE":     1,
+		"STORE_MODE_ENCRYPTED":   2,
+		"STORE_MODE_CONVERGENT":  3,
+	}
+)
+
+func (x StoreMode) Enum() *StoreMode {
+	p := new(StoreMode)
+	*p = x
+	return p
+}
+
+func (x StoreMode) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (StoreMode) Descriptor() protoreflect.EnumDescriptor {
+	return file_dfss_v1_node_proto_enumTypes[0].Descriptor()
+}
+
+func (StoreMode) Type() protoreflect.EnumType {
+	return &file_dfss_v1_node_proto_enumTypes[0]
+}
+
+func (x StoreMode) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use StoreMode.Descriptor instead.
+func (StoreMode) EnumDescriptor() ([]byte, []int) {
+	return file_dfss_v1_node_proto_rawDescGZIP(), []int{0}
+}
+
+// KeyRef names a value by its human-readable key or by its key hash
+type KeyRef struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Ref:
+	//
+	//	*KeyRef_Key
+	//	*KeyRef_KeyHash
+	Ref           isKeyRef_Ref `protobuf_oneof:"ref"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *KeyRef) Reset() {
+	*x = KeyRef{}
+	mi := &file_dfss_v1_node_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *KeyRef) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*KeyRef) ProtoMessage() {}
+
+func (x *KeyRef) ProtoReflect() protoreflect.Message {
+	mi := &file_dfss_v1_node_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use KeyRef.ProtoReflect.Descriptor instead.
+func (*KeyRef) Descriptor() ([]byte, []int) {
+	return file_dfss_v1_node_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *KeyRef) GetRef() isKeyRef_Ref {
+	if x != nil {
+		return x.Ref
+	}
+	return nil
+}
+
+func (x *KeyRef) GetKey() string {
+	if x != nil {
+		if x, ok := x.Ref.(*KeyRef_Key); ok {
+			return x.Key
+		}
+	}
+	return ""
+}
+
+func (x *KeyRef) GetKeyHash() []byte {
+	if x != nil {
+		if x, ok := x.Ref.(*KeyRef_KeyHash); ok {
+			return x.KeyHash
+		}
+	}
+	return nil
+}
+
+type isKeyRef_Ref interface {
+	isKeyRef_Ref()
+}
+
+type KeyRef_Key struct {
+	Key string `protobuf:"bytes,1,opt,name=key,proto3,oneof"` // Hashed with SHA-256 to the DHT key
+}
+
+type KeyRef_KeyHash struct {
+	KeyHash []byte `protobuf:"bytes,2,opt,name=key_hash,json=keyHash,proto3,oneof"` // 32-byte DHT key, e.g. of a value shared with this node
+}
+
+func (*KeyRef_Key) isKeyRef_Ref() {}
+
+func (*KeyRef_KeyHash) isKeyRef_Ref() {}
+
+type PutRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Value         []byte                 `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	Mode          StoreMode              `protobuf:"varint,3,opt,name=mode,proto3,enum=dfss.v1.StoreMode" json:"mode,omitempty"`
+	Recipients    [][]byte               `protobuf:"bytes,4,rep,name=recipients,proto3" json:"recipients,omitempty"` // PKIX public keys that may also decrypt an encrypted value
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PutRequest) Reset() {
+	*x = PutRequest{}
+	mi := &file_dfss_v1_node_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PutRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PutRequest) ProtoMessage() {}
+
+func (x *PutRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_dfss_v1_node_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PutRequest.ProtoReflect.Descriptor instead.
+func (*PutRequest) Descriptor() ([]byte, []int) {
+	return file_dfss_v1_node_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *PutRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *PutRequest) GetValue() []byte {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+func (x *PutRequest) GetMode() StoreMode {
+	if x != nil {
+		return x.Mode
+	}
+	return StoreMode_STORE_MODE_UNSPECIFIED
+}
+
+func (x *PutRequest) GetRecipients() [][]byte {
+	if x != nil {
+		return x.Recipients
+	}
+	return nil
+}
+
+type PutResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	KeyHash       []byte                 `protobuf:"bytes,1,opt,name=key_hash,json=keyHash,proto3" json:"key_hash,omitempty"`
+	Size          int64                  `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`
+	Sha256        []byte                 `protobuf:"bytes,3,opt,name=sha256,proto3" json:"sha256,omitempty"` // SHA-256 of the value
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PutResponse) Reset() {
+	*x = PutResponse{}
+	mi := &file_dfss_v1_node_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PutResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PutResponse) ProtoMessage() {}
+
+func (x *PutResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_dfss_v1_node_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PutResponse.ProtoReflect.Descriptor instead.
+func (*PutResponse) Descriptor() ([]byte, []int) {
+	return file_dfss_v1_node_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *PutResponse) GetKeyHash() []byte {
+	if x != nil {
+		return x.KeyHash
+	}
+	return nil
+}
+
+func (x *PutResponse) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *PutResponse) GetSha256() []byte {
+	if x != nil {
+		return x.Sha256
+	}
+	return nil
+}
+
+type GetRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ref           *KeyRef                `protobuf:"bytes,1,opt,name=ref,proto3" json:"ref,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetRequest) Reset() {
+	*x = GetRequest{}
+	mi := &file_dfss_v1_node_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRequest) ProtoMessage() {}
+
+func (x *GetRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_dfss_v1_node_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRequest.ProtoReflect.Descriptor instead.
+func (*GetRequest) Descriptor() ([]byte, []int) {
+	return file_dfss_v1_node_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetRequest) GetRef() *KeyRef {
+	if x != nil {
+		return x.Ref
+	}
+	return nil
+}
+
+type GetResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	KeyHash       []byte                 `protobuf:"bytes,1,opt,name=key_hash,json=keyHash,proto3" json:"key_hash,omitempty"`
+	Value         []byte                 `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	HopCount      int32                  `protobuf:"varint,3,opt,name=hop_count,json=hopCount,proto3" json:"hop_count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetResponse) Reset() {
+	*x = GetResponse{}
+	mi := &file_dfss_v1_node_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetResponse) ProtoMessage() {}
+
+func (x *GetResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_dfss_v1_node_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetResponse.ProtoReflect.Descriptor instead.
+func (*GetResponse) Descriptor() ([]byte, []int) {
+	return file_dfss_v1_node_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GetResponse) GetKeyHash() []byte {
+	if x != nil {
+		return x.KeyHash
+	}
+	return nil
+}
+
+func (x *GetResponse) GetValue() []byte {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+func (x *GetResponse) GetHopCount() int32 {
+	if x != nil {
+		return x.HopCount
+	}
+	return 0
+}
+
+type DeleteRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteRequest) Reset() {
+	*x = DeleteRequest{}
+	mi := &file_dfss_v1_node_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteRequest) ProtoMessage() {}
+
+func (x *DeleteRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_dfss_v1_node_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteRequest.ProtoReflect.Descriptor instead.
+func (*DeleteRequest) Descriptor() ([]byte, []int) {
+	return file_dfss_v1_node_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *DeleteRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+type DeleteResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	KeyHash       []byte                 `protobuf:"bytes,1,opt,name=key_hash,json=keyHash,proto3" json:"key_hash,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteResponse) Reset() {
+	*x = DeleteResponse{}
+	mi := &file_dfss_v1_node_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteResponse) ProtoMessage() {}
+
+func (x *DeleteResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_dfss_v1_node_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteResponse.ProtoReflect.Descriptor instead.
+func (*DeleteResponse) Descriptor() ([]byte, []int) {
+	return file_dfss_v1_node_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *DeleteResponse) GetKeyHash() []byte {
+	if x != nil {
+		return x.KeyHash
+	}
+	return nil
+}
+
+type StatusRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StatusRequest) Reset() {
+	*x = StatusRequest{}
+	mi := &file_dfss_v1_node_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatusRequest) ProtoMessage() {}
+
+func (x *StatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_dfss_v1_node_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatusRequest.ProtoReflect.Descriptor instead.
+func (*StatusRequest) Descriptor() ([]byte, []int) {
+	return file_dfss_v1_node_proto_rawDescGZIP(), []int{7}
+}
+
+type EvictionStats struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Evicted        int64                  `protobuf:"varint,1,opt,name=evicted,proto3" json:"evicted,omitempty"`
+	EvictedBytes   int64                  `protobuf:"varint,2,opt,name=evicted_bytes,json=evictedBytes,proto3" json:"evicted_bytes,omitempty"`
+	Cached         int64                  `protobuf:"varint,3,opt,name=cached,proto3" json:"cached,omitempty"`
+	NotResponsible int64                  `protobuf:"varint,4,opt,name=not_responsible,json=notResponsible,proto3" json:"not_responsible,omitempty"`
+	Responsible    int64                  `protobuf:"varint,5,opt,name=responsible,proto3" json:"responsible,omitempty"`
+	Refused        int64                  `protobuf:"varint,6,opt,name=refused,proto3" json:"refused,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *EvictionStats) Reset() {
+	*x = EvictionStats{}
+	mi := &file_dfss_v1_node_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EvictionStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EvictionStats) ProtoMessage() {}
+
+func (x *EvictionStats) ProtoReflect() protoreflect.Message {
+	mi := &file_dfss_v1_node_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EvictionStats.ProtoReflect.Descriptor instead.
+func (*EvictionStats) Descriptor() ([]byte, []int) {
+	return file_dfss_v1_node_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *EvictionStats) GetEvicted() int64 {
+	if x != nil {
+		return x.Evicted
+	}
+	return 0
+}
+
+func (x *EvictionStats) GetEvictedBytes() int64 {
+	if x != nil {
+		return x.EvictedBytes
+	}
+	return 0
+}
+
+func (x *EvictionStats) GetCached() int64 {
+	if x != nil {
+		return x.Cached
+	}
+	return 0
+}
+
+func (x *EvictionStats) GetNotResponsible() int64 {
+	if x != nil {
+		return x.NotResponsible
+	}
+	return 0
+}
+
+func (x *EvictionStats) GetResponsible() int64 {
+	if x != nil {
+		return x.Responsible
+	}
+	return 0
+}
+
+func (x *EvictionStats) GetRefused() int64 {
+	if x != nil {
+		return x.Refused
+	}
+	return 0
+}
+
+type StatusResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	NodeId        []byte                 `protobuf:"bytes,1,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
+	PublicKey     []byte                 `protobuf:"bytes,2,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"` // PKIX public key others encrypt for
+	Ip            string                 `protobuf:"bytes,3,opt,name=ip,proto3" json:"ip,omitempty"`
+	Port          int32                  `protobuf:"varint,4,opt,name=port,proto3" json:"port,omitempty"`
+	StoredKeys    int64                  `protobuf:"varint,5,opt,name=stored_keys,json=storedKeys,proto3" json:"stored_keys,omitempty"`
+	StoredBytes   int64                  `protobuf:"varint,6,opt,name=stored_bytes,json=storedBytes,proto3" json:"stored_bytes,omitempty"`
+	QuotaBytes    int64                  `protobuf:"varint,7,opt,name=quota_bytes,json=quotaBytes,proto3" json:"quota_bytes,omitempty"` // 0 when storage is unlimited
+	Evictions     *EvictionStats         `protobuf:"bytes,8,opt,name=evictions,proto3" json:"evictions,omitempty"`
+	KnownPeers    int64                  `protobuf:"varint,9,opt,name=known_peers,json=knownPeers,proto3" json:"known_peers,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StatusResponse) Reset() {
+	*x = StatusResponse{}
+	mi := &file_dfss_v1_node_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatusResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatusResponse) ProtoMessage() {}
+
+func (x *StatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_dfss_v1_node_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatusResponse.ProtoReflect.Descriptor instead.
+func (*StatusResponse) Descriptor() ([]byte, []int) {
+	return file_dfss_v1_node_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *StatusResponse) GetNodeId() []byte {
+	if x != nil {
+		return x.NodeId
+	}
+	return nil
+}
+
+func (x *StatusResponse) GetPublicKey() []byte {
+	if x != nil {
+		return x.PublicKey
+	}
+	return nil
+}
+
+func (x *StatusResponse) GetIp() string {
+	if x != nil {
+		return x.Ip
+	}
+	return ""
+}
+
+func (x *StatusResponse) GetPort() int32 {
+	if x != nil {
+		return x.Port
+	}
+	return 0
+}
+
+func (x *StatusResponse) GetStoredKeys() int64 {
+	if x != nil {
+		return x.StoredKeys
+	}
+	return 0
+}
+
+func (x *StatusResponse) GetStoredBytes() int64 {
+	if x != nil {
+		return x.StoredBytes
+	}
+	return 0
+}
+
+func (x *StatusResponse) GetQuotaBytes() int64 {
+	if x != nil {
+		return x.QuotaBytes
+	}
+	return 0
+}
+
+func (x *StatusResponse) GetEvictions() *EvictionStats {
+	if x != nil {
+		return x.Evictions
+	}
+	return nil
+}
+
+func (x *StatusResponse) GetKnownPeers() int64 {
+	if x != nil {
+		return x.KnownPeers
+	}
+	return 0
+}
+
+type Contact struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            []byte                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Ip            string                 `protobuf:"bytes,2,opt,name=ip,proto3" json:"ip,omitempty"`
+	Port          int32                  `protobuf:"varint,3,opt,name=port,proto3" json:"port,omitempty"`
+	LastSeenUnix  int64                  `protobuf:"varint,4,opt,name=last_seen_unix,json=lastSeenUnix,proto3" json:"last_seen_unix,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Contact) Reset() {
+	*x = Contact{}
+	mi := &file_dfss_v1_node_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Contact) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Contact) ProtoMessage() {}
+
+func (x *Contact) ProtoReflect() protoreflect.Message {
+	mi := &file_dfss_v1_node_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Contact.ProtoReflect.Descriptor instead.
+func (*Contact) Descriptor() ([]byte, []int) {
+	return file_dfss_v1_node_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *Contact) GetId() []byte {
+	if x != nil {
+		return x.Id
+	}
+	return nil
+}
+
+func (x *Contact) GetIp() string {
+	if x != nil {
+		return x.Ip
+	}
+	return ""
+}
+
+func (x *Contact) GetPort() int32 {
+	if x != nil {
+		return x.Port
+	}
+	return 0
+}
+
+func (x *Contact) GetLastSeenUnix() int64 {
+	if x != nil {
+		return x.LastSeenUnix
+	}
+	return 0
+}
+
+type Bucket struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Index         int32                  `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	Contacts      []*Contact             `protobuf:"bytes,2,rep,name=contacts,proto3" json:"contacts,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Bucket) Reset() {
+	*x = Bucket{}
+	mi := &file_dfss_v1_node_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Bucket) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Bucket) ProtoMessage() {}
+
+func (x *Bucket) ProtoReflect() protoreflect.Message {
+	mi := &file_dfss_v1_node_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Bucket.ProtoReflect.Descriptor instead.
+func (*Bucket) Descriptor() ([]byte, []int) {
+	return file_dfss_v1_node_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *Bucket) GetIndex() int32 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *Bucket) GetContacts() []*Contact {
+	if x != nil {
+		return x.Contacts
+	}
+	return nil
+}
+
+type RoutingTableRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RoutingTableRequest) Reset() {
+	*x = RoutingTableRequest{}
+	mi := &file_dfss_v1_node_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RoutingTableRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RoutingTableRequest) ProtoMessage() {}
+
+func (x *RoutingTableRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_dfss_v1_node_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RoutingTableRequest.ProtoReflect.Descriptor instead.
+func (*RoutingTableRequest) Descriptor() ([]byte, []int) {
+	return file_dfss_v1_node_proto_rawDescGZIP(), []int{12}
+}
+
+type RoutingTableResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Buckets       []*Bucket              `protobuf:"bytes,1,rep,name=buckets,proto3" json:"buckets,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RoutingTableResponse) Reset() {
+	*x = RoutingTableResponse{}
+	mi := &file_dfss_v1_node_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RoutingTableResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RoutingTableResponse) ProtoMessage() {}
+
+func (x *RoutingTableResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_dfss_v1_node_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RoutingTableResponse.ProtoReflect.Descriptor instead.
+func (*RoutingTableResponse) Descriptor() ([]byte, []int) {
+	return file_dfss_v1_node_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *RoutingTableResponse) GetBuckets() []*Bucket {
+	if x != nil {
+		return x.Buckets
+	}
+	return nil
+}
+
+type PeersRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PeersRequest) Reset() {
+	*x = PeersRequest{}
+	mi := &file_dfss_v1_node_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PeersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PeersRequest) ProtoMessage() {}
+
+func (x *PeersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_dfss_v1_node_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PeersRequest.ProtoReflect.Descriptor instead.
+func (*PeersRequest) Descriptor() ([]byte, []int) {
+	return file_dfss_v1_node_proto_rawDescGZIP(), []int{14}
+}
+
+type PeersResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Peers         []*Contact             `protobuf:"bytes,1,rep,name=peers,proto3" json:"peers,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PeersResponse) Reset() {
+	*x = PeersResponse{}
+	mi := &file_dfss_v1_node_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PeersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PeersResponse) ProtoMessage() {}
+
+func (x *PeersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_dfss_v1_node_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PeersResponse.ProtoReflect.Descriptor instead.
+func (*PeersResponse) Descriptor() ([]byte, []int) {
+	return file_dfss_v1_node_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *PeersResponse) GetPeers() []*Contact {
+	if x != nil {
+		return x.Peers
+	}
+	return nil
+}
+
+// UploadHeader is sent before the chunks. Only plain uploads are stored while
+// they arrive, the other modes need the whole value first.
+type UploadHeader struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Mode          StoreMode              `protobuf:"varint,2,opt,name=mode,proto3,enum=dfss.v1.StoreMode" json:"mode,omitempty"`
+	Recipients    [][]byte               `protobuf:"bytes,3,rep,name=recipients,proto3" json:"recipients,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UploadHeader) Reset() {
+	*x = UploadHeader{}
+	mi := &file_dfss_v1_node_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UploadHeader) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UploadHeader) ProtoMessage() {}
+
+func (x *UploadHeader) ProtoReflect() protoreflect.Message {
+	mi := &file_dfss_v1_node_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UploadHeader.ProtoReflect.Descriptor instead.
+func (*UploadHeader) Descriptor() ([]byte, []int) {
+	return file_dfss_v1_node_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *UploadHeader) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *UploadHeader) GetMode() StoreMode {
+	if x != nil {
+		return x.Mode
+	}
+	return StoreMode_STORE_MODE_UNSPECIFIED
+}
+
+func (x *UploadHeader) GetRecipients() [][]byte {
+	if x != nil {
+		return x.Recipients
+	}
+	return nil
+}
+
+// UploadRequest is a header in the first message and a chunk in every later one
+type UploadRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Part:
+	//
+	//	*UploadRequest_Header
+	//	*UploadRequest_Chunk
+	Part          isUploadRequest_Part `protobuf_oneof:"part"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UploadRequest) Reset() {
+	*x = UploadRequest{}
+	mi := &file_dfss_v1_node_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UploadRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UploadRequest) ProtoMessage() {}
+
+func (x *UploadRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_dfss_v1_node_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UploadRequest.ProtoReflect.Descriptor instead.
+func (*UploadRequest) Descriptor() ([]byte, []int) {
+	return file_dfss_v1_node_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *UploadRequest) GetPart() isUploadRequest_Part {
+	if x != nil {
+		return x.Part
+	}
+	return nil
+}
+
+func (x *UploadRequest) GetHeader() *UploadHeader {
+	if x != nil {
+		if x, ok := x.Part.(*UploadRequest_Header); ok {
+			return x.Header
+		}
+	}
+	return nil
+}
+
+func (x *UploadRequest) GetChunk() []byte {
+	if x != nil {
+		if x, ok := x.Part.(*UploadRequest_Chunk); ok {
+			return x.Chunk
+		}
+	}
+	return nil
+}
+
+type isUploadRequest_Part interface {
+	isUploadRequest_Part()
+}
+
+type UploadRequest_Header struct {
+	Header *UploadHeader `protobuf:"bytes,1,opt,name=header,proto3,oneof"`
+}
+
+type UploadRequest_Chunk struct {
+	Chunk []byte `protobuf:"bytes,2,opt,name=chunk,proto3,oneof"`
+}
+
+func (*UploadRequest_Header) isUploadRequest_Part() {}
+
+func (*UploadRequest_Chunk) isUploadRequest_Part() {}
+
+type UploadResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	KeyHash       []byte                 `protobuf:"bytes,1,opt,name=key_hash,json=keyHash,proto3" json:"key_hash,omitempty"`
+	Size          int64                  `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`
+	Sha256        []byte                 `protobuf:"bytes,3,opt,name=sha256,proto3" json:"sha256,omitempty"` // SHA-256 of the value
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UploadResponse) Reset() {
+	*x = UploadResponse{}
+	mi := &file_dfss_v1_node_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UploadResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UploadResponse) ProtoMessage() {}
+
+func (x *UploadResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_dfss_v1_node_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UploadResponse.ProtoReflect.Descriptor instead.
+func (*UploadResponse) Descriptor() ([]byte, []int) {
+	return file_dfss_v1_node_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *UploadResponse) GetKeyHash() []byte {
+	if x != nil {
+		return x.KeyHash
+	}
+	return nil
+}
+
+func (x *UploadResponse) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *UploadResponse) GetSha256() []byte {
+	if x != nil {
+		return x.Sha256
+	}
+	return nil
+}
+
+type DownloadRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ref           *KeyRef                `protobuf:"bytes,1,opt,name=ref,proto3" json:"ref,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DownloadRequest) Reset() {
+	*x = DownloadRequest{}
+	mi := &file_dfss_v1_node_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DownloadRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DownloadRequest) ProtoMessage() {}
+
+func (x *DownloadRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_dfss_v1_node_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DownloadRequest.ProtoReflect.Descriptor instead.
+func (*DownloadRequest) Descriptor() ([]byte, []int) {
+	return file_dfss_v1_node_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *DownloadRequest) GetRef() *KeyRef {
+	if x != nil {
+		return x.Ref
+	}
+	return nil
+}
+
+type DownloadHeader struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	KeyHash       []byte                 `protobuf:"bytes,1,opt,name=key_hash,json=keyHash,proto3" json:"key_hash,omitempty"`
+	Size          int64                  `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`
+	Sha256        []byte                 `protobuf:"bytes,3,opt,name=sha256,proto3" json:"sha256,omitempty"`
+	HopCount      int32                  `protobuf:"varint,4,opt,name=hop_count,json=hopCount,proto3" json:"hop_count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DownloadHeader) Reset() {
+	*x = DownloadHeader{}
+	mi := &file_dfss_v1_node_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DownloadHeader) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DownloadHeader) ProtoMessage() {}
+
+func (x *DownloadHeader) ProtoReflect() protoreflect.Message {
+	mi := &file_dfss_v1_node_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DownloadHeader.ProtoReflect.Descriptor instead.
+func (*DownloadHeader) Descriptor() ([]byte, []int) {
+	return file_dfss_v1_node_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *DownloadHeader) GetKeyHash() []byte {
+	if x != nil {
+		return x.KeyHash
+	}
+	return nil
+}
+
+func (x *DownloadHeader) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *DownloadHeader) GetSha256() []byte {
+	if x != nil {
+		return x.Sha256
+	}
+	return nil
+}
+
+func (x *DownloadHeader) GetHopCount() int32 {
+	if x != nil {
+		return x.HopCount
+	}
+	return 0
+}
+
+// DownloadResponse is a header in the first message and a chunk in every later one
+type DownloadResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Part:
+	//
+	//	*DownloadResponse_Header
+	//	*DownloadResponse_Chunk
+	Part          isDownloadResponse_Part `protobuf_oneof:"part"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DownloadResponse) Reset() {
+	*x = DownloadResponse{}
+	mi := &file_dfss_v1_node_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DownloadResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DownloadResponse) ProtoMessage() {}
+
+func (x *DownloadResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_dfss_v1_node_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DownloadResponse.ProtoReflect.Descriptor instead.
+func (*DownloadResponse) Descriptor() ([]byte, []int) {
+	return file_dfss_v1_node_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *DownloadResponse) GetPart() isDownloadResponse_Part {
+	if x != nil {
+		return x.Part
+	}
+	return nil
+}
+
+func (x *DownloadResponse) GetHeader() *DownloadHeader {
+	if x != nil {
+		if x, ok := x.Part.(*DownloadResponse_Header); ok {
+			return x.Header
+		}
+	}
+	return nil
+}
+
+func (x *DownloadResponse) GetChunk() []byte {
+	if x != nil {
+		if x, ok := x.Part.(*DownloadResponse_Chunk); ok {
+			return x.Chunk
+		}
+	}
+	return nil
+}
+
+type isDownloadResponse_Part interface {
+	isDownloadResponse_Part()
+}
+
+type DownloadResponse_Header struct {
+	Header *DownloadHeader `protobuf:"bytes,1,opt,name=header,proto3,oneof"`
+}
+
+type DownloadResponse_Chunk struct {
+	Chunk []byte `protobuf:"bytes,2,opt,name=chunk,proto3,oneof"`
+}
+
+func (*DownloadResponse_Header) isDownloadResponse_Part() {}
+
+func (*DownloadResponse_Chunk) isDownloadResponse_Part() {}
+
+var File_dfss_v1_node_proto protoreflect.FileDescriptor
+
+const file_dfss_v1_node_proto_rawDesc = "" +
+	"\n" +
+	"\x12dfss/v1/node.proto\x12\adfss.v1\"@\n" +
+	"\x06KeyRef\x12\x12\n" +
+	"\x03key\x18\x01 \x01(\tH\x00R\x03key\x12\x1b\n" +
+	"\bkey_hash\x18\x02 \x01(\fH\x00R\akeyHashB\x05\n" +
+	"\x03ref\"|\n" +
+	"\n" +
+	"PutRequest\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\fR\x05value\x12&\n" +
+	"\x04mode\x18\x03 \x01(\x0e2\x12.dfss.v1.StoreModeR\x04mode\x12\x1e\n" +
+	"\n" +
+	"recipients\x18\x04 \x03(\fR\n" +
+	"recipients\"T\n" +
+	"\vPutResponse\x12\x19\n" +
+	"\bkey_hash\x18\x01 \x01(\fR\akeyHash\x12\x12\n" +
+	"\x04size\x18\x02 \x01(\x03R\x04size\x12\x16\n" +
+	"\x06sha256\x18\x03 \x01(\fR\x06sha256\"/\n" +
+	"\n" +
+	"GetRequest\x12!\n" +
+	"\x03ref\x18\x01 \x01(\v2\x0f.dfss.v1.KeyRefR\x03ref\"[\n" +
+	"\vGetResponse\x12\x19\n" +
+	"\bkey_hash\x18\x01 \x01(\fR\akeyHash\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\fR\x05value\x12\x1b\n" +
+	"\thop_count\x18\x03 \x01(\x05R\bhopCount\"!\n" +
+	"\rDeleteRequest\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\"+\n" +
+	"\x0eDeleteResponse\x12\x19\n" +
+	"\bkey_hash\x18\x01 \x01(\fR\akeyHash\"\x0f\n" +
+	"\rStatusRequest\"\xcb\x01\n" +
+	"\rEvictionStats\x12\x18\n" +
+	"\aevicted\x18\x01 \x01(\x03R\aevicted\x12#\n" +
+	"\revicted_bytes\x18\x02 \x01(\x03R\fevictedBytes\x12\x16\n" +
+	"\x06cached\x18\x03 \x01(\x03R\x06cached\x12'\n" +
+	"\x0fnot_responsible\x18\x04 \x01(\x03R\x0enotResponsible\x12 \n" +
+	"\vresponsible\x18\x05 \x01(\x03R\vresponsible\x12\x18\n" +
+	"\arefused\x18\x06 \x01(\x03R\arefused\"\xa8\x02\n" +
+	"\x0eStatusResponse\x12\x17\n" +
+	"\anode_id\x18\x01 \x01(\fR\x06nodeId\x12\x1d\n" +
+	"\n" +
+	"public_key\x18\x02 \x01(\fR\tpublicKey\x12\x0e\n" +
+	"\x02ip\x18\x03 \x01(\tR\x02ip\x12\x12\n" +
+	"\x04port\x18\x04 \x01(\x05R\x04port\x12\x1f\n" +
+	"\vstored_keys\x18\x05 \x01(\x03R\n" +
+	"storedKeys\x12!\n" +
+	"\fstored_bytes\x18\x06 \x01(\x03R\vstoredBytes\x12\x1f\n" +
+	"\vquota_bytes\x18\a \x01(\x03R\n" +
+	"quotaBytes\x124\n" +
+	"\tevictions\x18\b \x01(\v2\x16.dfss.v1.EvictionStatsR\tevictions\x12\x1f\n" +
+	"\vknown_peers\x18\t \x01(\x03R\n" +
+	"knownPeers\"c\n" +
+	"\aContact\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\fR\x02id\x12\x0e\n" +
+	"\x02ip\x18\x02 \x01(\tR\x02ip\x12\x12\n" +
+	"\x04port\x18\x03 \x01(\x05R\x04port\x12$\n" +
+	"\x0elast_seen_unix\x18\x04 \x01(\x03R\flastSeenUnix\"L\n" +
+	"\x06Bucket\x12\x14\n" +
+	"\x05index\x18\x01 \x01(\x05R\x05index\x12,\n" +
+	"\bcontacts\x18\x02 \x03(\v2\x10.dfss.v1.ContactR\bcontacts\"\x15\n" +
+	"\x13RoutingTableRequest\"A\n" +
+	"\x14RoutingTableResponse\x12)\n" +
+	"\abuckets\x18\x01 \x03(\v2\x0f.dfss.v1.BucketR\abuckets\"\x0e\n" +
+	"\fPeersRequest\"7\n" +
+	"\rPeersResponse\x12&\n" +
+	"\x05peers\x18\x01 \x03(\v2\x10.dfss.v1.ContactR\x05peers\"h\n" +
+	"\fUploadHeader\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12&\n" +
+	"\x04mode\x18\x02 \x01(\x0e2\x12.dfss.v1.StoreModeR\x04mode\x12\x1e\n" +
+	"\n" +
+	"recipients\x18\x03 \x03(\fR\n" +
+	"recipients\"`\n" +
+	"\rUploadRequest\x12/\n" +
+	"\x06header\x18\x01 \x01(\v2\x15.dfss.v1.UploadHeaderH\x00R\x06header\x12\x16\n" +
+	"\x05chunk\x18\x02 \x01(\fH\x00R\x05chunkB\x06\n" +
+	"\x04part\"W\n" +
+	"\x0eUploadResponse\x12\x19\n" +
+	"\bkey_hash\x18\x01 \x01(\fR\akeyHash\x12\x12\n" +
+	"\x04size\x18\x02 \x01(\x03R\x04size\x12\x16\n" +
+	"\x06sha256\x18\x03 \x01(\fR\x06sha256\"4\n" +
+	"\x0fDownloadRequest\x12!\n" +
+	"\x03ref\x18\x01 \x01(\v2\x0f.dfss.v1.KeyRefR\x03ref\"t\n" +
+	"\x0eDownloadHeader\x12\x19\n" +
+	"\bkey_hash\x18\x01 \x01(\fR\akeyHash\x12\x12\n" +
+	"\x04size\x18\x02 \x01(\x03R\x04size\x12\x16\n" +
+	"\x06sha256\x18\x03 \x01(\fR\x06sha256\x12\x1b\n" +
+	"\thop_count\x18\x04 \x01(\x05R\bhopCount\"e\n" +
+	"\x10DownloadResponse\x121\n" +
+	"\x06header\x18\x01 \x01(\v2\x17.dfss.v1.DownloadHeaderH\x00R\x06header\x12\x16\n" +
+	"\x05chunk\x18\x02 \x01(\fH\x00R\x05chunkB\x06\n" +
+	"\x04part*t\n" +
+	"\tStoreMode\x12\x1a\n" +
+	"\x16STORE_MODE_UNSPECIFIED\x10\x00\x12\x16\n" +
+	"\x12STORE_MODE_ERASURE\x10\x01\x12\x18\n" +
+	"\x14STORE_MODE_ENCRYPTED\x10\x02\x12\x19\n" +
+	"\x15STORE_MODE_CONVERGENT\x10\x032\xec\x03\n" +
+	"\vNodeService\x120\n" +
+	"\x03Put\x12\x13.dfss.v1.PutRequest\x1a\x14.dfss.v1.PutResponse\x120\n" +
+	"\x03Get\x12\x13.dfss.v1.GetRequest\x1a\x14.dfss.v1.GetResponse\x129\n" +
+	"\x06Delete\x12\x16.dfss.v1.DeleteRequest\x1a\x17.dfss.v1.DeleteResponse\x129\n" +
+	"\x06Status\x12\x16.dfss.v1.StatusRequest\x1a\x17.dfss.v1.StatusResponse\x12K\n" +
+	"\fRoutingTable\x12\x1c.dfss.v1.RoutingTableRequest\x1a\x1d.dfss.v1.RoutingTableResponse\x126\n" +
+	"\x05Peers\x12\x15.dfss.v1.PeersRequest\x1a\x16.dfss.v1.PeersResponse\x12;\n" +
+	"\x06Upload\x12\x16.dfss.v1.UploadRequest\x1a\x17.dfss.v1.UploadResponse(\x01\x12A\n" +
+	"\bDownload\x12\x18.dfss.v1.DownloadRequest\x1a\x19.dfss.v1.DownloadResponse0\x01BJZHgithub.com/kutluhann/decentralized-file-sharing-system/rpc/dfssv1;dfssv1b\x06proto3"
+
+var (
+	file_dfss_v1_node_proto_rawDescOnce sync.Once
+	file_dfss_v1_node_proto_rawDescData []byte
+)
+
+func file_dfss_v1_node_proto_rawDescGZIP() []byte {
+	file_dfss_v1_node_proto_rawDescOnce.Do(func() {
+		file_dfss_v1_node_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_dfss_v1_node_proto_rawDesc), len(file_dfss_v1_node_proto_rawDesc)))
+	})
+	return file_dfss_v1_node_proto_rawDescData
+}
+
+var file_dfss_v1_node_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_dfss_v1_node_proto_msgTypes = make([]protoimpl.MessageInfo, 22)
+var file_dfss_v1_node_proto_goTypes = []any{
+	(StoreMode)(0),               // 0: dfss.v1.StoreMode
+	(*KeyRef)(nil),               // 1: dfss.v1.KeyRef
+	(*PutRequest)(nil),           // 2: dfss.v1.PutRequest
+	(*PutResponse)(nil),          // 3: dfss.v1.PutResponse
+	(*GetRequest)(nil),           // 4: dfss.v1.GetRequest
+	(*GetResponse)(nil),          // 5: dfss.v1.GetResponse
+	(*DeleteRequest)(nil),        // 6: dfss.v1.DeleteRequest
+	(*DeleteResponse)(nil),       // 7: dfss.v1.DeleteResponse
+	(*StatusRequest)(nil),        // 8: dfss.v1.StatusRequest
+	(*EvictionStats)(nil),        // 9: dfss.v1.EvictionStats
+	(*StatusResponse)(nil),       // 10: dfss.v1.StatusResponse
+	(*Contact)(nil),              // 11: dfss.v1.Contact
+	(*Bucket)(nil),               // 12: dfss.v1.Bucket
+	(*RoutingTableRequest)(nil),  // 13: dfss.v1.RoutingTableRequest
+	(*RoutingTableResponse)(nil), // 14: dfss.v1.RoutingTableResponse
+	(*PeersRequest)(nil),         // 15: dfss.v1.PeersRequest
+	(*PeersResponse)(nil),        // 16: dfss.v1.PeersResponse
+	(*UploadHeader)(nil),         // 17: dfss.v1.UploadHeader
+	(*UploadRequest)(nil),        // 18: dfss.v1.UploadRequest
+	(*UploadResponse)(nil),       // 19: dfss.v1.UploadResponse
+	(*DownloadRequest)(nil),      // 20: dfss.v1.DownloadRequest
+	(*DownloadHeader)(nil),       // 21: dfss.v1.DownloadHeader
+	(*DownloadResponse)(nil),     // 22: dfss.v1.DownloadResponse
+}
+var file_dfss_v1_node_proto_depIdxs = []int32{
+	0,  // 0: dfss.v1.PutRequest.mode:type_name -> dfss.v1.StoreMode
+	1,  // 1: dfss.v1.GetRequest.ref:type_name -> dfss.v1.KeyRef
+	9,  // 2: dfss.v1.StatusResponse.evictions:type_name -> dfss.v1.EvictionStats
+	11, // 3: dfss.v1.Bucket.contacts:type_name -> dfss.v1.Contact
+	12, // 4: dfss.v1.RoutingTableResponse.buckets:type_name -> dfss.v1.Bucket
+	11, // 5: dfss.v1.PeersResponse.peers:type_name -> dfss.v1.Contact
+	0,  // 6: dfss.v1.UploadHeader.mode:type_name -> dfss.v1.StoreMode
+	17, // 7: dfss.v1.UploadRequest.header:type_name -> dfss.v1.UploadHeader
+	1,  // 8: dfss.v1.DownloadRequest.ref:type_name -> dfss.v1.KeyRef
+	21, // 9: dfss.v1.DownloadResponse.header:type_name -> dfss.v1.DownloadHeader
+	2,  // 10: dfss.v1.NodeService.Put:input_type -> dfss.v1.PutRequest
+	4,  // 11: dfss.v1.NodeService.Get:input_type -> dfss.v1.GetRequest
+	6,  // 12: dfss.v1.NodeService.Delete:input_type -> dfss.v1.DeleteRequest
+	8,  // 13: dfss.v1.NodeService.Status:input_type -> dfss.v1.StatusRequest
+	13, // 14: dfss.v1.NodeService.RoutingTable:input_type -> dfss.v1.RoutingTableRequest
+	15, // 15: dfss.v1.NodeService.Peers:input_type -> dfss.v1.PeersRequest
+	18, // 16: dfss.v1.NodeService.Upload:input_type -> dfss.v1.UploadRequest
+	20, // 17: dfss.v1.NodeService.Download:input_type -> dfss.v1.DownloadRequest
+	3,  // 18: dfss.v1.NodeService.Put:output_type -> dfss.v1.PutResponse
+	5,  // 19: dfss.v1.NodeService.Get:output_type -> dfss.v1.GetResponse
+	7,  // 20: dfss.v1.NodeService.Delete:output_type -> dfss.v1.DeleteResponse
+	10, // 21: dfss.v1.NodeService.Status:output_type -> dfss.v1.StatusResponse
+	14, // 22: dfss.v1.NodeService.RoutingTable:output_type -> dfss.v1.RoutingTableResponse
+	16, // 23: dfss.v1.NodeService.Peers:output_type -> dfss.v1.PeersResponse
+	19, // 24: dfss.v1.NodeService.Upload:output_type -> dfss.v1.UploadResponse
+	22, // 25: dfss.v1.NodeService.Download:output_type -> dfss.v1.DownloadResponse
+	18, // [18:26] is the sub-list for method output_type
+	10, // [10:18] is the sub-list for method input_type
+	10, // [10:10] is the sub-list for extension type_name
+	10, // [10:10] is the sub-list for extension extendee
+	0,  // [0:10] is the sub-list for field type_name
+}
+
+func init() { file_dfss_v1_node_proto_init() }
+func file_dfss_v1_node_proto_init() {
+	if File_dfss_v1_node_proto != nil {
+		return
+	}
+	file_dfss_v1_node_proto_msgTypes[0].OneofWrappers = []any{
+		(*KeyRef_Key)(nil),
+		(*KeyRef_KeyHash)(nil),
+	}
+	file_dfss_v1_node_proto_msgTypes[17].OneofWrappers = []any{
+		(*UploadRequest_Header)(nil),
+		(*UploadRequest_Chunk)(nil),
+	}
+	file_dfss_v1_node_proto_msgTypes[21].OneofWrappers = []any{
+		(*DownloadResponse_Header)(nil),
+		(*DownloadResponse_Chunk)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_dfss_v1_node_proto_rawDesc), len(file_dfss_v1_node_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   22,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_dfss_v1_node_proto_goTypes,
+		DependencyIndexes: file_dfss_v1_node_proto_depIdxs,
+		EnumInfos:         file_dfss_v1_node_proto_enumTypes,
+		MessageInfos:      file_dfss_v1_node_proto_msgTypes,
+	}.Build()
+	File_dfss_v1_node_proto = out.File
+	file_dfss_v1_node_proto_goTypes = nil
+	file_dfss_v1_node_proto_depIdxs = nil
+}